@@ -1,26 +1,117 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
+var (
+	// ErrUnknownDriver indicates that the configured storage driver has not been registered
+	ErrUnknownDriver = errors.New("unknown storage driver")
+	// ErrInvalidTimeout indicates that a configured database timeout is not a positive duration
+	ErrInvalidTimeout = errors.New("invalid timeout")
+	// ErrInvalidPageSize indicates that a configured page size is negative, or the default exceeds the maximum
+	ErrInvalidPageSize = errors.New("invalid page size")
+	// ErrInvalidBatchSize indicates that the configured batch size is not positive
+	ErrInvalidBatchSize = errors.New("invalid batch size")
+)
+
 type Config struct {
 	AppName    string
 	ConfigPath string
-	Collection string
-	Database   string
-	DBURL      string
+	Driver     string
+	Mongo      MongoConfig
+	Memory     MemoryConfig
+	SQLite     SQLiteConfig
+	File       FileConfig
+	Timeouts   TimeoutConfig
+	Migrations MigrationsConfig
+	HTTP       HTTPConfig
 	Port       string
 	Version    string
 }
 
+// MongoConfig holds the settings for the mongo storage driver
+type MongoConfig struct {
+	URL        string
+	Database   string
+	Collection string
+}
+
+// MemoryConfig holds the settings for the in-memory storage driver
+type MemoryConfig struct {
+	SeedFile string
+}
+
+// SQLiteConfig holds the settings for the SQLite storage driver
+type SQLiteConfig struct {
+	Path string
+}
+
+// FileConfig holds the settings for the JSON file storage driver
+type FileConfig struct {
+	Path string
+}
+
+// TimeoutConfig bounds how long each kind of database operation may take
+type TimeoutConfig struct {
+	Connect     time.Duration
+	Ping        time.Duration
+	Read        time.Duration
+	Write       time.Duration
+	CursorDrain time.Duration
+	// Stream bounds how long GET / may spend streaming languages, which is much longer than reading a page
+	Stream time.Duration
+	// Index bounds how long creating the indexes at startup may take, which is much longer than a write when
+	// an index has to be built over an existing collection
+	Index time.Duration
+}
+
+// MigrationsConfig controls how schema migrations are applied
+type MigrationsConfig struct {
+	RunOnStartup bool
+}
+
+// HTTPConfig controls how the API treats requests
+type HTTPConfig struct {
+	// RequireIfMatch makes PUT, PATCH and DELETE requests without an If-Match header fail with 428 Precondition Required
+	RequireIfMatch bool
+	// DefaultPageSize is how many languages GET / returns when no limit is given. 0, the default, returns every
+	// language, as GET / did before it was paged.
+	DefaultPageSize int64
+	// MaxPageSize is the most languages GET / returns at once, whatever limit is given, or 0 for no cap
+	MaxPageSize int64
+	// MaxBatchSize is the most operations POST /batch accepts at once
+	MaxBatchSize int
+}
+
 func New() (Config, error) {
 	viper.SetDefault("AppName", AppName)
 	viper.SetDefault("ConfigPath", "config.json")
-	viper.SetDefault("Collection", "")
-	viper.SetDefault("Database", "")
-	viper.SetDefault("DBURL", "")
+	viper.SetDefault("Driver", "mongo")
+	viper.SetDefault("Mongo.URL", "")
+	viper.SetDefault("Mongo.Database", "")
+	viper.SetDefault("Mongo.Collection", "")
+	viper.SetDefault("Memory.SeedFile", "")
+	viper.SetDefault("SQLite.Path", "")
+	viper.SetDefault("File.Path", "")
+	viper.SetDefault("Timeouts.Connect", 10*time.Second)
+	viper.SetDefault("Timeouts.Ping", 10*time.Second)
+	viper.SetDefault("Timeouts.Read", 5*time.Second)
+	viper.SetDefault("Timeouts.Write", 5*time.Second)
+	viper.SetDefault("Timeouts.CursorDrain", 5*time.Second)
+	viper.SetDefault("Timeouts.Stream", 5*time.Minute)
+	viper.SetDefault("Timeouts.Index", 5*time.Minute)
+	viper.SetDefault("Migrations.RunOnStartup", true)
+	viper.SetDefault("HTTP.RequireIfMatch", false)
+	viper.SetDefault("HTTP.DefaultPageSize", 0)
+	viper.SetDefault("HTTP.MaxPageSize", 0)
+	viper.SetDefault("HTTP.MaxBatchSize", 1000)
 	viper.SetDefault("Port", "8080")
 	viper.SetDefault("Version", Version)
 
@@ -40,5 +131,83 @@ func New() (Config, error) {
 		return Config{}, err
 	}
 
+	c.Mongo.readLegacyKeys()
+
+	if !IsDriverRegistered(c.Driver) {
+		err = fmt.Errorf("%w %q, expected one of: %s", ErrUnknownDriver, c.Driver, strings.Join(Drivers(), ", "))
+		log.Error().Err(err).Msg("Error validating config file")
+		return Config{}, err
+	}
+
+	err = c.Timeouts.validate()
+	if err != nil {
+		log.Error().Err(err).Msg("Error validating config file")
+		return Config{}, err
+	}
+
+	err = c.HTTP.validate()
+	if err != nil {
+		log.Error().Err(err).Msg("Error validating config file")
+		return Config{}, err
+	}
+
 	return c, err
 }
+
+// readLegacyKeys fills in each mongo setting that isn't set from the top-level key it was read from before the mongo
+// settings moved under Mongo, warning that the old key is deprecated
+func (m *MongoConfig) readLegacyKeys() {
+	for _, key := range []struct {
+		legacy  string
+		current string
+		value   *string
+	}{
+		{"DBURL", "Mongo.URL", &m.URL},
+		{"Database", "Mongo.Database", &m.Database},
+		{"Collection", "Mongo.Collection", &m.Collection},
+	} {
+		if *key.value != "" || !viper.IsSet(key.legacy) {
+			continue
+		}
+
+		log.Warn().Msgf("Config key %s is deprecated, use %s instead", key.legacy, key.current)
+		*key.value = viper.GetString(key.legacy)
+	}
+}
+
+func (h HTTPConfig) validate() error {
+	if h.MaxPageSize < 0 {
+		return fmt.Errorf("%w: HTTP.MaxPageSize must not be negative, got %d", ErrInvalidPageSize, h.MaxPageSize)
+	}
+
+	if h.DefaultPageSize < 0 || (h.MaxPageSize > 0 && h.DefaultPageSize > h.MaxPageSize) {
+		return fmt.Errorf("%w: HTTP.DefaultPageSize must be between 0 and HTTP.MaxPageSize (%d), got %d", ErrInvalidPageSize, h.MaxPageSize, h.DefaultPageSize)
+	}
+
+	if h.MaxBatchSize <= 0 {
+		return fmt.Errorf("%w: HTTP.MaxBatchSize must be positive, got %d", ErrInvalidBatchSize, h.MaxBatchSize)
+	}
+
+	return nil
+}
+
+func (t TimeoutConfig) validate() error {
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"Connect", t.Connect},
+		{"Ping", t.Ping},
+		{"Read", t.Read},
+		{"Write", t.Write},
+		{"CursorDrain", t.CursorDrain},
+		{"Stream", t.Stream},
+		{"Index", t.Index},
+	} {
+		if timeout.value <= 0 {
+			return fmt.Errorf("%w: Timeouts.%s must be positive, got %s", ErrInvalidTimeout, timeout.name, timeout.value)
+		}
+	}
+
+	return nil
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"errors"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -26,13 +29,44 @@ func Test_New_ShouldReturnConfigOnSuccess(t *testing.T) {
 	expected := Config{
 		AppName:    AppName,
 		ConfigPath: "../../config.json",
-		Collection: "languages",
-		Database:   "languages",
-		DBURL:      "mongodb://host.docker.internal:27017/",
-		Port:       "8080",
-		Version:    Version,
+		Driver:     "mongo",
+		Mongo: MongoConfig{
+			URL:        "mongodb://host.docker.internal:27017/",
+			Database:   "languages",
+			Collection: "languages",
+		},
+		Memory: MemoryConfig{
+			SeedFile: "mockData.json",
+		},
+		SQLite: SQLiteConfig{
+			Path: "languages.db",
+		},
+		File: FileConfig{
+			Path: "languages.json",
+		},
+		Timeouts: TimeoutConfig{
+			Connect:     10 * time.Second,
+			Ping:        10 * time.Second,
+			Read:        5 * time.Second,
+			Write:       5 * time.Second,
+			CursorDrain: 5 * time.Second,
+			Stream:      5 * time.Minute,
+			Index:       5 * time.Minute,
+		},
+		Migrations: MigrationsConfig{
+			RunOnStartup: true,
+		},
+		HTTP: HTTPConfig{
+			RequireIfMatch:  false,
+			DefaultPageSize: 0,
+			MaxPageSize:     0,
+			MaxBatchSize:    1000,
+		},
+		Port:    "8080",
+		Version: Version,
 	}
 
+	RegisterDriver("mongo")
 	viper.Set("ConfigPath", "../../config.json")
 
 	cfg, err := New()
@@ -44,3 +78,86 @@ func Test_New_ShouldReturnConfigOnSuccess(t *testing.T) {
 		t.Errorf("Expected %v, got %v", expected, cfg)
 	}
 }
+
+func Test_New_ShouldReturnErrUnknownDriverOnUnregisteredDriver(t *testing.T) {
+	viper.Set("ConfigPath", "../../config.json")
+	viper.Set("Driver", "cassandra")
+	defer viper.Set("Driver", nil)
+
+	_, err := New()
+	if !errors.Is(err, ErrUnknownDriver) {
+		t.Errorf("Error should be ErrUnknownDriver, got %v", err)
+	}
+}
+
+func Test_New_ShouldReturnErrInvalidTimeoutOnNonPositiveTimeout(t *testing.T) {
+	RegisterDriver("mongo")
+	viper.Set("ConfigPath", "../../config.json")
+	viper.Set("Timeouts.Read", "0s")
+	defer viper.Set("Timeouts.Read", nil)
+
+	_, err := New()
+	if !errors.Is(err, ErrInvalidTimeout) {
+		t.Errorf("Error should be ErrInvalidTimeout, got %v", err)
+	}
+}
+
+func Test_New_ShouldReturnErrInvalidPageSizeWhenDefaultExceedsMax(t *testing.T) {
+	RegisterDriver("mongo")
+	viper.Set("ConfigPath", "../../config.json")
+	viper.Set("HTTP.DefaultPageSize", 500)
+	viper.Set("HTTP.MaxPageSize", 100)
+	defer viper.Set("HTTP.DefaultPageSize", nil)
+	defer viper.Set("HTTP.MaxPageSize", nil)
+
+	_, err := New()
+	if !errors.Is(err, ErrInvalidPageSize) {
+		t.Errorf("Error should be ErrInvalidPageSize, got %v", err)
+	}
+}
+
+func Test_New_ShouldReturnErrInvalidPageSizeOnNegativePageSize(t *testing.T) {
+	RegisterDriver("mongo")
+	viper.Set("ConfigPath", "../../config.json")
+	viper.Set("HTTP.MaxPageSize", -1)
+	defer viper.Set("HTTP.MaxPageSize", nil)
+
+	_, err := New()
+	if !errors.Is(err, ErrInvalidPageSize) {
+		t.Errorf("Error should be ErrInvalidPageSize, got %v", err)
+	}
+}
+
+func Test_New_ShouldReturnErrInvalidBatchSizeOnNonPositiveBatchSize(t *testing.T) {
+	RegisterDriver("mongo")
+	viper.Set("ConfigPath", "../../config.json")
+	viper.Set("HTTP.MaxBatchSize", 0)
+	defer viper.Set("HTTP.MaxBatchSize", nil)
+
+	_, err := New()
+	if !errors.Is(err, ErrInvalidBatchSize) {
+		t.Errorf("Error should be ErrInvalidBatchSize, got %v", err)
+	}
+}
+
+func Test_New_ShouldReadMongoSettingsFromLegacyKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"DBURL": "mongodb://localhost:27017/", "Database": "legacy", "Collection": "languages", "Mongo": {"Collection": "current"}}`), 0o600)
+	if err != nil {
+		t.Fatal("Error writing config file:", err)
+	}
+
+	RegisterDriver("mongo")
+	viper.Set("ConfigPath", path)
+	defer viper.Set("ConfigPath", nil)
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("Unexpected error while creating new config: %s", err)
+	}
+
+	expected := MongoConfig{URL: "mongodb://localhost:27017/", Database: "legacy", Collection: "current"}
+	if cfg.Mongo != expected {
+		t.Errorf("Expected %v, got %v", expected, cfg.Mongo)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"slices"
+	"sync"
+)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]struct{})
+)
+
+// RegisterDriver records name as a valid value for Config.Driver. Storage packages register themselves
+// through mgo.Register, which calls this, so config can validate the setting without importing them.
+func RegisterDriver(name string) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	drivers[name] = struct{}{}
+}
+
+// IsDriverRegistered reports whether name has been registered as a storage driver
+func IsDriverRegistered(name string) bool {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	_, ok := drivers[name]
+	return ok
+}
+
+// Drivers returns the names of every registered storage driver in alphabetical order
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
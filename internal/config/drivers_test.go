@@ -0,0 +1,38 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_RegisterDriver_ShouldMakeDriverRegistered(t *testing.T) {
+	RegisterDriver("registered")
+
+	if !IsDriverRegistered("registered") {
+		t.Error("IsDriverRegistered should return true after RegisterDriver")
+	}
+}
+
+func Test_IsDriverRegistered_ShouldReturnFalseForUnknownDriver(t *testing.T) {
+	if IsDriverRegistered("unknown") {
+		t.Error("IsDriverRegistered should return false for a driver that was never registered")
+	}
+}
+
+func Test_Drivers_ShouldReturnSortedNames(t *testing.T) {
+	RegisterDriver("zeta")
+	RegisterDriver("alpha")
+
+	names := Drivers()
+
+	var filtered []string
+	for _, name := range names {
+		if name == "zeta" || name == "alpha" {
+			filtered = append(filtered, name)
+		}
+	}
+
+	if !reflect.DeepEqual(filtered, []string{"alpha", "zeta"}) {
+		t.Errorf("Drivers should be sorted, got %v", names)
+	}
+}
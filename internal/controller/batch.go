@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// batchKinds are the operations a batch may hold, by the name they are given in the request
+var batchKinds = map[string]models.BatchKind{
+	"create":  models.BatchCreate,
+	"replace": models.BatchReplace,
+	"patch":   models.BatchPatch,
+	"delete":  models.BatchDelete,
+}
+
+// batchRequest is the body of POST /batch
+type batchRequest struct {
+	// Atomic makes either every operation be applied or none of them
+	Atomic     bool             `json:"atomic"`
+	Operations []batchOperation `json:"operations"`
+}
+
+// batchOperation is one operation of a batch, which is applied as the request of the same kind on its own would be.
+// IfMatch takes the values the If-Match header of that request does.
+type batchOperation struct {
+	Op       string          `json:"op"`
+	Id       string          `json:"id"`
+	IfMatch  string          `json:"ifMatch"`
+	Language models.Language `json:"language"`
+}
+
+// batchResponse holds the result of each operation of a batch, in the order they were sent
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// batchResult is the status the request of the same kind as an operation would have had, with the id of the
+// language it wrote or the message it would have failed with
+type batchResult struct {
+	Status int    `json:"status"`
+	Id     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// batchOperation turns an operation as it is sent into the write it makes, or fails with the status and message
+// the request of the same kind would have
+func (ctrl *Controller) batchOperation(op batchOperation) (operation models.BatchOperation, failure batchResult) {
+	kind, ok := batchKinds[op.Op]
+	if !ok {
+		return operation, batchResult{Status: http.StatusBadRequest, Id: op.Id, Error: `Unknown operation "` + op.Op + `", expected one of create, replace, patch or delete`}
+	}
+
+	operation = models.BatchOperation{Kind: kind, Id: op.Id, Language: op.Language}
+	if kind == models.BatchCreate {
+		return operation, failure
+	}
+
+	revision, err := ctrl.revision(op.IfMatch)
+	if errors.Is(err, errIfMatchRequired) {
+		return operation, batchResult{Status: http.StatusPreconditionRequired, Id: op.Id, Error: "An ifMatch is required to modify a language"}
+	}
+
+	if err != nil {
+		return operation, batchResult{Status: http.StatusBadRequest, Id: op.Id, Error: "Invalid ifMatch"}
+	}
+	operation.Revision = revision
+
+	if kind == models.BatchPatch {
+		if len(operation.Language.Creators) > 0 {
+			operation.Language.Creators = strings.Split(operation.Language.Creators[0], ",")
+		}
+
+		if len(operation.Language.Extensions) > 0 {
+			operation.Language.Extensions = strings.Split(operation.Language.Extensions[0], ",")
+		}
+	}
+
+	return operation, failure
+}
+
+// batchStatus is the status and message the request of the same kind as operation would have responded with
+func batchStatus(operation models.BatchOperation, result models.BatchResult) batchResult {
+	if result.Err == nil {
+		switch {
+		case operation.Kind == models.BatchCreate, operation.Kind == models.BatchReplace && result.Upserted:
+			return batchResult{Status: http.StatusCreated, Id: result.Id}
+		case operation.Kind == models.BatchDelete:
+			return batchResult{Status: http.StatusNoContent, Id: result.Id}
+		default:
+			return batchResult{Status: http.StatusOK, Id: result.Id}
+		}
+	}
+
+	failure := batchResult{Id: result.Id}
+	if failure.Id == "" {
+		failure.Id = operation.Id
+	}
+
+	var conflict models.ConflictError
+	switch {
+	case errors.Is(result.Err, models.ErrNotApplied):
+		failure.Status, failure.Error = http.StatusFailedDependency, "Not applied as another operation in the batch failed"
+	case errors.Is(result.Err, models.ErrInvalidId):
+		failure.Status, failure.Error = http.StatusBadRequest, "The given id is not a valid id"
+	case errors.Is(result.Err, models.ErrIdMismatch):
+		failure.Status, failure.Error = http.StatusBadRequest, "The language's id does not match the given id"
+	case errors.Is(result.Err, models.ErrNotFound) && operation.Kind == models.BatchDelete:
+		failure.Status, failure.Error = http.StatusNotFound, "No language found with that id to delete"
+	case errors.Is(result.Err, models.ErrNotFound):
+		failure.Status, failure.Error = http.StatusNotFound, "No language found with that id to update"
+	case errors.Is(result.Err, models.ErrPreconditionFailed):
+		failure.Status, failure.Error = http.StatusPreconditionFailed, preconditionFailed(operation.Revision)
+	case errors.Is(result.Err, models.ErrDuplicateId):
+		failure.Status, failure.Error = http.StatusConflict, "A language with that id already exists"
+	case errors.As(result.Err, &conflict):
+		failure.Status, failure.Error = http.StatusConflict, "A language with that name already exists with id "+conflict.Id
+	case errors.Is(result.Err, models.ErrTimeout):
+		failure.Status, failure.Error = http.StatusGatewayTimeout, "The database did not respond in time"
+	default:
+		failure.Status, failure.Error = http.StatusInternalServerError, "An error occurred processing this request"
+	}
+
+	return failure
+}
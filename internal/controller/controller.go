@@ -3,27 +3,36 @@ package controller
 import (
 	"languages-api/internal/config"
 	"languages-api/internal/models"
+	"languages-api/internal/query"
 	"languages-api/internal/repo"
 
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
-	"github.com/gorilla/schema"
 	"github.com/rs/zerolog/log"
 )
 
 type APIController interface {
 	HealthCheckHandler(repo repo.Repository) http.HandlerFunc
 	GetLanguagesHandler(repo repo.Repository) http.HandlerFunc
+	SearchHandler(repo repo.Repository) http.HandlerFunc
+	StatsHandler(repo repo.Repository) http.HandlerFunc
+	GetExtensionsHandler(repo repo.Repository) http.HandlerFunc
+	GetExtensionHandler(repo repo.Repository) http.HandlerFunc
+	ClassifyHandler(repo repo.Repository) http.HandlerFunc
+	DetectHandler(repo repo.Repository) http.HandlerFunc
 	GetLanguageHandler(repo repo.Repository) http.HandlerFunc
 	CreateLanguageHandler(repo repo.Repository) http.HandlerFunc
 	UpsertLanguageHandler(repo repo.Repository) http.HandlerFunc
 	UpdateLanguageHandler(repo repo.Repository) http.HandlerFunc
 	DeleteLanguageHandler(repo repo.Repository) http.HandlerFunc
+	BatchHandler(repo repo.Repository) http.HandlerFunc
 	NotFoundPageHandler(w http.ResponseWriter, r *http.Request)
 }
 
@@ -55,7 +64,7 @@ func New(cfg config.Config) *Controller {
 func (ctrl *Controller) HealthCheckHandler(repo repo.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		mongoStatus := http.StatusOK
-		err := repo.Ping()
+		err := repo.Ping(r.Context())
 		if err != nil {
 			mongoStatus = http.StatusInternalServerError
 		}
@@ -82,11 +91,73 @@ func (ctrl *Controller) HealthCheckHandler(repo repo.Repository) http.HandlerFun
 
 func (ctrl *Controller) GetLanguagesHandler(repo repo.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var queryStrings models.Language
+		values := r.URL.Query()
 
-		err := schema.NewDecoder().Decode(&queryStrings, r.URL.Query())
+		sort, err := query.ParseSort(values.Get("sort"))
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to decode query string")
+			log.Error().Err(err).Msg("Failed to read sort parameter")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid sort parameter: " + err.Error())); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+		values.Del("sort")
+
+		fields, err := query.ParseFields(values.Get("fields"))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read fields parameter")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid fields parameter: " + err.Error())); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+		values.Del("fields")
+
+		// Languages are streamed when a request accepts newline delimited JSON, so that exports of the whole
+		// catalog aren't held in memory. Streams have no totals or facets, and can't walk backwards.
+		stream := acceptsNDJSON(r)
+
+		facets, err := query.ParseFacets(values.Get("facets"))
+		if err == nil && stream && len(facets) > 0 {
+			err = errFacetsWithStream
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read facets parameter")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid facets parameter: " + err.Error())); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+		values.Del("facets")
+
+		readPage := ctrl.page
+		if stream {
+			readPage = ctrl.streamPage
+		}
+
+		page, err := readPage(values, sort)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read pagination parameters")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid pagination parameters: " + err.Error())); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		expression := values.Get("filter")
+		values.Del("filter")
+
+		f, err := filter(values)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read filters")
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 			w.WriteHeader(http.StatusBadRequest)
 			if _, innerErr := w.Write([]byte("Invalid query string")); innerErr != nil {
@@ -95,16 +166,44 @@ func (ctrl *Controller) GetLanguagesHandler(repo repo.Repository) http.HandlerFu
 			return
 		}
 
-		if len(queryStrings.Creators) > 0 {
-			queryStrings.Creators = strings.Split(queryStrings.Creators[0], ",")
+		if expression != "" {
+			f.Expr, err = query.ParseExpr(expression)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to parse filter expression")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				if _, innerErr := w.Write([]byte("Invalid filter expression: " + err.Error())); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
 		}
 
-		if len(queryStrings.Extensions) > 0 {
-			queryStrings.Extensions = strings.Split(queryStrings.Extensions[0], ",")
+		if stream {
+			opts := page.streamOptions()
+			opts.Fields = fields
+			streamLanguages(w, r, repo, f, opts)
+			return
 		}
 
-		languages, errs := repo.GetLanguages(queryStrings)
+		opts := page.findOptions()
+		opts.Fields = fields
+		opts.Facets = facets
+
+		languages, errs := repo.GetLanguages(r.Context(), f, opts)
 		if len(errs) > 0 && errs[0] != nil {
+			for _, err = range errs {
+				if errors.Is(err, models.ErrTimeout) {
+					log.Error().Err(err).Msg("Timed out getting languages")
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+			}
+
 			for _, err = range errs {
 				if err != nil {
 					log.Error().Err(err).Msg("Failed to get languages")
@@ -118,8 +217,391 @@ func (ctrl *Controller) GetLanguagesHandler(repo repo.Repository) http.HandlerFu
 			return
 		}
 
+		var hasPrev, hasNext bool
+		languages.Languages, hasPrev, hasNext = page.trim(languages.Languages)
+
+		if len(fields) > 0 {
+			values.Set("fields", strings.Join(fields, ","))
+		}
+		if len(facets) > 0 {
+			values.Set("facets", strings.Join(facets, ","))
+		}
+		if expression != "" {
+			values.Set("filter", expression)
+		}
+		if links := page.links(r, values, languages.Languages, hasPrev, hasNext); links != "" {
+			w.Header().Set("Link", links)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(project(languages, fields)); err != nil {
+			log.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
+// SearchHandler ranks the languages matching the q parameter, narrowed by the same filters GET / accepts.
+// Results are paged with limit and offset only, as cursors mark positions in a sorted list rather than a ranking.
+func (ctrl *Controller) SearchHandler(repo repo.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+
+		q := values.Get("q")
+		values.Del("q")
+		if len(query.Terms(q)) == 0 {
+			log.Error().Msg("Search query is missing")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("A search query is required")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		cursors := values.Has("after") || values.Has("before")
+		page, err := ctrl.page(values, nil)
+		if cursors {
+			err = errCursorWithSearch
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read pagination parameters")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid pagination parameters: " + err.Error())); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		f, err := filter(values)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read filters")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid query string")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		results, errs := repo.SearchLanguages(r.Context(), q, f, models.FindOptions{Limit: page.limit, Offset: page.offset})
+		if len(errs) > 0 && errs[0] != nil {
+			for _, err = range errs {
+				if errors.Is(err, models.ErrTimeout) {
+					log.Error().Err(err).Msg("Timed out searching languages")
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+			}
+
+			for _, err = range errs {
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to search languages")
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		values.Set("q", q)
+		if links := page.offsetLinks(r, values, int64(len(results.Results)), results.Total); links != "" {
+			w.Header().Set("Link", links)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			log.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
+// defaultTopCreators is how many creators StatsHandler ranks if the top parameter isn't given
+const defaultTopCreators = 10
+
+// statsSections are the parts of the statistics GET /stats/{section} can return on their own, named as they are
+// in the full response
+var statsSections = map[string]func(stats models.Stats) interface{}{
+	"totals":               func(stats models.Stats) interface{} { return stats.Totals },
+	"byYear":               func(stats models.Stats) interface{} { return stats.ByYear },
+	"byDecade":             func(stats models.Stats) interface{} { return stats.ByDecade },
+	"topCreators":          func(stats models.Stats) interface{} { return stats.TopCreators },
+	"extensions":           func(stats models.Stats) interface{} { return stats.Extensions },
+	"missingFirstAppeared": func(stats models.Stats) interface{} { return stats.MissingFirstAppeared },
+}
+
+// StatsHandler summarises every stored language, or just the section named in the path. The top parameter sets
+// how many of the most prolific creators are listed.
+func (ctrl *Controller) StatsHandler(repo repo.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		section, hasSection := mux.Vars(r)["section"]
+		if _, ok := statsSections[section]; hasSection && !ok {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+			if _, innerErr := w.Write([]byte("No statistics found with that name")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		top := int64(defaultTopCreators)
+		if value := r.URL.Query().Get("top"); value != "" {
+			var err error
+			top, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || top < 1 {
+				log.Error().Err(err).Msg("Failed to read top parameter")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				if _, innerErr := w.Write([]byte("The top parameter must be a positive integer")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+		}
+
+		stats, err := repo.GetStats(r.Context(), top)
+		if err != nil {
+			if errors.Is(err, models.ErrTimeout) {
+				log.Error().Err(err).Msg("Timed out getting stats")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			log.Error().Err(err).Msg("Failed to get stats")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		var output interface{} = stats
+		if hasSection {
+			output = statsSections[section](stats)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(output); err != nil {
+			log.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
+// GetExtensionsHandler lists every extension the stored languages use, with the languages that use each
+func (ctrl *Controller) GetExtensionsHandler(repo repo.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extensions, errs := repo.GetExtensions(r.Context())
+		if len(errs) > 0 && errs[0] != nil {
+			for _, err := range errs {
+				if errors.Is(err, models.ErrTimeout) {
+					log.Error().Err(err).Msg("Timed out getting extensions")
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+			}
+
+			for _, err := range errs {
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to get extensions")
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(extensions); err != nil {
+			log.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
+// GetExtensionHandler lists the languages that use the extension in the path, which may be given with or without
+// its leading dot and is matched case-sensitively
+func (ctrl *Controller) GetExtensionHandler(repo repo.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extension, errs := repo.GetExtension(r.Context(), mux.Vars(r)["ext"])
+		if len(errs) > 0 && errs[0] != nil {
+			for _, err := range errs {
+				if errors.Is(err, models.ErrTimeout) {
+					log.Error().Err(err).Msg("Timed out getting extension")
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+			}
+
+			for _, err := range errs {
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to get extension")
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		if len(extension.Languages) == 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+			if _, innerErr := w.Write([]byte("No language found with that extension")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(extension); err != nil {
+			log.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
+// ClassifyHandler picks the languages the file named by the filename parameter may be written in from its
+// extension. A file no language's extension matches has no candidates rather than being an error.
+func (ctrl *Controller) ClassifyHandler(repo repo.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filename := r.URL.Query().Get("filename")
+		if filename == "" {
+			log.Error().Msg("Filename is missing")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("A filename is required")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		classification, errs := repo.Classify(r.Context(), filename)
+		if len(errs) > 0 && errs[0] != nil {
+			for _, err := range errs {
+				if errors.Is(err, models.ErrTimeout) {
+					log.Error().Err(err).Msg("Timed out classifying file")
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+			}
+
+			for _, err := range errs {
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to classify file")
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(classification); err != nil {
+			log.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
+// maxDetectBytes is the largest file DetectHandler reads. Clues are found near the start and end of a file, so
+// larger files are better sent cut down than whole.
+const maxDetectBytes = 1 << 20
+
+// DetectHandler ranks the languages the file in the request body may be written in, by the shebang, modelines and
+// keywords in its content and the extension of the optional filename parameter
+func (ctrl *Controller) DetectHandler(repo repo.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDetectBytes))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read request body")
+
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				if _, innerErr := w.Write([]byte("The file must be at most " + strconv.Itoa(maxDetectBytes) + " bytes")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid request body")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		filename := r.URL.Query().Get("filename")
+		if len(content) == 0 && filename == "" {
+			log.Error().Msg("Content and filename are missing")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("The file content or a filename is required")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		detection, errs := repo.Detect(r.Context(), string(content), filename)
+		if len(errs) > 0 && errs[0] != nil {
+			for _, err := range errs {
+				if errors.Is(err, models.ErrTimeout) {
+					log.Error().Err(err).Msg("Timed out detecting language")
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+			}
+
+			for _, err := range errs {
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to detect language")
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(languages); err != nil {
+		if err := json.NewEncoder(w).Encode(detection); err != nil {
 			log.Error().Err(err).Msg("Failed to write response")
 		}
 	}
@@ -129,7 +611,18 @@ func (ctrl *Controller) GetLanguageHandler(repo repo.Repository) http.HandlerFun
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
-		output, err := repo.GetLanguage(id)
+		fields, err := query.ParseFields(r.URL.Query().Get("fields"))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read fields parameter")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid fields parameter: " + err.Error())); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		output, err := repo.GetLanguage(r.Context(), id, fields)
 		if err != nil {
 			if errors.Is(err, models.ErrInvalidId) {
 				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -149,6 +642,16 @@ func (ctrl *Controller) GetLanguageHandler(repo repo.Repository) http.HandlerFun
 				return
 			}
 
+			if errors.Is(err, models.ErrTimeout) {
+				log.Error().Err(err).Msg("Timed out getting language")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
 			log.Error().Err(err).Msg("Failed to get language")
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -159,8 +662,9 @@ func (ctrl *Controller) GetLanguageHandler(repo repo.Repository) http.HandlerFun
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag(output.Revision))
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(output); err != nil {
+		if err := json.NewEncoder(w).Encode(projection{language: output, fields: fields}); err != nil {
 			log.Error().Err(err).Msg("Failed to write response")
 		}
 	}
@@ -181,8 +685,37 @@ func (ctrl *Controller) CreateLanguageHandler(repo repo.Repository) http.Handler
 			return
 		}
 
-		id, err := repo.PostLanguage(language)
+		id, err := repo.PostLanguage(r.Context(), language)
 		if err != nil {
+			if errors.Is(err, models.ErrDuplicateId) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusConflict)
+				if _, innerErr := w.Write([]byte("A language with that id already exists")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			var conflict models.ConflictError
+			if errors.As(err, &conflict) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusConflict)
+				if _, innerErr := w.Write([]byte("A language with that name already exists with id " + conflict.Id)); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			if errors.Is(err, models.ErrTimeout) {
+				log.Error().Err(err).Msg("Timed out creating language")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
 			log.Error().Err(err).Msg("Failed to create language")
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -193,6 +726,7 @@ func (ctrl *Controller) CreateLanguageHandler(repo repo.Repository) http.Handler
 		}
 
 		w.Header().Add("Location", "/"+url.PathEscape(id))
+		w.Header().Set("ETag", etag(1))
 		w.WriteHeader(http.StatusCreated)
 	}
 }
@@ -201,8 +735,27 @@ func (ctrl *Controller) UpsertLanguageHandler(repo repo.Repository) http.Handler
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
+		revision, err := ctrl.ifMatch(r)
+		if err != nil {
+			if errors.Is(err, errIfMatchRequired) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusPreconditionRequired)
+				if _, innerErr := w.Write([]byte("An If-Match header is required to modify a language")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid If-Match header")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
 		var language = models.Language{}
-		err := json.NewDecoder(r.Body).Decode(&language)
+		err = json.NewDecoder(r.Body).Decode(&language)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to decode request body")
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -213,7 +766,7 @@ func (ctrl *Controller) UpsertLanguageHandler(repo repo.Repository) http.Handler
 			return
 		}
 
-		isUpserted, err := repo.PutLanguage(id, language)
+		written, isUpserted, err := repo.PutLanguage(r.Context(), id, language, revision)
 		if err != nil {
 			if errors.Is(err, models.ErrInvalidId) {
 				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -224,6 +777,44 @@ func (ctrl *Controller) UpsertLanguageHandler(repo repo.Repository) http.Handler
 				return
 			}
 
+			if errors.Is(err, models.ErrIdMismatch) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				if _, innerErr := w.Write([]byte("The language's id does not match the given id")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			if errors.Is(err, models.ErrPreconditionFailed) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusPreconditionFailed)
+				if _, innerErr := w.Write([]byte(preconditionFailed(revision))); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			var conflict models.ConflictError
+			if errors.As(err, &conflict) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusConflict)
+				if _, innerErr := w.Write([]byte("A language with that name already exists with id " + conflict.Id)); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			if errors.Is(err, models.ErrTimeout) {
+				log.Error().Err(err).Msg("Timed out upserting language")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
 			log.Error().Err(err).Msg("Failed to upsert language")
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -233,6 +824,7 @@ func (ctrl *Controller) UpsertLanguageHandler(repo repo.Repository) http.Handler
 			return
 		}
 
+		w.Header().Set("ETag", etag(written))
 		if isUpserted {
 			w.Header().Add("Location", "/"+url.PathEscape(id))
 			w.WriteHeader(http.StatusCreated)
@@ -246,9 +838,39 @@ func (ctrl *Controller) UpdateLanguageHandler(repo repo.Repository) http.Handler
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
+		revision, err := ctrl.ifMatch(r)
+		if err != nil {
+			if errors.Is(err, errIfMatchRequired) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusPreconditionRequired)
+				if _, innerErr := w.Write([]byte("An If-Match header is required to modify a language")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid If-Match header")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
 		var update models.Language
+		var patch models.Patch
+		var operations models.JSONPatch
+		var written int64
 
-		err := json.NewDecoder(r.Body).Decode(&update)
+		patchType := mediaType(r)
+		switch patchType {
+		case mergePatch:
+			err = json.NewDecoder(r.Body).Decode(&patch)
+		case jsonPatch:
+			err = json.NewDecoder(r.Body).Decode(&operations)
+		default:
+			err = json.NewDecoder(r.Body).Decode(&update)
+		}
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to decode request body")
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -259,15 +881,40 @@ func (ctrl *Controller) UpdateLanguageHandler(repo repo.Repository) http.Handler
 			return
 		}
 
-		if len(update.Creators) > 0 {
-			update.Creators = strings.Split(update.Creators[0], ",")
-		}
+		switch patchType {
+		case mergePatch:
+			if removesName(patch) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				if _, innerErr := w.Write([]byte("A language's name cannot be removed")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
 
-		if len(update.Extensions) > 0 {
-			update.Extensions = strings.Split(update.Extensions[0], ",")
-		}
+			written, err = repo.MergePatchLanguage(r.Context(), id, patch, revision)
+		case jsonPatch:
+			if err := query.ValidatePatch(operations); err != nil {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				if _, innerErr := w.Write([]byte("Invalid patch: " + err.Error())); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			written, err = repo.JSONPatchLanguage(r.Context(), id, operations, revision)
+		default:
+			if len(update.Creators) > 0 {
+				update.Creators = strings.Split(update.Creators[0], ",")
+			}
 
-		err = repo.PatchLanguage(id, update)
+			if len(update.Extensions) > 0 {
+				update.Extensions = strings.Split(update.Extensions[0], ",")
+			}
+
+			written, err = repo.PatchLanguage(r.Context(), id, update, revision)
+		}
 		if err != nil {
 			if errors.Is(err, models.ErrInvalidId) {
 				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -287,6 +934,45 @@ func (ctrl *Controller) UpdateLanguageHandler(repo repo.Repository) http.Handler
 				return
 			}
 
+			if errors.Is(err, models.ErrPreconditionFailed) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusPreconditionFailed)
+				if _, innerErr := w.Write([]byte(preconditionFailed(revision))); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			var conflict models.ConflictError
+			if errors.As(err, &conflict) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusConflict)
+				if _, innerErr := w.Write([]byte("A language with that name already exists with id " + conflict.Id)); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			var patchError models.PatchError
+			if errors.As(err, &patchError) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusConflict)
+				if _, innerErr := w.Write([]byte("Operation " + strconv.Itoa(patchError.Operation) + " of the patch failed, as " + patchError.Reason)); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			if errors.Is(err, models.ErrTimeout) {
+				log.Error().Err(err).Msg("Timed out updating language")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
 			log.Error().Err(err).Msg("Failed to update language")
 
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -297,6 +983,7 @@ func (ctrl *Controller) UpdateLanguageHandler(repo repo.Repository) http.Handler
 			return
 		}
 
+		w.Header().Set("ETag", etag(written))
 		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -305,7 +992,26 @@ func (ctrl *Controller) DeleteLanguageHandler(repo repo.Repository) http.Handler
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := mux.Vars(r)["id"]
 
-		err := repo.DeleteLanguage(id)
+		revision, err := ctrl.ifMatch(r)
+		if err != nil {
+			if errors.Is(err, errIfMatchRequired) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusPreconditionRequired)
+				if _, innerErr := w.Write([]byte("An If-Match header is required to modify a language")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid If-Match header")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		err = repo.DeleteLanguage(r.Context(), id, revision)
 		if err != nil {
 			if errors.Is(err, models.ErrInvalidId) {
 				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -324,6 +1030,26 @@ func (ctrl *Controller) DeleteLanguageHandler(repo repo.Repository) http.Handler
 				}
 				return
 			}
+
+			if errors.Is(err, models.ErrPreconditionFailed) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusPreconditionFailed)
+				if _, innerErr := w.Write([]byte(preconditionFailed(revision))); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
+			if errors.Is(err, models.ErrTimeout) {
+				log.Error().Err(err).Msg("Timed out deleting language")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusGatewayTimeout)
+				if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+
 			log.Error().Err(err).Msg("Failed to delete language")
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -337,6 +1063,108 @@ func (ctrl *Controller) DeleteLanguageHandler(repo repo.Repository) http.Handler
 	}
 }
 
+// BatchHandler applies a list of create, replace, patch and delete operations in order, responding with the status
+// each would have had as a request of its own. Operations that fail don't stop the rest from being applied, unless
+// the batch is atomic, in which case either every operation is applied or none are.
+func (ctrl *Controller) BatchHandler(repo repo.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var batch batchRequest
+
+		err := json.NewDecoder(r.Body).Decode(&batch)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to decode request body")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("Invalid request body")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		if len(batch.Operations) == 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("A batch must have at least one operation")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		if limit := ctrl.Config.HTTP.MaxBatchSize; limit > 0 && len(batch.Operations) > limit {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			if _, innerErr := w.Write([]byte("A batch may have at most " + strconv.Itoa(limit) + " operations")); innerErr != nil {
+				log.Error().Err(innerErr).Msg("Failed to write response")
+			}
+			return
+		}
+
+		// Operations that are invalid fail here, and only the rest are passed on
+		response := batchResponse{Results: make([]batchResult, len(batch.Operations))}
+		var operations []models.BatchOperation
+		var positions []int
+		for i, op := range batch.Operations {
+			operation, failure := ctrl.batchOperation(op)
+			if failure.Status != 0 {
+				response.Results[i] = failure
+				continue
+			}
+
+			operations = append(operations, operation)
+			positions = append(positions, i)
+		}
+
+		results := make([]models.BatchResult, len(operations))
+		if batch.Atomic && len(operations) < len(batch.Operations) {
+			for i := range results {
+				results[i] = models.BatchResult{Err: models.ErrNotApplied}
+			}
+		} else if len(operations) > 0 {
+			results, err = repo.BatchLanguages(r.Context(), operations, batch.Atomic)
+			if err != nil {
+				if errors.Is(err, models.ErrTransactionsUnsupported) {
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusNotImplemented)
+					if _, innerErr := w.Write([]byte("The database does not support atomic batches")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+
+				if errors.Is(err, models.ErrTimeout) {
+					log.Error().Err(err).Msg("Timed out applying batch")
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+						log.Error().Err(innerErr).Msg("Failed to write response")
+					}
+					return
+				}
+
+				log.Error().Err(err).Msg("Failed to apply batch")
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusInternalServerError)
+				if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+					log.Error().Err(innerErr).Msg("Failed to write response")
+				}
+				return
+			}
+		}
+
+		for j, result := range results {
+			if result.Err != nil && !errors.Is(result.Err, models.ErrNotApplied) {
+				log.Error().Err(result.Err).Int("operation", positions[j]).Msg("Failed to apply batch operation")
+			}
+			response.Results[positions[j]] = batchStatus(operations[j], result)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
 func (ctrl *Controller) NotFoundPageHandler(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusNotFound)
@@ -344,3 +1172,56 @@ func (ctrl *Controller) NotFoundPageHandler(w http.ResponseWriter, _ *http.Reque
 		log.Error().Err(innerErr).Msg("Failed to write response")
 	}
 }
+
+// errIfMatchRequired indicates that a request without an If-Match header was made when the config requires one
+var errIfMatchRequired = errors.New("an If-Match header is required")
+
+// errInvalidIfMatch indicates that the If-Match header is neither "*" nor a single ETag returned by GetLanguageHandler
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// etag returns the strong entity tag for a revision of a language
+func etag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// preconditionFailed explains why a write given revision failed with models.ErrPreconditionFailed
+func preconditionFailed(revision int64) string {
+	if revision == models.AnyRevision {
+		return "No language exists with that id"
+	}
+
+	return "The language has been modified since it was retrieved"
+}
+
+// ifMatch returns the revision the request's If-Match header requires the language to be at. It returns
+// models.AnyRevision if the header is "*", so that the language must exist, and 0, meaning any revision or none,
+// if the header is missing and the config doesn't require it.
+func (ctrl *Controller) ifMatch(r *http.Request) (revision int64, err error) {
+	return ctrl.revision(r.Header.Get("If-Match"))
+}
+
+// revision returns the revision an If-Match header, or the ifMatch of a batch operation, requires the language to be at
+func (ctrl *Controller) revision(header string) (revision int64, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		if ctrl.Config.HTTP.RequireIfMatch {
+			return 0, errIfMatchRequired
+		}
+		return 0, nil
+	}
+
+	if header == "*" {
+		return models.AnyRevision, nil
+	}
+
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+
+	revision, err = strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || revision < 1 {
+		return 0, errInvalidIfMatch
+	}
+
+	return revision, nil
+}
@@ -2,7 +2,9 @@ package controller
 
 import (
 	"languages-api/internal/models"
+	"languages-api/internal/query"
 
+	"context"
 	"net/http"
 )
 
@@ -33,11 +35,19 @@ type mockRepository struct {
 	errs       []error
 	id         string
 	isUpserted bool
+	revision   int64
 	ls         models.Languages
+	rs         models.SearchResults
+	stats      models.Stats
+	extensions []models.Extension
+	extension  models.Extension
+	class      models.Classification
+	detection  models.Detection
 	l          models.Language
+	results    []models.BatchResult
 }
 
-func (r mockRepository) Ping() error {
+func (r mockRepository) Ping(_ context.Context) error {
 	return r.err
 }
 
@@ -45,26 +55,124 @@ func (r mockRepository) Close() error {
 	return r.err
 }
 
-func (r mockRepository) GetLanguages(_ models.Language) (models.Languages, []error) {
-	return r.ls, r.errs
+// GetLanguages returns the languages in ls that hold the creators and extensions filter asks for, the same way
+// every driver matches them, so that tests can check which set mode a query string selects. The facets opts asks
+// for are counted across the languages returned.
+func (r mockRepository) GetLanguages(_ context.Context, filter models.Filter, opts models.FindOptions) (models.Languages, []error) {
+	languages := r.ls
+	if len(filter.Creators) > 0 || len(filter.Extensions) > 0 {
+		languages = models.Languages{Languages: []models.Language{}, Total: r.ls.Total}
+		for _, language := range r.ls.Languages {
+			creators := len(filter.Creators) == 0 || query.MatchSet(language.Creators, filter.Creators, filter.CreatorsSet, func(creator string, pattern string) bool {
+				return query.Match(creator, pattern, filter.CreatorsMatch)
+			})
+			extensions := len(filter.Extensions) == 0 || query.MatchSet(language.Extensions, filter.Extensions, filter.ExtensionsSet, func(extension string, pattern string) bool {
+				return extension == pattern
+			})
+
+			if creators && extensions {
+				languages.Languages = append(languages.Languages, language)
+			} else {
+				languages.Total--
+			}
+		}
+	}
+
+	if len(opts.Facets) > 0 {
+		languages.Facets = query.CountFacets(languages.Languages, opts.Facets)
+	}
+
+	return languages, r.errs
+}
+
+// StreamLanguages sends every language in ls, then fails with the first of errs if there are any, so that tests
+// can fail a stream before or after languages have been written
+func (r mockRepository) StreamLanguages(_ context.Context, _ models.Filter, _ models.FindOptions, send func(models.Language) error) error {
+	for _, language := range r.ls.Languages {
+		err := send(language)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(r.errs) > 0 {
+		return r.errs[0]
+	}
+
+	return nil
 }
 
-func (r mockRepository) GetLanguage(_ string) (models.Language, error) {
+func (r mockRepository) SearchLanguages(_ context.Context, _ string, _ models.Filter, _ models.FindOptions) (models.SearchResults, []error) {
+	return r.rs, r.errs
+}
+
+func (r mockRepository) GetLanguage(_ context.Context, _ string, _ []string) (models.Language, error) {
 	return r.l, r.err
 }
 
-func (r mockRepository) PostLanguage(_ models.Language) (string, error) {
+func (r mockRepository) PostLanguage(_ context.Context, _ models.Language) (string, error) {
 	return r.id, r.err
 }
 
-func (r mockRepository) PutLanguage(_ string, _ models.Language) (isUpserted bool, err error) {
-	return r.isUpserted, r.err
+func (r mockRepository) PutLanguage(_ context.Context, _ string, _ models.Language, _ int64) (written int64, isUpserted bool, err error) {
+	return r.revision, r.isUpserted, r.err
 }
 
-func (r mockRepository) PatchLanguage(_ string, _ models.Language) (err error) {
-	return r.err
+func (r mockRepository) PatchLanguage(_ context.Context, _ string, _ models.Language, _ int64) (written int64, err error) {
+	return r.revision, r.err
+}
+
+func (r mockRepository) MergePatchLanguage(_ context.Context, _ string, _ models.Patch, _ int64) (written int64, err error) {
+	return r.revision, r.err
 }
 
-func (r mockRepository) DeleteLanguage(_ string) (err error) {
+func (r mockRepository) JSONPatchLanguage(_ context.Context, _ string, _ models.JSONPatch, _ int64) (written int64, err error) {
+	return r.revision, r.err
+}
+
+func (r mockRepository) DeleteLanguage(_ context.Context, _ string, _ int64) (err error) {
 	return r.err
 }
+
+// BatchLanguages returns results if it is set. Otherwise every operation succeeds, with its own id or, if it creates
+// a language, with id, so that tests can check which operations are passed on.
+func (r mockRepository) BatchLanguages(_ context.Context, operations []models.BatchOperation, _ bool) ([]models.BatchResult, error) {
+	if r.err != nil || r.results != nil {
+		return r.results, r.err
+	}
+
+	results := make([]models.BatchResult, len(operations))
+	for i, operation := range operations {
+		results[i] = models.BatchResult{Id: operation.Id, Upserted: operation.Kind == models.BatchReplace && r.isUpserted}
+		if operation.Kind == models.BatchCreate {
+			results[i].Id = r.id
+		}
+	}
+
+	return results, nil
+}
+
+// GetStats returns stats with only the topCreators first of its creators, so that tests can check the top
+// parameter is passed on
+func (r mockRepository) GetStats(_ context.Context, topCreators int64) (models.Stats, error) {
+	stats := r.stats
+	stats.TopCreators = stats.TopCreators[:min(topCreators, int64(len(stats.TopCreators)))]
+
+	return stats, r.err
+}
+
+func (r mockRepository) GetExtensions(_ context.Context) ([]models.Extension, []error) {
+	return r.extensions, r.errs
+}
+
+func (r mockRepository) GetExtension(_ context.Context, _ string) (models.Extension, []error) {
+	return r.extension, r.errs
+}
+
+func (r mockRepository) Classify(_ context.Context, _ string) (models.Classification, []error) {
+	return r.class, r.errs
+}
+
+func (r mockRepository) Detect(_ context.Context, _ string, _ string) (models.Detection, []error) {
+	return r.detection, r.errs
+}
@@ -3,9 +3,11 @@ package controller
 import (
 	"languages-api/internal/models"
 
+	"context"
 	"errors"
 	"net/http"
 	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -75,7 +77,7 @@ func Test_Ping_ShouldReturnStructError(t *testing.T) {
 	e := errors.New("golang")
 	mr := mockRepository{err: e}
 
-	err := mr.Ping()
+	err := mr.Ping(context.Background())
 	if !errors.Is(err, e) {
 		t.Errorf("Ping should return struct error, but got %v", err)
 	}
@@ -119,7 +121,7 @@ func Test_GetLanguages_ShouldReturnStructLanguages(t *testing.T) {
 
 	mr := mockRepository{ls: expected}
 
-	langs, errs := mr.GetLanguages(models.Language{})
+	langs, errs := mr.GetLanguages(context.Background(), models.Filter{}, models.FindOptions{})
 	if errs != nil {
 		t.Errorf("GetLanguages should not return error, but got %v", errs)
 	}
@@ -136,12 +138,23 @@ func Test_GetLanguages_ShouldReturnStructErrors(t *testing.T) {
 
 	mr := mockRepository{errs: expected}
 
-	_, errs := mr.GetLanguages(models.Language{})
+	_, errs := mr.GetLanguages(context.Background(), models.Filter{}, models.FindOptions{})
 	if !reflect.DeepEqual(errs, expected) {
 		t.Errorf("GetLanguages should return %v, but got %v", expected, errs)
 	}
 }
 
+func Test_GetLanguages_ShouldMatchStructLanguagesLikeDrivers(t *testing.T) {
+	expected := []models.Language{{Name: "Elixir", Creators: []string{"José Valim"}}}
+
+	mr := mockRepository{ls: models.Languages{Languages: append(slices.Clone(expected), models.Language{Name: "Go", Creators: []string{"Rob Pike"}}), Total: 2}}
+
+	langs, _ := mr.GetLanguages(context.Background(), models.Filter{Creators: []string{"jose", "ken"}, CreatorsMatch: models.MatchPrefix, CreatorsSet: models.SetAny}, models.FindOptions{})
+	if !reflect.DeepEqual(langs.Languages, expected) || langs.Total != 1 {
+		t.Errorf("GetLanguages should return %v of 1, but got %v of %d", expected, langs.Languages, langs.Total)
+	}
+}
+
 func Test_GetLanguage_ShouldReturnStructLanguage(t *testing.T) {
 	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
 	if err != nil {
@@ -166,7 +179,7 @@ func Test_GetLanguage_ShouldReturnStructLanguage(t *testing.T) {
 
 	mr := mockRepository{l: expected}
 
-	lang, err := mr.GetLanguage("")
+	lang, err := mr.GetLanguage(context.Background(), "", nil)
 	if err != nil {
 		t.Errorf("GetLanguage should not return error, but got %v", err)
 	}
@@ -181,7 +194,7 @@ func Test_GetLanguage_ShouldReturnStructError(t *testing.T) {
 
 	mr := mockRepository{err: expected}
 
-	_, err := mr.GetLanguage("")
+	_, err := mr.GetLanguage(context.Background(), "", nil)
 	if !reflect.DeepEqual(err, expected) {
 		t.Errorf("GetLanguage should return %v, but got %v", expected, err)
 	}
@@ -191,7 +204,7 @@ func Test_PostLanguage_ShouldReturnStructId(t *testing.T) {
 	id := "id"
 	mr := mockRepository{id: id}
 
-	result, err := mr.PostLanguage(models.Language{})
+	result, err := mr.PostLanguage(context.Background(), models.Language{})
 	if err != nil {
 		t.Errorf("PostLanguage should not return error, but got %v", err)
 	}
@@ -206,7 +219,7 @@ func Test_PostLanguage_ShouldReturnStructError(t *testing.T) {
 
 	mr := mockRepository{err: expected}
 
-	_, err := mr.PostLanguage(models.Language{})
+	_, err := mr.PostLanguage(context.Background(), models.Language{})
 	if !reflect.DeepEqual(err, expected) {
 		t.Errorf("PostLanguage should return %v, but got %v", expected, err)
 	}
@@ -215,7 +228,7 @@ func Test_PostLanguage_ShouldReturnStructError(t *testing.T) {
 func Test_PutLanguage_ShouldReturnStructId(t *testing.T) {
 	mr := mockRepository{isUpserted: true}
 
-	isUpserted, err := mr.PutLanguage("", models.Language{})
+	_, isUpserted, err := mr.PutLanguage(context.Background(), "", models.Language{}, 0)
 	if err != nil {
 		t.Errorf("PutLanguage should not return error, but got %v", err)
 	}
@@ -230,7 +243,7 @@ func Test_PutLanguage_ShouldReturnStructError(t *testing.T) {
 
 	mr := mockRepository{err: expected}
 
-	_, err := mr.PutLanguage("", models.Language{})
+	_, _, err := mr.PutLanguage(context.Background(), "", models.Language{}, 0)
 	if !reflect.DeepEqual(err, expected) {
 		t.Errorf("PutLanguage should return %v, but got %v", expected, err)
 	}
@@ -241,19 +254,55 @@ func Test_PatchLanguage_ShouldReturnStructError(t *testing.T) {
 
 	mr := mockRepository{err: expected}
 
-	err := mr.PatchLanguage("", models.Language{})
+	_, err := mr.PatchLanguage(context.Background(), "", models.Language{}, 0)
 	if !reflect.DeepEqual(err, expected) {
 		t.Errorf("PatchLanguage should return %v, but got %v", expected, err)
 	}
 }
 
+func Test_MergePatchLanguage_ShouldReturnStructError(t *testing.T) {
+	expected := errors.New("golang")
+
+	mr := mockRepository{err: expected}
+
+	_, err := mr.MergePatchLanguage(context.Background(), "", models.Patch{}, 0)
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("MergePatchLanguage should return %v, but got %v", expected, err)
+	}
+}
+
+func Test_JSONPatchLanguage_ShouldReturnStructError(t *testing.T) {
+	expected := errors.New("golang")
+
+	mr := mockRepository{err: expected}
+
+	_, err := mr.JSONPatchLanguage(context.Background(), "", models.JSONPatch{}, 0)
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("JSONPatchLanguage should return %v, but got %v", expected, err)
+	}
+}
+
 func Test_DeleteLanguage_ShouldReturnStructError(t *testing.T) {
 	expected := errors.New("golang")
 
 	mr := mockRepository{err: expected}
 
-	err := mr.DeleteLanguage("")
+	err := mr.DeleteLanguage(context.Background(), "", 0)
 	if !reflect.DeepEqual(err, expected) {
 		t.Errorf("DeleteLanguage should return %v, but got %v", expected, err)
 	}
 }
+
+func Test_BatchLanguages_ShouldSucceedForEveryOperationWithoutStructResults(t *testing.T) {
+	mr := mockRepository{id: "1", isUpserted: true}
+
+	results, err := mr.BatchLanguages(context.Background(), []models.BatchOperation{{Kind: models.BatchCreate}, {Kind: models.BatchReplace, Id: "2"}}, false)
+	if err != nil {
+		t.Error("Error applying batch:", err)
+	}
+
+	expected := []models.BatchResult{{Id: "1"}, {Id: "2", Upserted: true}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("BatchLanguages should return %v, but got %v", expected, results)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"languages-api/internal/models"
 
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,9 +24,8 @@ var (
 	cfg = config.Config{
 		AppName:    config.AppName,
 		ConfigPath: "",
-		Collection: "",
-		Database:   "",
-		DBURL:      "",
+		Driver:     "",
+		Mongo:      config.MongoConfig{},
 		Port:       "",
 		Version:    config.Version,
 	}
@@ -275,34 +275,1128 @@ func Test_GetLanguagesHandler_ShouldReturnErrorMessageOnGetLanguagesError(t *tes
 	}
 }
 
+func Test_GetLanguagesHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{errs: []error{fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
 func Test_GetLanguagesHandler_ShouldHaveContentTypeHeaderOnSuccess(t *testing.T) {
 	expected := "application/json"
 
-	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: models.Languages{}})
+
+	handler.ServeHTTP(rr, req)
+
+	ct := rr.Header().Get("Content-Type")
+
+	if ct != expected {
+		t.Errorf("Expected Content-Type of %s, but got %v", expected, ct)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus200OnSuccess(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnLanguagesOnSuccess(t *testing.T) {
+	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
+	if err != nil {
+		t.Error("Error parsing timestamp:", err)
+	}
+
+	expected := models.Languages{
+		Languages: []models.Language{
+			models.Language{
+				Id:   primitive.NewObjectID(),
+				Name: "Golang",
+				Creators: []string{
+					"Robert Griesemer",
+					"Rob Pike",
+					"Ken Thompson",
+				},
+				Extensions: []string{
+					".go",
+				},
+				FirstAppeared: &firstAppeared,
+				Year:          2009,
+				Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
+			},
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: expected})
+
+	handler.ServeHTTP(rr, req)
+
+	var respBody models.Languages
+
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(respBody, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, respBody)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnInvalidLimit(t *testing.T) {
+	expected := "Invalid pagination parameters: limit must be a positive integer"
+
+	req, err := http.NewRequest(http.MethodGet, "/?limit=0", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != expected {
+		t.Errorf("Expected 400 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnInvalidCursor(t *testing.T) {
+	for _, query := range []string{"after=not-a-cursor", "before=e30", "after=" + encodeCursor(cursor{Id: primitive.NewObjectID()}) + "&offset=2"} {
+		req, err := http.NewRequest(http.MethodGet, "/?"+query, nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s but got %v", query, rr.Code)
+		}
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnUnknownSortField(t *testing.T) {
+	expected := `Invalid sort parameter: unknown sort field "creators", expected one of: name, year, firstAppeared`
+
+	req, err := http.NewRequest(http.MethodGet, "/?sort=-year,creators", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != expected {
+		t.Errorf("Expected 400 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnCursorForDifferentSort(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/?sort=name&after="+encodeCursor(cursor{Sort: "-year", Id: primitive.NewObjectID(), Year: 1995}), nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnInvalidFilter(t *testing.T) {
+	for _, query := range []string{"year[between]=1990", "name[gte]=C", "firstAppeared[after]=yesterday", "year[lt]=soon"} {
+		req, err := http.NewRequest(http.MethodGet, "/?"+query, nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s but got %v", query, rr.Code)
+		}
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400PointingAtInvalidFilterExpression(t *testing.T) {
+	expected := `Invalid filter expression: column 9: expected a year, got "\"1990\""`
+
+	req, err := http.NewRequest(http.MethodGet, "/?filter="+url.QueryEscape(`year >= "1990"`), nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, rr.Body.String())
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldKeepFilterExpressionInLinks(t *testing.T) {
+	ls := models.Languages{Languages: []models.Language{{Id: primitive.NewObjectID(), Name: "A"}, {Id: primitive.NewObjectID(), Name: "B"}}, Total: 2}
+	expression := `year >= 1990 and creators has "Anders Hejlsberg"`
+	expected := fmt.Sprintf(`</?%s>; rel="next"`, url.Values{"limit": {"1"}, "after": {encodeCursor(cursor{Id: ls.Languages[0].Id})}, "filter": {expression}}.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, "/?limit=1&filter="+url.QueryEscape(expression), nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls})
+
+	handler.ServeHTTP(rr, req)
+
+	if link := rr.Header().Get("Link"); link != expected {
+		t.Errorf("Expected Link of %s, but got %s", expected, link)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldSetNextLinkWhenMoreLanguagesExist(t *testing.T) {
+	ls := models.Languages{
+		Languages: []models.Language{{Id: primitive.NewObjectID(), Name: "A"}, {Id: primitive.NewObjectID(), Name: "B"}, {Id: primitive.NewObjectID(), Name: "C"}},
+		Total:     5,
+	}
+	expected := fmt.Sprintf(`</?%s>; rel="next"`, url.Values{"limit": {"2"}, "after": {encodeCursor(cursor{Id: ls.Languages[1].Id})}, "year": {"1995"}}.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, "/?limit=2&year=1995", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls})
+
+	handler.ServeHTTP(rr, req)
+
+	if link := rr.Header().Get("Link"); link != expected {
+		t.Errorf("Expected Link of %s, but got %s", expected, link)
+	}
+
+	var respBody models.Languages
+
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(respBody.Languages) != 2 || respBody.Total != 5 {
+		t.Errorf("Expected the first 2 of 5 languages but got %v", respBody)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldSetPrevLinkAfterCursor(t *testing.T) {
+	ls := models.Languages{Languages: []models.Language{{Id: primitive.NewObjectID(), Name: "D"}, {Id: primitive.NewObjectID(), Name: "E"}}, Total: 5}
+	expected := fmt.Sprintf(`</?%s>; rel="prev"`, url.Values{"limit": {"2"}, "before": {encodeCursor(cursor{Id: ls.Languages[0].Id})}}.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, "/?limit=2&after="+encodeCursor(cursor{Id: primitive.NewObjectID()}), nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls})
+
+	handler.ServeHTTP(rr, req)
+
+	if link := rr.Header().Get("Link"); link != expected {
+		t.Errorf("Expected Link of %s, but got %s", expected, link)
+	}
+}
+
+func Test_SearchHandler_ShouldReturnStatus400WithoutQuery(t *testing.T) {
+	for _, query := range []string{"", "q=", "q=%20"} {
+		req, err := http.NewRequest(http.MethodGet, "/search?"+query, nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := ctrl.SearchHandler(mockRepository{})
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q but got %v", query, rr.Code)
+		}
+	}
+}
+
+func Test_SearchHandler_ShouldReturnStatus400OnCursor(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/search?q=java&after="+encodeCursor(cursor{Id: primitive.NewObjectID()}), nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.SearchHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
+func Test_SearchHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/search?q=java", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.SearchHandler(mockRepository{errs: []error{models.ErrTimeout}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_SearchHandler_ShouldReturnResultsWithLinks(t *testing.T) {
+	rs := models.SearchResults{
+		Results: []models.SearchResult{{Language: models.Language{Id: primitive.NewObjectID(), Name: "Java"}, Score: 8, Highlights: map[string][]string{"name": {"<em>Java</em>"}}}},
+		Total:   3,
+	}
+	expected := fmt.Sprintf(`</search?%s>; rel="prev", </search?%s>; rel="next"`,
+		url.Values{"q": {"java"}, "limit": {"1"}, "year": {"1995"}}.Encode(),
+		url.Values{"q": {"java"}, "limit": {"1"}, "offset": {"2"}, "year": {"1995"}}.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, "/search?q=java&limit=1&offset=1&year=1995", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.SearchHandler(mockRepository{rs: rs})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	if link := rr.Header().Get("Link"); link != expected {
+		t.Errorf("Expected Link of %s, but got %s", expected, link)
+	}
+
+	var respBody models.SearchResults
+
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(respBody, rs) {
+		t.Errorf("Expected %+v, but got %+v", rs, respBody)
+	}
+}
+
+func Test_StatsHandler_ShouldReturnStatus400OnInvalidTop(t *testing.T) {
+	for _, top := range []string{"0", "-1", "ten"} {
+		req, err := http.NewRequest(http.MethodGet, "/stats?top="+top, nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := ctrl.StatsHandler(mockRepository{})
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for top=%s but got %v", top, rr.Code)
+		}
+	}
+}
+
+func Test_StatsHandler_ShouldReturnStatus404OnUnknownSection(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/stats/popularity", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"section": "popularity"})
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.StatsHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %v", rr.Code)
+	}
+}
+
+func Test_StatsHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/stats", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.StatsHandler(mockRepository{err: models.ErrTimeout})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_StatsHandler_ShouldReturnStatus500OnError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/stats", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.StatsHandler(mockRepository{err: errors.New("aggregate")})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 but got %v", rr.Code)
+	}
+}
+
+func Test_StatsHandler_ShouldReturnTopCreatorsSection(t *testing.T) {
+	stats := models.Stats{TopCreators: []models.NameCount{{Name: "Anders Hejlsberg", Count: 2}, {Name: "Bjarne Stroustrup", Count: 1}}}
+	expected := stats.TopCreators[:1]
+
+	req, err := http.NewRequest(http.MethodGet, "/stats/topCreators?top=1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"section": "topCreators"})
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.StatsHandler(mockRepository{stats: stats})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody []models.NameCount
+
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(respBody, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, respBody)
+	}
+}
+
+func Test_GetExtensionsHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/extensions", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetExtensionsHandler(mockRepository{errs: []error{models.ErrTimeout}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_GetExtensionHandler_ShouldReturnStatus404IfNoLanguageUsesIt(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/extensions/.xyz", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"ext": ".xyz"})
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetExtensionHandler(mockRepository{extension: models.Extension{Extension: ".xyz", Languages: []models.LanguageRef{}}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %v", rr.Code)
+	}
+}
+
+func Test_GetExtensionHandler_ShouldReturnStatus500OnError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/extensions/.go", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"ext": ".go"})
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetExtensionHandler(mockRepository{errs: []error{errors.New("find")}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 but got %v", rr.Code)
+	}
+}
+
+func Test_ClassifyHandler_ShouldReturnStatus400WithoutFilename(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/classify?filename=", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.ClassifyHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
+func Test_ClassifyHandler_ShouldReturnClassification(t *testing.T) {
+	class := models.Classification{
+		Filename:   "util.h",
+		Extension:  ".h",
+		Candidates: []models.LanguageRef{{Id: primitive.NewObjectID(), Name: "C"}, {Id: primitive.NewObjectID(), Name: "C++"}},
+		Ambiguous:  true,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/classify?filename=util.h", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.ClassifyHandler(mockRepository{class: class})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody models.Classification
+
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(respBody, class) {
+		t.Errorf("Expected %+v, but got %+v", class, respBody)
+	}
+}
+
+func Test_DetectHandler_ShouldReturnStatus400WithoutContentOrFilename(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/detect", http.NoBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.DetectHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
+func Test_DetectHandler_ShouldReturnStatus413OnLargeFile(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/detect", bytes.NewBuffer(bytes.Repeat([]byte("x"), maxDetectBytes+1)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.DetectHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 but got %v", rr.Code)
+	}
+}
+
+func Test_DetectHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/detect?filename=main.go", http.NoBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.DetectHandler(mockRepository{errs: []error{models.ErrTimeout}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_DetectHandler_ShouldReturnGuesses(t *testing.T) {
+	detection := models.Detection{Guesses: []models.Guess{
+		{Language: models.LanguageRef{Id: primitive.NewObjectID(), Name: "Python"}, Score: 10, Reasons: []string{"shebang python3"}},
+	}}
+
+	req, err := http.NewRequest(http.MethodPost, "/detect", bytes.NewBufferString("#!/usr/bin/env python3\n"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.DetectHandler(mockRepository{detection: detection})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody models.Detection
+
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(respBody, detection) {
+		t.Errorf("Expected %+v, but got %+v", detection, respBody)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnUnknownFacet(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/?facets=year,popularity", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnFacetsNextToLanguages(t *testing.T) {
+	ls := models.Languages{Languages: []models.Language{
+		{Id: primitive.NewObjectID(), Name: "A", Creators: []string{"X"}, Year: 1990},
+		{Id: primitive.NewObjectID(), Name: "B", Creators: []string{"X", "Y"}, Year: 1991},
+	}, Total: 3}
+	expected := `{"languages":[{"name":"A"}],"total":3,"facets":{"decade":[{"year":1990,"count":2}],"creators":[{"name":"X","count":2},{"name":"Y","count":1}]}}` + "\n"
+	expectedLink := fmt.Sprintf(`</?%s>; rel="next"`, url.Values{"limit": {"1"}, "after": {encodeCursor(cursor{Id: ls.Languages[0].Id})}, "fields": {"name"}, "facets": {"creators,decade"}}.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, "/?fields=name&limit=1&facets=creators,decade", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, rr.Body.String())
+	}
+
+	if link := rr.Header().Get("Link"); link != expectedLink {
+		t.Errorf("Expected Link of %s, but got %s", expectedLink, link)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldStreamLanguagesAsNDJSON(t *testing.T) {
+	ls := models.Languages{Languages: []models.Language{{Id: primitive.NewObjectID(), Name: "A", Year: 1990}, {Id: primitive.NewObjectID(), Name: "B", Year: 1991}}, Total: 2}
+	expected := `{"name":"A"}` + "\n" + `{"name":"B"}` + "\n"
+
+	req, err := http.NewRequest(http.MethodGet, "/?fields=name&limit=1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Expected Content-Type of application/x-ndjson, but got %s", contentType)
+	}
+
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, rr.Body.String())
+	}
+
+	if !rr.Flushed {
+		t.Error("Expected streamed languages to be flushed")
+	}
+
+	if link := rr.Header().Get("Link"); link != "" {
+		t.Errorf("Expected no Link header, but got %s", link)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldStreamNothingWhenNoLanguagesMatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Accept", "application/json;q=0.5, application/x-ndjson")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/x-ndjson" || rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty 200 stream, but got %v %s %q", rr.Code, rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldNotStreamIfNDJSONIsRefused(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Accept", "application/json, application/x-ndjson;q=0")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: models.Languages{Languages: []models.Language{}}})
+
+	handler.ServeHTTP(rr, req)
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type of application/json, but got %s", contentType)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnStreamWithBeforeOrFacets(t *testing.T) {
+	for _, target := range []string{"/?before=" + encodeCursor(cursor{Id: primitive.NewObjectID()}), "/?facets=year"} {
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Set("Accept", "application/x-ndjson")
+
+		rr := httptest.NewRecorder()
+		handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s but got %v", target, rr.Code)
+		}
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus504OnStreamTimeoutBeforeAnyLanguage(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{errs: []error{models.ErrTimeout}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldAbortStreamOnErrorAfterFirstLanguage(t *testing.T) {
+	ls := models.Languages{Languages: []models.Language{{Id: primitive.NewObjectID(), Name: "A", Year: 1990}}}
+
+	req, err := http.NewRequest(http.MethodGet, "/?fields=name", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls, errs: []error{errors.New("cursor error")}})
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("Expected the handler to abort, but got %v", recovered)
+		}
+
+		if rr.Code != http.StatusOK || rr.Body.String() != `{"name":"A"}`+"\n" {
+			t.Errorf("Expected the first language to have been sent, but got %v %s", rr.Code, rr.Body.String())
+		}
+	}()
+
+	handler.ServeHTTP(rr, req)
+}
+
+func Test_BatchHandler_ShouldReturnStatus400OnDecodeError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte("Invalid request body")))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.BatchHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != "Invalid request body" {
+		t.Errorf("Expected 400 Invalid request body but got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func Test_BatchHandler_ShouldReturnStatus400OnEmptyBatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(`{"operations": []}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.BatchHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != "A batch must have at least one operation" {
+		t.Errorf("Expected 400 but got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func Test_BatchHandler_ShouldReturnStatus400WhenBatchExceedsMaxBatchSize(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(`{"operations": [{"op": "delete", "id": "1"}, {"op": "delete", "id": "2"}, {"op": "delete", "id": "3"}]}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := (&Controller{Config: config.Config{HTTP: config.HTTPConfig{MaxBatchSize: 2}}}).BatchHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != "A batch may have at most 2 operations" {
+		t.Errorf("Expected 400 but got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func Test_BatchHandler_ShouldReturnStatusOfEachOperation(t *testing.T) {
+	body := `{"operations": [
+		{"op": "create", "language": {"name": "Golang"}},
+		{"op": "replace", "id": "2", "ifMatch": "\"1\"", "language": {"name": "Carbon"}},
+		{"op": "patch", "id": "3", "language": {"year": 2012}},
+		{"op": "delete", "id": "4", "ifMatch": "*"},
+		{"op": "rename", "id": "5"},
+		{"op": "delete", "id": "6", "ifMatch": "1"}
+	]}`
+
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.BatchHandler(mockRepository{id: "1", isUpserted: true})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected 200 with JSON but got %v %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	var respBody batchResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Fatal("Error decoding response:", err)
+	}
+
+	expected := []batchResult{
+		{Status: http.StatusCreated, Id: "1"},
+		{Status: http.StatusCreated, Id: "2"},
+		{Status: http.StatusOK, Id: "3"},
+		{Status: http.StatusNoContent, Id: "4"},
+		{Status: http.StatusBadRequest, Id: "5", Error: `Unknown operation "rename", expected one of create, replace, patch or delete`},
+		{Status: http.StatusBadRequest, Id: "6", Error: "Invalid ifMatch"},
+	}
+	if !reflect.DeepEqual(respBody.Results, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, respBody.Results)
+	}
+}
+
+func Test_BatchHandler_ShouldReturnStatus428ForOperationsWithoutIfMatchWhenRequired(t *testing.T) {
+	body := `{"operations": [{"op": "create", "language": {"name": "Golang"}}, {"op": "delete", "id": "2"}]}`
+
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := (&Controller{Config: config.Config{HTTP: config.HTTPConfig{RequireIfMatch: true}}}).BatchHandler(mockRepository{id: "1"})
+
+	handler.ServeHTTP(rr, req)
+
+	var respBody batchResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Fatal("Error decoding response:", err)
+	}
+
+	expected := []batchResult{
+		{Status: http.StatusCreated, Id: "1"},
+		{Status: http.StatusPreconditionRequired, Id: "2", Error: "An ifMatch is required to modify a language"},
+	}
+	if !reflect.DeepEqual(respBody.Results, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, respBody.Results)
+	}
+}
+
+func Test_BatchHandler_ShouldApplyNothingFromAnAtomicBatchWithAnInvalidOperation(t *testing.T) {
+	body := `{"atomic": true, "operations": [{"op": "delete", "id": "1"}, {"op": "rename", "id": "2"}]}`
+
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.BatchHandler(mockRepository{err: errors.New("the batch should not be passed on")})
+
+	handler.ServeHTTP(rr, req)
+
+	var respBody batchResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Fatal("Error decoding response:", err)
+	}
+
+	if len(respBody.Results) != 2 || respBody.Results[0].Status != http.StatusFailedDependency || respBody.Results[1].Status != http.StatusBadRequest {
+		t.Errorf("Expected 424 and 400 but got %+v", respBody.Results)
+	}
+}
+
+func Test_BatchHandler_ShouldReturnStatusOfEachFailedOperation(t *testing.T) {
+	body := `{"operations": [
+		{"op": "patch", "id": "1"}, {"op": "delete", "id": "2"}, {"op": "replace", "id": "3"}, {"op": "create"},
+		{"op": "patch", "id": "5"}, {"op": "delete", "id": "6"}, {"op": "replace", "id": "7"}, {"op": "patch", "id": "8"}
+	]}`
+
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.BatchHandler(mockRepository{results: []models.BatchResult{
+		{Id: "1", Err: models.ErrNotFound},
+		{Id: "2", Err: models.ErrNotFound},
+		{Id: "3", Err: models.ErrPreconditionFailed},
+		{Id: "4", Err: models.ConflictError{Id: "9"}},
+		{Err: models.ErrInvalidId},
+		{Id: "6", Err: models.ErrNotApplied},
+		{Id: "7", Err: models.ErrTimeout},
+		{Id: "8", Err: errors.New("unexpected error")},
+	}})
+
+	handler.ServeHTTP(rr, req)
+
+	var respBody batchResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Fatal("Error decoding response:", err)
+	}
+
+	expected := []batchResult{
+		{Status: http.StatusNotFound, Id: "1", Error: "No language found with that id to update"},
+		{Status: http.StatusNotFound, Id: "2", Error: "No language found with that id to delete"},
+		{Status: http.StatusPreconditionFailed, Id: "3", Error: "The language has been modified since it was retrieved"},
+		{Status: http.StatusConflict, Id: "4", Error: "A language with that name already exists with id 9"},
+		{Status: http.StatusBadRequest, Id: "5", Error: "The given id is not a valid id"},
+		{Status: http.StatusFailedDependency, Id: "6", Error: "Not applied as another operation in the batch failed"},
+		{Status: http.StatusGatewayTimeout, Id: "7", Error: "The database did not respond in time"},
+		{Status: http.StatusInternalServerError, Id: "8", Error: "An error occurred processing this request"},
+	}
+	if !reflect.DeepEqual(respBody.Results, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, respBody.Results)
+	}
+}
+
+func Test_BatchHandler_ShouldReturnStatus501WhenTransactionsAreUnsupported(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(`{"atomic": true, "operations": [{"op": "delete", "id": "1"}]}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.BatchHandler(mockRepository{err: models.ErrTransactionsUnsupported})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotImplemented || rr.Body.String() != "The database does not support atomic batches" {
+		t.Errorf("Expected 501 but got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func Test_BatchHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(`{"operations": [{"op": "delete", "id": "1"}]}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.BatchHandler(mockRepository{err: fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout || rr.Body.String() != "The database did not respond in time" {
+		t.Errorf("Expected 504 but got %v %s", rr.Code, rr.Body.String())
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldReturnStatus200OnMergePatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"wiki": null, "firstAppeared": null}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json; charset=utf-8")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldReturnStatus400OnMergePatchRemovingName(t *testing.T) {
+	expected := "A language's name cannot be removed"
+
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"name": null}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != expected {
+		t.Errorf("Expected 400 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldReturnStatus400OnMergePatchDecodeError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"creators": "Rob Pike"}`)))
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
 
 	rr := httptest.NewRecorder()
-	handler := ctrl.GetLanguagesHandler(mockRepository{ls: models.Languages{}})
+	handler := ctrl.UpdateLanguageHandler(mockRepository{})
 
 	handler.ServeHTTP(rr, req)
 
-	ct := rr.Header().Get("Content-Type")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
 
-	if ct != expected {
-		t.Errorf("Expected Content-Type of %s, but got %v", expected, ct)
+func Test_UpdateLanguageHandler_ShouldReturnStatus404OnMergePatchNotFoundError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"year": 2012}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{err: models.ErrNotFound})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %v", rr.Code)
 	}
 }
 
-func Test_GetLanguagesHandler_ShouldReturnStatus200OnSuccess(t *testing.T) {
-	req, err := http.NewRequest(http.MethodGet, "/", nil)
+func Test_UpdateLanguageHandler_ShouldReturnStatus200OnJSONPatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`[{"op": "add", "path": "/creators/-", "value": "Russ Cox"}]`)))
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("Content-Type", "application/json-patch+json")
 
 	rr := httptest.NewRecorder()
-	handler := ctrl.GetLanguagesHandler(mockRepository{})
+	handler := ctrl.UpdateLanguageHandler(mockRepository{})
 
 	handler.ServeHTTP(rr, req)
 
@@ -311,51 +1405,183 @@ func Test_GetLanguagesHandler_ShouldReturnStatus200OnSuccess(t *testing.T) {
 	}
 }
 
-func Test_GetLanguagesHandler_ShouldReturnLanguagesOnSuccess(t *testing.T) {
-	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
+func Test_UpdateLanguageHandler_ShouldReturnStatus400OnInvalidJSONPatch(t *testing.T) {
+	expected := `Invalid patch: invalid operation 0: unknown op "append", expected one of: add, remove, replace, move, copy, test`
+
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`[{"op": "append", "path": "/creators", "value": "Russ Cox"}]`)))
 	if err != nil {
-		t.Error("Error parsing timestamp:", err)
+		t.Error(err)
 	}
+	req.Header.Set("Content-Type", "application/json-patch+json")
 
-	expected := models.Languages{
-		Languages: []models.Language{
-			models.Language{
-				Id:   primitive.NewObjectID(),
-				Name: "Golang",
-				Creators: []string{
-					"Robert Griesemer",
-					"Rob Pike",
-					"Ken Thompson",
-				},
-				Extensions: []string{
-					".go",
-				},
-				FirstAppeared: &firstAppeared,
-				Year:          2009,
-				Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
-			},
-		},
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != expected {
+		t.Errorf("Expected 400 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
 	}
+}
 
-	req, err := http.NewRequest(http.MethodGet, "/", nil)
+func Test_UpdateLanguageHandler_ShouldReturnStatus400OnJSONPatchDecodeError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"op": "remove", "path": "/wiki"}`)))
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("Content-Type", "application/json-patch+json")
 
 	rr := httptest.NewRecorder()
-	handler := ctrl.GetLanguagesHandler(mockRepository{ls: expected})
+	handler := ctrl.UpdateLanguageHandler(mockRepository{})
 
 	handler.ServeHTTP(rr, req)
 
-	var respBody models.Languages
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
 
-	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+func Test_UpdateLanguageHandler_ShouldReturnStatus409OnPatchError(t *testing.T) {
+	expected := "Operation 1 of the patch failed, as the value at /wiki is not the one tested"
+
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`[{"op": "remove", "path": "/year"}, {"op": "test", "path": "/wiki", "value": ""}]`)))
 	if err != nil {
 		t.Error(err)
 	}
+	req.Header.Set("Content-Type", "application/json-patch+json")
 
-	if !reflect.DeepEqual(respBody, expected) {
-		t.Errorf("Expected %+v but got %+v", expected, respBody)
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{err: models.PatchError{Operation: 1, Reason: "the value at /wiki is not the one tested"}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict || rr.Body.String() != expected {
+		t.Errorf("Expected 409 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
+	}
+}
+
+func Test_CreateLanguageHandler_ShouldHaveETagHeaderOnSuccess(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.CreateLanguageHandler(mockRepository{id: "1"})
+
+	handler.ServeHTTP(rr, req)
+
+	if etag := rr.Header().Get("ETag"); rr.Code != http.StatusCreated || etag != `"1"` {
+		t.Errorf(`Expected 201 with ETag "1", but got %v with %v`, rr.Code, etag)
+	}
+}
+
+func Test_UpsertLanguageHandler_ShouldHaveETagHeaderOnSuccess(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", `"3"`)
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpsertLanguageHandler(mockRepository{revision: 4})
+
+	handler.ServeHTTP(rr, req)
+
+	if etag := rr.Header().Get("ETag"); rr.Code != http.StatusOK || etag != `"4"` {
+		t.Errorf(`Expected 200 with ETag "4", but got %v with %v`, rr.Code, etag)
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldHaveETagHeaderOnSuccess(t *testing.T) {
+	for _, contentType := range []string{"application/json", "application/merge-patch+json", "application/json-patch+json"} {
+		body := `{"name":"Go"}`
+		if contentType == "application/json-patch+json" {
+			body = `[{"op": "replace", "path": "/name", "value": "Go"}]`
+		}
+
+		req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+
+		rr := httptest.NewRecorder()
+		handler := ctrl.UpdateLanguageHandler(mockRepository{revision: 4})
+
+		handler.ServeHTTP(rr, req)
+
+		if etag := rr.Header().Get("ETag"); rr.Code != http.StatusOK || etag != `"4"` {
+			t.Errorf(`%s: Expected 200 with ETag "4", but got %v with %v`, contentType, rr.Code, etag)
+		}
+	}
+}
+
+func Test_UpsertLanguageHandler_ShouldReturnStatus412IfNothingMatchesAnyIfMatch(t *testing.T) {
+	expected := "No language exists with that id"
+
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", "*")
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpsertLanguageHandler(mockRepository{err: models.ErrPreconditionFailed})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPreconditionFailed || rr.Body.String() != expected {
+		t.Errorf("Expected 412 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
+	}
+}
+
+func Test_revision_ShouldRequireTheLanguageToExistForAnyIfMatch(t *testing.T) {
+	for header, expected := range map[string]int64{"": 0, "*": models.AnyRevision, ` "3" `: 3} {
+		revision, err := ctrl.revision(header)
+		if err != nil || revision != expected {
+			t.Errorf("revision(%q) should return %d, but got %d and %v", header, expected, revision, err)
+		}
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnStatus400OnUnknownField(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/?fields=name,popularity", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldReturnOnlySelectedFields(t *testing.T) {
+	ls := models.Languages{Languages: []models.Language{{Id: primitive.NewObjectID(), Name: "A", Year: 1990}, {Id: primitive.NewObjectID(), Name: "B", Year: 1991}}, Total: 3}
+	expected := `{"languages":[{"name":"A"}],"total":3}` + "\n"
+	expectedLink := fmt.Sprintf(`</?%s>; rel="next"`, url.Values{"limit": {"1"}, "after": {encodeCursor(cursor{Id: ls.Languages[0].Id})}, "fields": {"name"}}.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, "/?fields=name&limit=1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, rr.Body.String())
+	}
+
+	if link := rr.Header().Get("Link"); link != expectedLink {
+		t.Errorf("Expected Link of %s, but got %s", expectedLink, link)
 	}
 }
 
@@ -527,6 +1753,42 @@ func Test_GetLanguageHandler_ShouldReturnErrorMessageOnInternalError(t *testing.
 	}
 }
 
+func Test_GetLanguageHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguageHandler(mockRepository{err: fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_GetLanguageHandler_ShouldReturnErrorMessageOnTimeoutError(t *testing.T) {
+	expected := "The database did not respond in time"
+
+	req, err := http.NewRequest(http.MethodGet, "/1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguageHandler(mockRepository{err: fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)})
+
+	handler.ServeHTTP(rr, req)
+
+	respBody := rr.Body.String()
+
+	if !reflect.DeepEqual(respBody, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, respBody)
+	}
+}
+
 func Test_GetLanguageHandler_ShouldHaveContentTypeHeaderOnSuccess(t *testing.T) {
 	expected := "application/json"
 
@@ -563,6 +1825,26 @@ func Test_GetLanguageHandler_ShouldReturnStatus200OnSuccess(t *testing.T) {
 	}
 }
 
+func Test_GetLanguageHandler_ShouldHaveETagHeaderOnSuccess(t *testing.T) {
+	expected := `"3"`
+
+	req, err := http.NewRequest(http.MethodGet, "/1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguageHandler(mockRepository{l: models.Language{Revision: 3}})
+
+	handler.ServeHTTP(rr, req)
+
+	etag := rr.Header().Get("ETag")
+
+	if etag != expected {
+		t.Errorf("Expected ETag of %s, but got %v", expected, etag)
+	}
+}
+
 func Test_GetLanguageHandler_ShouldReturnLanguageOnSuccess(t *testing.T) {
 	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
 	if err != nil {
@@ -607,6 +1889,44 @@ func Test_GetLanguageHandler_ShouldReturnLanguageOnSuccess(t *testing.T) {
 	}
 }
 
+func Test_GetLanguageHandler_ShouldReturnStatus400OnUnknownField(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/1?fields=name,nameKey", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
+func Test_GetLanguageHandler_ShouldReturnOnlySelectedFields(t *testing.T) {
+	expected := `{"name":"Golang","year":2009}` + "\n"
+
+	req, err := http.NewRequest(http.MethodGet, "/1?fields=year,name", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.GetLanguageHandler(mockRepository{l: models.Language{Id: primitive.NewObjectID(), Name: "Golang", Year: 2009, Revision: 2}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, rr.Body.String())
+	}
+
+	if etag := rr.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("Expected an ETag of the revision, but got %s", etag)
+	}
+}
+
 func Test_CreateLanguageHandler_ShouldHaveContentTypeHeaderOnDecodeError(t *testing.T) {
 	expected := "text/plain; charset=utf-8"
 
@@ -775,18 +2095,88 @@ func Test_CreateLanguageHandler_ShouldReturnErrorMessageOnInternalError(t *testi
 		Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
 	}
 
-	reqBody, err := json.Marshal(lang)
+	reqBody, err := json.Marshal(lang)
+	if err != nil {
+		t.Error(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(reqBody))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.CreateLanguageHandler(mockRepository{err: errors.New("internal server error")})
+
+	handler.ServeHTTP(rr, req)
+
+	respBody := rr.Body.String()
+
+	if !reflect.DeepEqual(respBody, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, respBody)
+	}
+}
+
+func Test_CreateLanguageHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.CreateLanguageHandler(mockRepository{err: fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_CreateLanguageHandler_ShouldReturnStatus409OnConflictError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.CreateLanguageHandler(mockRepository{err: models.ConflictError{Id: "5f1e7a4c2b3d4e5f6a7b8c9d"}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 but got %v", rr.Code)
+	}
+}
+
+func Test_CreateLanguageHandler_ShouldReturnStatus409OnDuplicateIdError(t *testing.T) {
+	expected := "A language with that id already exists"
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"_id":"5f1e7a4c2b3d4e5f6a7b8c9d","name":"Golang"}`)))
 	if err != nil {
 		t.Error(err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(reqBody))
+	rr := httptest.NewRecorder()
+	handler := ctrl.CreateLanguageHandler(mockRepository{err: models.ErrDuplicateId})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict || rr.Body.String() != expected {
+		t.Errorf("Expected 409 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
+	}
+}
+
+func Test_CreateLanguageHandler_ShouldReturnExistingIdOnConflictError(t *testing.T) {
+	expected := "A language with that name already exists with id 5f1e7a4c2b3d4e5f6a7b8c9d"
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"name":"Golang"}`)))
 	if err != nil {
 		t.Error(err)
 	}
 
 	rr := httptest.NewRecorder()
-	handler := ctrl.CreateLanguageHandler(mockRepository{err: errors.New("internal server error")})
+	handler := ctrl.CreateLanguageHandler(mockRepository{err: models.ConflictError{Id: "5f1e7a4c2b3d4e5f6a7b8c9d"}})
 
 	handler.ServeHTTP(rr, req)
 
@@ -1255,6 +2645,106 @@ func Test_UpsertLanguageHandler_ShouldReturnErrorMessageOnInternalError(t *testi
 	}
 }
 
+func Test_UpsertLanguageHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpsertLanguageHandler(mockRepository{err: fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_UpsertLanguageHandler_ShouldReturnStatus409OnConflictError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpsertLanguageHandler(mockRepository{err: models.ConflictError{Id: "5f1e7a4c2b3d4e5f6a7b8c9d"}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 but got %v", rr.Code)
+	}
+}
+
+func Test_UpsertLanguageHandler_ShouldReturnStatus400OnIdMismatchError(t *testing.T) {
+	expected := "The language's id does not match the given id"
+
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpsertLanguageHandler(mockRepository{err: models.ErrIdMismatch})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Body.String() != expected {
+		t.Errorf("Expected 400 with %q but got %v with %q", expected, rr.Code, rr.Body.String())
+	}
+}
+
+func Test_UpsertLanguageHandler_ShouldReturnStatus412OnPreconditionFailedError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", `"1"`)
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpsertLanguageHandler(mockRepository{err: models.ErrPreconditionFailed})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 but got %v", rr.Code)
+	}
+}
+
+func Test_UpsertLanguageHandler_ShouldReturnStatus428WithoutRequiredIfMatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := (&Controller{Config: config.Config{HTTP: config.HTTPConfig{RequireIfMatch: true}}}).UpsertLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected 428 but got %v", rr.Code)
+	}
+}
+
+func Test_UpsertLanguageHandler_ShouldReturnStatus400OnInvalidIfMatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", `W/"1"`)
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpsertLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
 func Test_UpsertLanguageHandler_ShouldHaveLocationHeaderOnIsUpsertedSuccess(t *testing.T) {
 	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
 	if err != nil {
@@ -1891,6 +3381,88 @@ func Test_UpdateLanguageHandler_ShouldReturnErrorMessageOnInternalError(t *testi
 	}
 }
 
+func Test_UpdateLanguageHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"year":2009}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{err: fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldReturnStatus409OnConflictError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"name":"Golang"}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{err: models.ConflictError{Id: "5f1e7a4c2b3d4e5f6a7b8c9d"}})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 but got %v", rr.Code)
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldReturnStatus412OnPreconditionFailedError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"year":2009}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", `"1"`)
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{err: models.ErrPreconditionFailed})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 but got %v", rr.Code)
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldReturnStatus428WithoutRequiredIfMatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"year":2009}`)))
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := (&Controller{Config: config.Config{HTTP: config.HTTPConfig{RequireIfMatch: true}}}).UpdateLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected 428 but got %v", rr.Code)
+	}
+}
+
+func Test_UpdateLanguageHandler_ShouldReturnStatus400OnInvalidIfMatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPatch, "/1", bytes.NewReader([]byte(`{"year":2009}`)))
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", `W/"1"`)
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.UpdateLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
 func Test_UpdateLanguageHandler_ShouldReturnStatus200OnSuccess(t *testing.T) {
 	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
 	if err != nil {
@@ -2147,6 +3719,72 @@ func Test_DeleteLanguageHandler_ShouldReturnErrorMessageOnInternalError(t *testi
 	}
 }
 
+func Test_DeleteLanguageHandler_ShouldReturnStatus504OnTimeoutError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodDelete, "/1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.DeleteLanguageHandler(mockRepository{err: fmt.Errorf("%w: %w", models.ErrTimeout, context.DeadlineExceeded)})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 but got %v", rr.Code)
+	}
+}
+
+func Test_DeleteLanguageHandler_ShouldReturnStatus412OnPreconditionFailedError(t *testing.T) {
+	req, err := http.NewRequest(http.MethodDelete, "/1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", `"1"`)
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.DeleteLanguageHandler(mockRepository{err: models.ErrPreconditionFailed})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 but got %v", rr.Code)
+	}
+}
+
+func Test_DeleteLanguageHandler_ShouldReturnStatus428WithoutRequiredIfMatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodDelete, "/1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := (&Controller{Config: config.Config{HTTP: config.HTTPConfig{RequireIfMatch: true}}}).DeleteLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected 428 but got %v", rr.Code)
+	}
+}
+
+func Test_DeleteLanguageHandler_ShouldReturnStatus400OnInvalidIfMatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodDelete, "/1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	req.Header.Set("If-Match", `W/"1"`)
+
+	rr := httptest.NewRecorder()
+	handler := ctrl.DeleteLanguageHandler(mockRepository{})
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 but got %v", rr.Code)
+	}
+}
+
 func Test_DeleteLanguageHandler_ShouldReturnStatus204OnSuccess(t *testing.T) {
 	req, err := http.NewRequest(http.MethodDelete, "/1", nil)
 	if err != nil {
@@ -2230,3 +3868,150 @@ func Test_NotFoundPageHandler_ShouldWriteInvalidURLMessage(t *testing.T) {
 		t.Errorf("Expected %+v but got %+v", expected, mrw.message)
 	}
 }
+
+func Test_filter_ShouldReadComparisonOperators(t *testing.T) {
+	gte, lt := int32(1990), int32(2000)
+	after := time.Date(1995, time.January, 1, 0, 0, 0, 0, time.UTC)
+	expected := models.Filter{
+		Creators:      []string{"Rob Pike", "Ken Thompson"},
+		FirstAppeared: models.Range[time.Time]{Gt: &after},
+		Year:          models.Range[int32]{Gte: &gte, Lt: &lt},
+	}
+
+	values, err := url.ParseQuery("year[gte]=1990&year[lt]=2000&firstAppeared[after]=1995-01-01&creators=Rob Pike,Ken Thompson")
+	if err != nil {
+		t.Error(err)
+	}
+
+	f, err := filter(values)
+	if err != nil {
+		t.Errorf("Unexpected error reading filters: %v", err)
+	}
+
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, f)
+	}
+}
+
+func Test_filter_ShouldReadMatchModes(t *testing.T) {
+	expected := models.Filter{Name: "java", NameMatch: models.MatchPrefix, Creators: []string{"jose"}, CreatorsMatch: models.MatchContains}
+
+	values, err := url.ParseQuery("name[prefix]=java&creators[contains]=jose")
+	if err != nil {
+		t.Error(err)
+	}
+
+	f, err := filter(values)
+	if err != nil {
+		t.Errorf("Unexpected error reading filters: %v", err)
+	}
+
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, f)
+	}
+
+	_, err = filter(url.Values{"name": {"java"}, "name[prefix]": {"ja"}})
+	if !errors.Is(err, errDuplicateFilter) {
+		t.Errorf("Expected errDuplicateFilter but got %v", err)
+	}
+}
+
+func Test_filter_ShouldReturnErrDuplicateFilterOnRepeatedParameter(t *testing.T) {
+	for _, query := range []string{"creators=Rob Pike&creators=Ken Thompson", "year=1990&year=2000", "wiki=a&wiki=b", "year=&year=1990"} {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Error(err)
+		}
+
+		_, err = filter(values)
+		if !errors.Is(err, errDuplicateFilter) {
+			t.Errorf("Expected errDuplicateFilter for %s but got %v", query, err)
+		}
+	}
+}
+
+func Test_filter_ShouldReadSetModes(t *testing.T) {
+	expected := models.Filter{
+		Creators:      []string{"rob", "ken"},
+		CreatorsMatch: models.MatchPrefix,
+		CreatorsSet:   models.SetNone,
+		Extensions:    []string{".h", ".hpp"},
+		ExtensionsSet: models.SetAny,
+	}
+
+	values, err := url.ParseQuery("creators[none][prefix]=rob,ken&extensions[any]=.h,.hpp")
+	if err != nil {
+		t.Error(err)
+	}
+
+	f, err := filter(values)
+	if err != nil {
+		t.Errorf("Unexpected error reading filters: %v", err)
+	}
+
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, f)
+	}
+
+	for _, query := range []string{"extensions[prefix]=.h", "creators[any][none]=rob", "creators[exact][prefix]=rob", "year[gte][lte]=1990"} {
+		values, err = url.ParseQuery(query)
+		if err != nil {
+			t.Error(err)
+		}
+
+		_, err = filter(values)
+		if !errors.Is(err, errUnknownOperator) {
+			t.Errorf("Expected errUnknownOperator for %s but got %v", query, err)
+		}
+	}
+
+	_, err = filter(url.Values{"extensions": {".h"}, "extensions[any]": {".c"}})
+	if !errors.Is(err, errDuplicateFilter) {
+		t.Errorf("Expected errDuplicateFilter but got %v", err)
+	}
+}
+
+func Test_GetLanguagesHandler_ShouldMatchArraysInTheRequestedSetMode(t *testing.T) {
+	ls := models.Languages{
+		Languages: []models.Language{
+			{Id: primitive.NewObjectID(), Name: "C", Extensions: []string{".c", ".h"}},
+			{Id: primitive.NewObjectID(), Name: "C++", Extensions: []string{".cpp", ".h", ".hpp"}},
+			{Id: primitive.NewObjectID(), Name: "Go", Extensions: []string{".go"}},
+		},
+		Total: 3,
+	}
+
+	for query, expected := range map[string][]string{
+		"extensions=.h,.hpp":       {"C++"},
+		"extensions[all]=.h,.hpp":  {"C++"},
+		"extensions[any]=.h,.hpp":  {"C", "C++"},
+		"extensions[none]=.h,.hpp": {"Go"},
+		"extensions[only]=.h,.c":   {"C"},
+	} {
+		req, err := http.NewRequest(http.MethodGet, "/?"+query, nil)
+		if err != nil {
+			t.Error(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := ctrl.GetLanguagesHandler(mockRepository{ls: ls})
+
+		handler.ServeHTTP(rr, req)
+
+		var respBody models.Languages
+
+		err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+		if err != nil {
+			t.Error(err)
+		}
+
+		var names []string
+		for _, l := range respBody.Languages {
+			names = append(names, l.Name)
+		}
+
+		if !reflect.DeepEqual(names, expected) || respBody.Total != int64(len(expected)) {
+			t.Errorf("Expected %v for %s but got %v of %d", expected, query, names, respBody.Total)
+		}
+	}
+}
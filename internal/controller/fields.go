@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"bytes"
+	"encoding/json"
+	"slices"
+)
+
+// projection encodes a language with only the fields a request selected, in the order models.Language declares
+// them, instead of with zero values for the rest. Nil fields encodes every field.
+type projection struct {
+	language models.Language
+	fields   []string
+}
+
+func (p projection) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(p.language)
+	if err != nil || len(p.fields) == 0 {
+		return data, err
+	}
+
+	var values map[string]json.RawMessage
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for _, field := range query.Fields {
+		if !slices.Contains(p.fields, field) {
+			continue
+		}
+
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+
+		// Fields that are left out when empty, such as heuristics, are encoded as null once selected
+		value, ok := values[field]
+		if !ok {
+			value = json.RawMessage("null")
+		}
+
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+// projectedLanguages is a page of languages encoded with only the fields a request selected
+type projectedLanguages struct {
+	// Projected takes the place of the embedded languages when encoding, as it is less deeply nested.
+	// It comes first so that the fields are encoded in the same order as models.Languages.
+	Projected []projection `json:"languages"`
+	models.Languages
+}
+
+func project(languages models.Languages, fields []string) projectedLanguages {
+	projected := projectedLanguages{Languages: languages, Projected: make([]projection, len(languages.Languages))}
+	for i, language := range languages.Languages {
+		projected.Projected[i] = projection{language: language, fields: fields}
+	}
+
+	return projected
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	errUnknownFilter   = errors.New("unknown filter")
+	errUnknownOperator = errors.New("unknown filter operator")
+	errInvalidValue    = errors.New("invalid filter value")
+	errDuplicateFilter = errors.New("filter given more than once")
+)
+
+// matchModes are the operators name and creators can be matched with
+var matchModes = map[string]models.MatchMode{"": models.MatchExact, "exact": models.MatchExact, "prefix": models.MatchPrefix, "contains": models.MatchContains}
+
+// setModes are the operators creators and extensions can be matched with
+var setModes = map[string]models.SetMode{"": models.SetAll, "all": models.SetAll, "any": models.SetAny, "none": models.SetNone, "only": models.SetExact}
+
+// filter reads the filters left in values once every other parameter, such as sort, limit and filter, has been removed.
+// Fields are matched case-insensitively. name and creators may be given a match mode in brackets, such as
+// name[prefix]=go, year and firstAppeared may be compared with an operator, such as year[gte]=1990,
+// and firstAppeared also accepts after and before. creators and extensions take comma separated values that
+// must all be held by default, or any, none or only them with a set mode such as extensions[any]=.h,.hpp.
+// creators can be given both, as in creators[none][contains]=rob,ken. A filter can only be given once.
+func filter(values url.Values) (f models.Filter, err error) {
+	for key := range values {
+		if len(values[key]) > 1 {
+			return models.Filter{}, fmt.Errorf("%w: %q", errDuplicateFilter, key)
+		}
+
+		value := values.Get(key)
+		if value == "" {
+			continue
+		}
+
+		field, operators := key, []string{""}
+		if i := strings.IndexByte(key, '['); i >= 0 && strings.HasSuffix(key, "]") {
+			field, operators = key[:i], strings.Split(key[i+1:len(key)-1], "][")
+		}
+
+		// Only the array fields take more than one operator
+		operator := operators[0]
+		if len(operators) > 1 && !strings.EqualFold(field, "creators") && !strings.EqualFold(field, "extensions") {
+			return models.Filter{}, fmt.Errorf("%w in %q, only creators and extensions take more than one", errUnknownOperator, key)
+		}
+
+		switch {
+		case strings.EqualFold(field, "name"):
+			err = setMatch(&f.Name, &f.NameMatch, key, operator, value)
+		case strings.EqualFold(field, "creators"):
+			err = setArray(&f.Creators, &f.CreatorsSet, &f.CreatorsMatch, key, operators, value)
+		case strings.EqualFold(field, "extensions"):
+			err = setArray(&f.Extensions, &f.ExtensionsSet, nil, key, operators, value)
+		case strings.EqualFold(field, "wiki"):
+			err = noOperator(key, operator)
+			f.Wiki = value
+		case strings.EqualFold(field, "year"):
+			err = setBound(&f.Year, key, operator, value, false, func(value string) (int32, error) {
+				year, err := strconv.ParseInt(value, 10, 32)
+				return int32(year), err
+			})
+		case strings.EqualFold(field, "firstAppeared"):
+			err = setBound(&f.FirstAppeared, key, operator, value, true, query.ParseDate)
+		default:
+			err = fmt.Errorf("%w %q", errUnknownFilter, key)
+		}
+
+		if err != nil {
+			return models.Filter{}, err
+		}
+	}
+
+	return f, nil
+}
+
+func noOperator(key string, operator string) error {
+	if operator != "" {
+		return fmt.Errorf("%w in %q, only name, creators, extensions, year and firstAppeared take operators", errUnknownOperator, key)
+	}
+
+	return nil
+}
+
+// setMatch sets a text filter and the mode the operator names, which must only be given once
+func setMatch(filter *string, mode *models.MatchMode, key string, operator string, value string) error {
+	m, ok := matchModes[operator]
+	if !ok {
+		return fmt.Errorf("%w in %q, expected exact, prefix or contains", errUnknownOperator, key)
+	}
+
+	if *filter != "" {
+		return fmt.Errorf("%w: %q", errDuplicateFilter, key)
+	}
+
+	*filter, *mode = value, m
+	return nil
+}
+
+// setArray sets an array filter to the comma separated values and the modes its operators name, which can be a set
+// mode and, if match isn't nil, a match mode for each value. The filter must only be given once.
+func setArray(filter *[]string, set *models.SetMode, match *models.MatchMode, key string, operators []string, value string) error {
+	var setGiven, matchGiven bool
+
+	for _, operator := range operators {
+		if s, ok := setModes[operator]; ok && !setGiven {
+			*set, setGiven = s, true
+			continue
+		}
+
+		if m, ok := matchModes[operator]; ok && match != nil && !matchGiven {
+			*match, matchGiven = m, true
+			continue
+		}
+
+		if match == nil {
+			return fmt.Errorf("%w in %q, expected all, any, none or only", errUnknownOperator, key)
+		}
+
+		return fmt.Errorf("%w in %q, expected all, any, none or only and exact, prefix or contains", errUnknownOperator, key)
+	}
+
+	if *filter != nil {
+		return fmt.Errorf("%w: %q", errDuplicateFilter, key)
+	}
+
+	*filter = strings.Split(value, ",")
+	return nil
+}
+
+// setBound sets the bound of r the operator names to the parsed value. after and before are only
+// accepted as names for gt and lt if dates is set.
+func setBound[T any](r *models.Range[T], key string, operator string, value string, dates bool, parse func(string) (T, error)) error {
+	bounds := map[string]**T{"": &r.Eq, "eq": &r.Eq, "gt": &r.Gt, "gte": &r.Gte, "lt": &r.Lt, "lte": &r.Lte}
+	if dates {
+		bounds["after"] = &r.Gt
+		bounds["before"] = &r.Lt
+	}
+
+	bound, ok := bounds[operator]
+	if !ok {
+		return fmt.Errorf("%w in %q", errUnknownOperator, key)
+	}
+
+	if *bound != nil {
+		return fmt.Errorf("%w: %q", errDuplicateFilter, key)
+	}
+
+	parsed, err := parse(value)
+	if err != nil {
+		return fmt.Errorf("%w for %q: %q", errInvalidValue, key, value)
+	}
+
+	*bound = &parsed
+	return nil
+}
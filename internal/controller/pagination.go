@@ -0,0 +1,271 @@
+package controller
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errInvalidLimit     = errors.New("limit must be a positive integer")
+	errInvalidOffset    = errors.New("offset must be a non-negative integer")
+	errInvalidCursor    = errors.New("invalid cursor")
+	errCursorSort       = errors.New("cursor was made for a different sort")
+	errCursorConflict   = errors.New("after and before cannot be used together")
+	errOffsetWithCursor = errors.New("offset cannot be used with after or before")
+	errCursorWithSearch = errors.New("search results can only be paged with limit and offset")
+	errBeforeWithStream = errors.New("streamed languages can only be paged with limit, offset and after")
+)
+
+// cursor marks a position in the language list by the id and sort fields of the language at that position.
+// Clients only ever see it base64 encoded, so what it holds can change without breaking them.
+type cursor struct {
+	Sort          string             `json:"sort,omitempty"`
+	Id            primitive.ObjectID `json:"id"`
+	Name          string             `json:"name,omitempty"`
+	Year          int32              `json:"year,omitempty"`
+	FirstAppeared *time.Time         `json:"firstAppeared,omitempty"`
+}
+
+// newCursor marks the position of language in the list sorted by sort
+func newCursor(language models.Language, sort []models.SortField) cursor {
+	c := cursor{Sort: query.FormatSort(sort), Id: language.Id}
+	for _, f := range sort {
+		switch f.Field {
+		case query.FieldName:
+			c.Name = language.Name
+		case query.FieldYear:
+			c.Year = language.Year
+		case query.FieldFirstAppeared:
+			c.FirstAppeared = language.FirstAppeared
+		}
+	}
+
+	return c
+}
+
+// anchor is the language at the cursor's position, holding only what Find needs to find it
+func (c cursor) anchor() *models.Language {
+	return &models.Language{Id: c.Id, Name: c.Name, Year: c.Year, FirstAppeared: c.FirstAppeared}
+}
+
+func encodeCursor(c cursor) string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		// A cursor only holds an ObjectID, which always marshals
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, sort []models.SortField) (c cursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, errInvalidCursor
+	}
+
+	err = json.Unmarshal(data, &c)
+	if err != nil || c.Id.IsZero() {
+		return cursor{}, errInvalidCursor
+	}
+
+	if c.Sort != query.FormatSort(sort) {
+		return cursor{}, errCursorSort
+	}
+
+	return c, nil
+}
+
+// page is the part of the language list a GET / request asked for
+type page struct {
+	limit  int64
+	offset int64
+	sort   []models.SortField
+	after  *models.Language
+	before *models.Language
+}
+
+// page reads limit, offset, after and before from query and removes them, leaving only the filters.
+// Cursors must have been made for the given sort.
+// A missing limit falls back to HTTP.DefaultPageSize and any limit is capped at HTTP.MaxPageSize, either of which
+// may be 0 for every language.
+func (ctrl *Controller) page(values url.Values, sort []models.SortField) (p page, err error) {
+	return readPage(values, sort, ctrl.Config.HTTP.DefaultPageSize, ctrl.Config.HTTP.MaxPageSize)
+}
+
+// streamPage reads the part of the language list a streamed GET / request asked for like page does, but without
+// a default or cap on the limit, as streamed languages are never all held at once. Streams can't walk backwards,
+// so before isn't accepted.
+func (ctrl *Controller) streamPage(values url.Values, sort []models.SortField) (p page, err error) {
+	if values.Has("before") {
+		return page{}, errBeforeWithStream
+	}
+
+	return readPage(values, sort, 0, 0)
+}
+
+// readPage reads a page the way page describes, where a defaultSize or maxSize of 0 means there is no default or cap
+func readPage(values url.Values, sort []models.SortField, defaultSize int64, maxSize int64) (p page, err error) {
+	defer func() {
+		for _, key := range []string{"limit", "offset", "after", "before"} {
+			values.Del(key)
+		}
+	}()
+
+	p.sort = sort
+
+	p.limit = defaultSize
+	if values.Has("limit") {
+		p.limit, err = strconv.ParseInt(values.Get("limit"), 10, 64)
+		if err != nil || p.limit <= 0 {
+			return page{}, errInvalidLimit
+		}
+	}
+
+	if maxSize > 0 && (p.limit <= 0 || p.limit > maxSize) {
+		p.limit = maxSize
+	}
+
+	if values.Has("offset") {
+		p.offset, err = strconv.ParseInt(values.Get("offset"), 10, 64)
+		if err != nil || p.offset < 0 {
+			return page{}, errInvalidOffset
+		}
+	}
+
+	if values.Has("after") && values.Has("before") {
+		return page{}, errCursorConflict
+	}
+
+	if (values.Has("after") || values.Has("before")) && values.Has("offset") {
+		return page{}, errOffsetWithCursor
+	}
+
+	if values.Has("after") {
+		c, err := decodeCursor(values.Get("after"), sort)
+		if err != nil {
+			return page{}, err
+		}
+		p.after = c.anchor()
+	}
+
+	if values.Has("before") {
+		c, err := decodeCursor(values.Get("before"), sort)
+		if err != nil {
+			return page{}, err
+		}
+		p.before = c.anchor()
+	}
+
+	return p, nil
+}
+
+// findOptions asks for one language more than the page holds, so that trim can tell whether there is another page
+func (p page) findOptions() models.FindOptions {
+	opts := models.FindOptions{Offset: p.offset, Sort: p.sort, After: p.after, Before: p.before}
+	if p.limit > 0 {
+		opts.Limit = p.limit + 1
+	}
+
+	return opts
+}
+
+// streamOptions asks for exactly the languages of the page, as a stream has no links to other pages
+func (p page) streamOptions() models.FindOptions {
+	return models.FindOptions{Limit: p.limit, Offset: p.offset, Sort: p.sort, After: p.after}
+}
+
+// trim drops the extra language findOptions asked for and reports whether there are languages either side of the page
+func (p page) trim(languages []models.Language) (trimmed []models.Language, hasPrev bool, hasNext bool) {
+	extra := p.limit > 0 && int64(len(languages)) > p.limit
+
+	if p.before != nil {
+		if extra {
+			languages = languages[1:]
+		}
+
+		return languages, extra, len(languages) > 0
+	}
+
+	if extra {
+		languages = languages[:p.limit]
+	}
+
+	return languages, len(languages) > 0 && (p.after != nil || p.offset > 0), extra
+}
+
+// links builds the Link header for the pages either side of languages, keeping the request's filters
+func (p page) links(r *http.Request, filters url.Values, languages []models.Language, hasPrev bool, hasNext bool) string {
+	var links []string
+
+	link := func(key string, language models.Language, rel string) string {
+		values := url.Values{}
+		for name, value := range filters {
+			values[name] = value
+		}
+
+		if len(p.sort) > 0 {
+			values.Set("sort", query.FormatSort(p.sort))
+		}
+		if p.limit > 0 {
+			values.Set("limit", strconv.FormatInt(p.limit, 10))
+		}
+		values.Set(key, encodeCursor(newCursor(language, p.sort)))
+
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, values.Encode(), rel)
+	}
+
+	if hasPrev {
+		links = append(links, link("before", languages[0], "prev"))
+	}
+
+	if hasNext {
+		links = append(links, link("after", languages[len(languages)-1], "next"))
+	}
+
+	return strings.Join(links, ", ")
+}
+
+// offsetLinks builds the Link header for the pages either side of a page of n results out of total, keeping the
+// request's other parameters. Pages are addressed by offset, for lists that cursors can't mark positions in.
+func (p page) offsetLinks(r *http.Request, params url.Values, n int64, total int64) string {
+	var links []string
+
+	link := func(offset int64, rel string) string {
+		values := url.Values{}
+		for name, value := range params {
+			values[name] = value
+		}
+
+		if p.limit > 0 {
+			values.Set("limit", strconv.FormatInt(p.limit, 10))
+		}
+		if offset > 0 {
+			values.Set("offset", strconv.FormatInt(offset, 10))
+		}
+
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, values.Encode(), rel)
+	}
+
+	if p.offset > 0 && p.limit > 0 {
+		links = append(links, link(max(p.offset-p.limit, 0), "prev"))
+	}
+
+	if p.offset+n < total {
+		links = append(links, link(p.offset+n, "next"))
+	}
+
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"languages-api/internal/models"
+
+	"mime"
+	"net/http"
+)
+
+const (
+	// mergePatch is the media type of a JSON merge patch (RFC 7396), which PATCH /{id} applies when it is sent one
+	mergePatch = "application/merge-patch+json"
+	// jsonPatch is the media type of a JSON patch (RFC 6902), which PATCH /{id} applies when it is sent one
+	jsonPatch = "application/json-patch+json"
+)
+
+// mediaType is the media type of the request body, without its parameters, or empty if it has none
+func mediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	return mediaType
+}
+
+// removesName reports whether a merge patch would leave a language without a name, which every language needs
+func removesName(patch models.Patch) bool {
+	return patch.Name.Set && (patch.Name.Value == nil || *patch.Name.Value == "")
+}
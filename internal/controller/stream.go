@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/repo"
+
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ndjson is the media type of newline delimited JSON, which GET / streams languages as when a request accepts it
+const ndjson = "application/x-ndjson"
+
+var errFacetsWithStream = errors.New("facets cannot be counted for streamed languages")
+
+// acceptsNDJSON reports whether the Accept header of r asks for newline delimited JSON
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err == nil && mediaType == ndjson && params["q"] != "0" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// streamLanguages writes the languages repo streams as newline delimited JSON, with only the fields opts selects,
+// flushing each line so that they reach the client as they are read. Errors before the first language is written
+// get the same responses GET / gives them. After that the status has been sent, so the response is aborted instead,
+// which leaves it unterminated for the client to see that it is incomplete.
+func streamLanguages(w http.ResponseWriter, r *http.Request, repo repo.Repository, f models.Filter, opts models.FindOptions) {
+	rc := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+
+	var started bool
+	var writeErr error
+
+	err := repo.StreamLanguages(r.Context(), f, opts, func(language models.Language) error {
+		if !started {
+			w.Header().Set("Content-Type", ndjson)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+
+		writeErr = encoder.Encode(projection{language: language, fields: opts.Fields})
+		if writeErr == nil {
+			writeErr = rc.Flush()
+			if errors.Is(writeErr, http.ErrNotSupported) {
+				writeErr = nil
+			}
+		}
+
+		return writeErr
+	})
+
+	if writeErr != nil {
+		log.Error().Err(writeErr).Msg("Failed to write response")
+		return
+	}
+
+	if err != nil && started {
+		log.Error().Err(err).Msg("Failed to stream languages, aborting response")
+		panic(http.ErrAbortHandler)
+	}
+
+	if errors.Is(err, models.ErrTimeout) {
+		log.Error().Err(err).Msg("Timed out streaming languages")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusGatewayTimeout)
+		if _, innerErr := w.Write([]byte("The database did not respond in time")); innerErr != nil {
+			log.Error().Err(innerErr).Msg("Failed to write response")
+		}
+		return
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to stream languages")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, innerErr := w.Write([]byte("An error occurred processing this request")); innerErr != nil {
+			log.Error().Err(innerErr).Msg("Failed to write response")
+		}
+		return
+	}
+
+	if !started {
+		w.Header().Set("Content-Type", ndjson)
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,526 @@
+package jsonfile
+
+import (
+	"languages-api/internal/config"
+	"languages-api/internal/mem"
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DriverName is the Config.Driver value that selects FileConnector
+const DriverName = "file"
+
+func init() {
+	mgo.Register(DriverName, FileConnector{})
+}
+
+// catalog is the shape of the file, the same as mockData.json
+type catalog struct {
+	Languages []models.Language `json:"languages"`
+}
+
+// FileClient implements the mgo.Client interface on top of a JSON document shaped like mockData.json.
+// Languages are served from an in-memory index that is reloaded whenever the file changes on disk,
+// and every write rewrites the whole file atomically while holding an advisory lock on <path>.lock.
+type FileClient struct {
+	Path string
+
+	mu      sync.Mutex
+	store   *mem.MemoryClient
+	modTime time.Time
+	size    int64
+}
+
+// NewFileClient loads the catalog at path, creating it on the first write if it doesn't exist yet
+func NewFileClient(path string) (*FileClient, error) {
+	fc := &FileClient{Path: path}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc, fc.withLock(func() error {
+		return fc.reload()
+	})
+}
+
+// Ping checks that the catalog can still be read
+func (fc *FileClient) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc.refresh()
+}
+
+// Disconnect is a no-op as every write is flushed to disk before it returns
+func (fc *FileClient) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// EnsureIndexes is a no-op as the in-memory index checks that names stay unique on every write
+func (fc *FileClient) EnsureIndexes(_ context.Context) error {
+	return nil
+}
+
+func (fc *FileClient) Find(ctx context.Context, filter interface{}, opts models.FindOptions) (languages models.Languages, errs []error) {
+	store, err := fc.current()
+	if err != nil {
+		return models.Languages{Languages: []models.Language{}}, []error{err}
+	}
+
+	return store.Find(ctx, filter, opts)
+}
+
+func (fc *FileClient) Stream(ctx context.Context, filter interface{}, opts models.FindOptions, send func(models.Language) error) (err error) {
+	store, err := fc.current()
+	if err != nil {
+		return err
+	}
+
+	return store.Stream(ctx, filter, opts, send)
+}
+
+func (fc *FileClient) FindOne(ctx context.Context, id string, fields []string) (language models.Language, err error) {
+	store, err := fc.current()
+	if err != nil {
+		return models.Language{}, err
+	}
+
+	return store.FindOne(ctx, id, fields)
+}
+
+func (fc *FileClient) InsertOne(ctx context.Context, document interface{}) (insertedId string, err error) {
+	err = fc.write(func(store *mem.MemoryClient) error {
+		insertedId, err = store.InsertOne(ctx, document)
+		return err
+	})
+
+	return
+}
+
+func (fc *FileClient) ReplaceOne(ctx context.Context, id string, document interface{}, revision int64) (written int64, isUpserted bool, err error) {
+	err = fc.write(func(store *mem.MemoryClient) error {
+		written, isUpserted, err = store.ReplaceOne(ctx, id, document, revision)
+		return err
+	})
+
+	return
+}
+
+func (fc *FileClient) UpdateOne(ctx context.Context, id string, update interface{}, revision int64) (written int64, err error) {
+	err = fc.write(func(store *mem.MemoryClient) error {
+		written, err = store.UpdateOne(ctx, id, update, revision)
+		return err
+	})
+
+	return
+}
+
+func (fc *FileClient) DeleteOne(ctx context.Context, id string, revision int64) (err error) {
+	return fc.write(func(store *mem.MemoryClient) error {
+		return store.DeleteOne(ctx, id, revision)
+	})
+}
+
+// BulkWrite saves the file once, after every operation of the batch has been made
+func (fc *FileClient) BulkWrite(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error) {
+	err = fc.write(func(store *mem.MemoryClient) error {
+		results, err = store.BulkWrite(ctx, operations, atomic)
+		return err
+	})
+
+	return
+}
+
+func (fc *FileClient) Stats(ctx context.Context, topCreators int64) (stats models.Stats, err error) {
+	store, err := fc.current()
+	if err != nil {
+		return models.Stats{}, err
+	}
+
+	return store.Stats(ctx, topCreators)
+}
+
+// current returns the index, reloading it first if the file has changed since it was last read
+func (fc *FileClient) current() (*mem.MemoryClient, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.refresh(); err != nil {
+		return nil, err
+	}
+
+	return fc.store, nil
+}
+
+// write applies fn to an up-to-date index and saves the result, all while holding the file lock
+func (fc *FileClient) write(fn func(store *mem.MemoryClient) error) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc.withLock(func() error {
+		err := fc.reloadIfChanged()
+		if err != nil {
+			return err
+		}
+
+		err = fn(fc.store)
+		if err != nil {
+			return err
+		}
+
+		err = fc.save()
+		if err != nil {
+			// The index now holds a change that never made it to disk, so force the next call to re-read the file
+			fc.modTime = time.Time{}
+			fc.size = -1
+		}
+
+		return err
+	})
+}
+
+// refresh reloads the index if the file has changed. Callers must hold mu.
+func (fc *FileClient) refresh() error {
+	changed, err := fc.changed()
+	if err != nil || !changed {
+		return err
+	}
+
+	return fc.withLock(fc.reloadIfChanged)
+}
+
+// reloadIfChanged re-checks the file under the lock, as another process may have rewritten it in the meantime
+func (fc *FileClient) reloadIfChanged() error {
+	changed, err := fc.changed()
+	if err != nil || !changed {
+		return err
+	}
+
+	return fc.reload()
+}
+
+func (fc *FileClient) changed() (bool, error) {
+	info, err := os.Stat(fc.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fc.store == nil || fc.size != 0 || !fc.modTime.IsZero(), nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return fc.store == nil || !info.ModTime().Equal(fc.modTime) || info.Size() != fc.size, nil
+}
+
+// reload replaces the index with the contents of the file, saving it straight back if any language
+// had to be given an id or revision so that they stay stable across reloads. Callers must hold the lock.
+func (fc *FileClient) reload() error {
+	store := mem.NewMemoryClient()
+
+	data, err := os.ReadFile(fc.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		fc.store, fc.modTime, fc.size = store, time.Time{}, 0
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var stored catalog
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return err
+	}
+
+	incomplete := false
+	for _, language := range stored.Languages {
+		incomplete = incomplete || language.Id.IsZero() || language.Revision == 0
+	}
+
+	err = store.Load(stored.Languages)
+	if err != nil {
+		return err
+	}
+
+	fc.store = store
+
+	if incomplete {
+		return fc.save()
+	}
+
+	return fc.stat()
+}
+
+// save writes the index to the catalog file
+func (fc *FileClient) save() error {
+	data, err := json.MarshalIndent(catalog{Languages: fc.store.Snapshot()}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(fc.Path, data)
+	if err != nil {
+		return err
+	}
+
+	return fc.stat()
+}
+
+// writeFile writes data to a temporary file next to path and renames it into place
+func writeFile(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// Only does anything if the rename below didn't happen
+		if err := os.Remove(tmp.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Error().Err(err).Msg("Failed to remove temporary file")
+		}
+	}()
+
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// AppliedMigrations returns the record of every migration that has been applied, in version order
+func (fc *FileClient) AppliedMigrations(ctx context.Context) (migrations []models.AppliedMigration, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	err = fc.withLock(func() error {
+		migrations, err = fc.readMigrations()
+		return err
+	})
+
+	return
+}
+
+// RecordMigration marks a migration as applied
+func (fc *FileClient) RecordMigration(ctx context.Context, migration models.AppliedMigration) (err error) {
+	return fc.writeMigrations(ctx, func(migrations []models.AppliedMigration) []models.AppliedMigration {
+		return append(migrations, migration)
+	})
+}
+
+// RemoveMigration marks a migration as no longer applied
+func (fc *FileClient) RemoveMigration(ctx context.Context, version int) (err error) {
+	return fc.writeMigrations(ctx, func(migrations []models.AppliedMigration) []models.AppliedMigration {
+		return slices.DeleteFunc(migrations, func(m models.AppliedMigration) bool {
+			return m.Version == version
+		})
+	})
+}
+
+// migrationLock is the holder of the migration lock, as saved in the migration lock file
+type migrationLock struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// AcquireMigrationLock takes or extends the migration lock for owner, unless someone else holds an unexpired one
+func (fc *FileClient) AcquireMigrationLock(ctx context.Context, owner string, until time.Time) (acquired bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	err = fc.withLock(func() error {
+		lock, err := fc.readMigrationLock()
+		if err != nil {
+			return err
+		}
+
+		if lock.Owner != "" && lock.Owner != owner && time.Now().Before(lock.ExpiresAt) {
+			return nil
+		}
+
+		data, err := json.Marshal(migrationLock{Owner: owner, ExpiresAt: until})
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(fc.migrationLockPath(), data)
+		acquired = err == nil
+
+		return err
+	})
+
+	return
+}
+
+// ReleaseMigrationLock gives up the migration lock if owner holds it
+func (fc *FileClient) ReleaseMigrationLock(ctx context.Context, owner string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc.withLock(func() error {
+		lock, err := fc.readMigrationLock()
+		if err != nil || lock.Owner != owner {
+			return err
+		}
+
+		return os.Remove(fc.migrationLockPath())
+	})
+}
+
+// migrationLockPath is where the holder of the migration lock is saved while one is held
+func (fc *FileClient) migrationLockPath() string {
+	return fc.Path + ".migrations.lock.json"
+}
+
+// readMigrationLock reads the migration lock file, returning an empty lock if nobody holds it. Callers must hold the lock.
+func (fc *FileClient) readMigrationLock() (lock migrationLock, err error) {
+	data, err := os.ReadFile(fc.migrationLockPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	}
+
+	if err != nil {
+		return lock, err
+	}
+
+	err = json.Unmarshal(data, &lock)
+
+	return
+}
+
+// migrationsPath is where applied migrations are recorded, kept apart so the catalog stays shaped like mockData.json
+func (fc *FileClient) migrationsPath() string {
+	return fc.Path + ".migrations.json"
+}
+
+// readMigrations reads the applied migrations file. Callers must hold the lock.
+func (fc *FileClient) readMigrations() (migrations []models.AppliedMigration, err error) {
+	data, err := os.ReadFile(fc.migrationsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &migrations)
+
+	return
+}
+
+// writeMigrations applies fn to the applied migrations and saves the result in version order, all while holding the file lock
+func (fc *FileClient) writeMigrations(ctx context.Context, fn func(migrations []models.AppliedMigration) []models.AppliedMigration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc.withLock(func() error {
+		migrations, err := fc.readMigrations()
+		if err != nil {
+			return err
+		}
+
+		migrations = fn(migrations)
+		slices.SortFunc(migrations, func(a, b models.AppliedMigration) int {
+			return a.Version - b.Version
+		})
+
+		data, err := json.MarshalIndent(migrations, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		return writeFile(fc.migrationsPath(), data)
+	})
+}
+
+func (fc *FileClient) stat() error {
+	info, err := os.Stat(fc.Path)
+	if err != nil {
+		return err
+	}
+
+	fc.modTime, fc.size = info.ModTime(), info.Size()
+
+	return nil
+}
+
+// withLock runs fn while holding an exclusive lock on the catalog's lock file
+func (fc *FileClient) withLock(fn func() error) error {
+	f, err := os.OpenFile(fc.Path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close catalog lock file")
+		}
+	}()
+
+	err = lockFile(f)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := unlockFile(f); err != nil {
+			log.Error().Err(err).Msg("Failed to unlock catalog lock file")
+		}
+	}()
+
+	return fn()
+}
+
+// FileConnector implements the mgo.Connector interface
+type FileConnector struct{}
+
+// Connect loads the configured catalog file
+func (fc FileConnector) Connect(cfg config.Config) (mgo.Client, error) {
+	return NewFileClient(cfg.File.Path)
+}
@@ -0,0 +1,326 @@
+package jsonfile
+
+import (
+	"languages-api/internal/config"
+	"languages-api/internal/mgo"
+	"languages-api/internal/mgo/mgotest"
+	"languages-api/internal/models"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func readCatalog(t *testing.T, path string) catalog {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("Error reading catalog:", err)
+	}
+
+	var stored catalog
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		t.Fatal("Error unmarshalling catalog:", err)
+	}
+
+	return stored
+}
+
+func Test_FileClient_ShouldMeetTheClientContract(t *testing.T) {
+	mgotest.Run(t, func(t *testing.T) mgo.Client {
+		fc, err := NewFileClient(filepath.Join(t.TempDir(), "languages.json"))
+		if err != nil {
+			t.Fatal("Error creating client:", err)
+		}
+
+		return fc
+	})
+}
+
+func Test_NewFileClient_ShouldStartEmptyIfFileDoesNotExist(t *testing.T) {
+	fc, err := NewFileClient(filepath.Join(t.TempDir(), "languages.json"))
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	langs, errs := fc.Find(context.Background(), models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		t.Errorf("Unexpected errors in Find: %v", errs)
+	}
+
+	if len(langs.Languages) != 0 {
+		t.Errorf("Find should return no languages, but got %v", langs.Languages)
+	}
+}
+
+func Test_NewFileClient_ShouldReturnUnmarshalError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	err := os.WriteFile(path, []byte("not json"), 0o644)
+	if err != nil {
+		t.Fatal("Error writing catalog:", err)
+	}
+
+	_, err = NewFileClient(path)
+
+	var syntaxError *json.SyntaxError
+
+	if !errors.As(err, &syntaxError) {
+		t.Errorf("Error should be of type json.SyntaxError, got %v", err)
+	}
+}
+
+func Test_NewFileClient_ShouldPersistGeneratedIds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	data, err := os.ReadFile("../../mockData.json")
+	if err != nil {
+		t.Fatal("Error reading mock data:", err)
+	}
+
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		t.Fatal("Error writing catalog:", err)
+	}
+
+	fc, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	langs, _ := fc.Find(context.Background(), models.Filter{}, models.FindOptions{})
+	catalog := readCatalog(t, path)
+
+	if !reflect.DeepEqual(langs.Languages, catalog.Languages) {
+		t.Errorf("The catalog file should hold the served languages with their ids, expected %v, got %v", langs.Languages, catalog.Languages)
+	}
+}
+
+func Test_InsertOne_ShouldWriteLanguageToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	fc, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	id, err := fc.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	catalog := readCatalog(t, path)
+	if len(catalog.Languages) != 1 || catalog.Languages[0].Id.Hex() != id {
+		t.Errorf("The catalog file should hold the inserted language, but got %v", catalog.Languages)
+	}
+}
+
+func Test_InsertOne_ShouldNotLeaveTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	fc, err := NewFileClient(filepath.Join(dir, "languages.json"))
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	_, err = fc.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal("Error reading directory:", err)
+	}
+
+	for _, entry := range entries {
+		if entry.Name() != "languages.json" && entry.Name() != "languages.json.lock" {
+			t.Errorf("Unexpected file %s left behind", entry.Name())
+		}
+	}
+}
+
+func Test_FindOne_ShouldSeeChangesMadeByAnotherClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	first, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	second, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	id, err := first.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	lang, err := second.FindOne(context.Background(), id, nil)
+	if err != nil {
+		t.Errorf("FindOne should find the language written by the other client, but got %v", err)
+	}
+
+	if lang.Name != "Golang" {
+		t.Errorf("FindOne should return Golang, but got %v", lang)
+	}
+}
+
+func Test_FindOne_ShouldReturnErrorIfFileCannotBeReloaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	fc, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	err = os.WriteFile(path, []byte("not a catalog"), 0o644)
+	if err != nil {
+		t.Fatal("Error writing file:", err)
+	}
+
+	_, err = fc.FindOne(context.Background(), primitive.NewObjectID().Hex(), nil)
+	var syntaxError *json.SyntaxError
+	if !errors.As(err, &syntaxError) {
+		t.Errorf("FindOne should return the error reloading the file, but got %v", err)
+	}
+}
+
+func Test_UpdateOne_ShouldNotDropChangesMadeByAnotherClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	first, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	second, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	id, err := first.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, err = second.UpdateOne(context.Background(), id, models.Language{Year: 2012}, 0)
+	if err != nil {
+		t.Errorf("UpdateOne should update the language written by the other client, but got %v", err)
+	}
+
+	catalog := readCatalog(t, path)
+	if len(catalog.Languages) != 1 || catalog.Languages[0].Year != 2012 {
+		t.Errorf("The catalog file should hold the updated language, but got %v", catalog.Languages)
+	}
+}
+
+func Test_DeleteOne_ShouldRemoveLanguageFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	fc, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	id, err := fc.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	err = fc.DeleteOne(context.Background(), id, 0)
+	if err != nil {
+		t.Error("Error deleting language:", err)
+	}
+
+	catalog := readCatalog(t, path)
+	if len(catalog.Languages) != 0 {
+		t.Errorf("The catalog file should be empty, but got %v", catalog.Languages)
+	}
+}
+
+func Test_BulkWrite_ShouldWriteEveryOperationToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	fc, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	id, err := fc.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	results, err := fc.BulkWrite(context.Background(), []models.BatchOperation{
+		{Kind: models.BatchDelete, Id: id},
+		{Kind: models.BatchCreate, Language: models.Language{Name: "Carbon"}},
+	}, false)
+	if err != nil || results[0].Err != nil || results[1].Err != nil {
+		t.Errorf("Unexpected error in BulkWrite: %v, %+v", err, results)
+	}
+
+	catalog := readCatalog(t, path)
+	if len(catalog.Languages) != 1 || catalog.Languages[0].Name != "Carbon" {
+		t.Errorf("The catalog file should only hold Carbon, but got %v", catalog.Languages)
+	}
+}
+
+func Test_Connect_ShouldReturnFileClient(t *testing.T) {
+	c, err := FileConnector{}.Connect(config.Config{File: config.FileConfig{Path: filepath.Join(t.TempDir(), "languages.json")}})
+	if err != nil {
+		t.Error("Unexpected error returned from Connect():", err)
+	}
+
+	if reflect.TypeOf(c).String() != "*jsonfile.FileClient" {
+		t.Errorf("Connect() should return a FileClient pointer, but got %s", reflect.TypeOf(c).String())
+	}
+}
+
+func Test_RecordMigration_ShouldBeSeenByAnotherClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.json")
+
+	first, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	second, err := NewFileClient(path)
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	for _, version := range []int{2, 1} {
+		err = first.RecordMigration(context.Background(), models.AppliedMigration{Version: version, Name: "test"})
+		if err != nil {
+			t.Error("Error recording migration:", err)
+		}
+	}
+
+	err = first.RemoveMigration(context.Background(), 2)
+	if err != nil {
+		t.Error("Error removing migration:", err)
+	}
+
+	migrations, err := second.AppliedMigrations(context.Background())
+	if err != nil {
+		t.Error("Error getting applied migrations:", err)
+	}
+
+	if len(migrations) != 1 || migrations[0].Version != 1 {
+		t.Errorf("AppliedMigrations should return only version 1, but got %v", migrations)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Recording migrations should not write the catalog, but got %v", err)
+	}
+}
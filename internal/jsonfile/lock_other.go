@@ -0,0 +1,17 @@
+//go:build !unix
+
+package jsonfile
+
+import (
+	"os"
+)
+
+// lockFile is a no-op on platforms without flock, leaving only the in-process mutex to serialize writes
+func lockFile(_ *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without flock
+func unlockFile(_ *os.File) error {
+	return nil
+}
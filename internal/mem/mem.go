@@ -0,0 +1,688 @@
+package mem
+
+import (
+	"languages-api/internal/config"
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"cmp"
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DriverName is the Config.Driver value that selects MemoryConnector
+const DriverName = "memory"
+
+func init() {
+	mgo.Register(DriverName, MemoryConnector{})
+}
+
+// MemoryClient implements the mgo.Client interface by keeping languages in process memory
+type MemoryClient struct {
+	mu         sync.RWMutex
+	languages  map[primitive.ObjectID]models.Language
+	order      []primitive.ObjectID
+	migrations map[int]models.AppliedMigration
+	lockOwner  string
+	lockExpiry time.Time
+}
+
+// NewMemoryClient returns an empty MemoryClient
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		languages:  make(map[primitive.ObjectID]models.Language),
+		migrations: make(map[int]models.AppliedMigration),
+	}
+}
+
+// Load adds the given languages to the store, generating ids for those that don't have one
+// and starting those without a revision at revision 1
+func (mc *MemoryClient) Load(languages []models.Language) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	for _, language := range languages {
+		if language.Id.IsZero() {
+			language.Id = primitive.NewObjectID()
+		}
+
+		if language.Revision == 0 {
+			language.Revision = 1
+		}
+
+		if _, ok := mc.languages[language.Id]; ok {
+			return models.ErrDuplicateId
+		}
+
+		if err := mc.conflict(language); err != nil {
+			return err
+		}
+
+		mc.put(language)
+	}
+
+	return nil
+}
+
+// Snapshot returns a copy of every stored language in insertion order
+func (mc *MemoryClient) Snapshot() []models.Language {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	languages := make([]models.Language, 0, len(mc.order))
+	for _, id := range mc.order {
+		languages = append(languages, clone(mc.languages[id]))
+	}
+
+	return languages
+}
+
+// Ping only fails if ctx is already done, as there is no connection to check
+func (mc *MemoryClient) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Disconnect is a no-op as there is no connection to terminate
+func (mc *MemoryClient) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// EnsureIndexes is a no-op as every write checks that names stay unique
+func (mc *MemoryClient) EnsureIndexes(_ context.Context) error {
+	return nil
+}
+
+func (mc *MemoryClient) Find(ctx context.Context, filter interface{}, opts models.FindOptions) (languages models.Languages, errs []error) {
+	conditions := filter.(models.Filter)
+
+	if err := ctx.Err(); err != nil {
+		return models.Languages{Languages: []models.Language{}}, []error{err}
+	}
+
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	var matched []models.Language
+	for _, id := range mc.order {
+		stored := mc.languages[id]
+		if matches(stored, conditions) {
+			matched = append(matched, stored)
+		}
+	}
+
+	compare := func(a, b models.Language) int {
+		return query.Compare(a, b, opts.Sort)
+	}
+	slices.SortFunc(matched, compare)
+
+	selected := query.Selected(opts.Fields, opts.Sort)
+
+	languages.Languages = []models.Language{}
+	for _, stored := range page(matched, opts, compare) {
+		languages.Languages = append(languages.Languages, query.Project(clone(stored), selected))
+	}
+	languages.Total = int64(len(matched))
+	languages.Facets = query.CountFacets(matched, opts.Facets)
+
+	return
+}
+
+// Stream sends the languages Find returns, which are copies, so the store isn't locked while they are sent
+func (mc *MemoryClient) Stream(ctx context.Context, filter interface{}, opts models.FindOptions, send func(models.Language) error) (err error) {
+	if opts.Before != nil {
+		return models.ErrStreamBefore
+	}
+
+	languages, errs := mc.Find(ctx, filter, opts)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	for _, language := range languages.Languages {
+		err = send(language)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mc *MemoryClient) FindOne(ctx context.Context, id string, fields []string) (language models.Language, err error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Language{}, models.ErrInvalidId
+	}
+
+	if err := ctx.Err(); err != nil {
+		return models.Language{}, err
+	}
+
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	stored, ok := mc.languages[objectId]
+	if !ok {
+		return models.Language{}, models.ErrNotFound
+	}
+
+	return query.Project(clone(stored), query.Selected(fields, nil)), nil
+}
+
+func (mc *MemoryClient) InsertOne(ctx context.Context, document interface{}) (insertedId string, err error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	return mc.insert(document.(models.Language))
+}
+
+func (mc *MemoryClient) ReplaceOne(ctx context.Context, id string, document interface{}, revision int64) (written int64, isUpserted bool, err error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, false, models.ErrInvalidId
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	return mc.replace(objectId, document.(models.Language), revision)
+}
+
+func (mc *MemoryClient) UpdateOne(ctx context.Context, id string, update interface{}, revision int64) (written int64, err error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, models.ErrInvalidId
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	return mc.update(objectId, update, revision)
+}
+
+func (mc *MemoryClient) DeleteOne(ctx context.Context, id string, revision int64) (err error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.ErrInvalidId
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	return mc.remove(objectId, revision)
+}
+
+// BulkWrite holds the lock for the whole batch, so no other write is made between its operations. An atomic
+// batch is rolled back by restoring the languages as they were before it.
+func (mc *MemoryClient) BulkWrite(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	languages, order := maps.Clone(mc.languages), slices.Clone(mc.order)
+
+	results, rollback := mgo.ApplyBatch(operations, atomic, func(operation models.BatchOperation) (result models.BatchResult) {
+		if operation.Kind == models.BatchCreate {
+			result.Id, result.Err = mc.insert(operation.Language)
+			return result
+		}
+
+		objectId, err := primitive.ObjectIDFromHex(operation.Id)
+		if err != nil {
+			result.Err = models.ErrInvalidId
+			return result
+		}
+		result.Id = objectId.Hex()
+
+		switch operation.Kind {
+		case models.BatchReplace:
+			_, result.Upserted, result.Err = mc.replace(objectId, operation.Language, operation.Revision)
+		case models.BatchPatch:
+			_, result.Err = mc.update(objectId, operation.Language, operation.Revision)
+		default:
+			result.Err = mc.remove(objectId, operation.Revision)
+		}
+
+		return result
+	})
+
+	if rollback {
+		mc.languages, mc.order = languages, order
+	}
+
+	return results, nil
+}
+
+func (mc *MemoryClient) Stats(ctx context.Context, topCreators int64) (stats models.Stats, err error) {
+	if err := ctx.Err(); err != nil {
+		return models.Stats{}, err
+	}
+
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	languages := make([]models.Language, 0, len(mc.order))
+	for _, id := range mc.order {
+		languages = append(languages, mc.languages[id])
+	}
+
+	return query.Stats(languages, topCreators), nil
+}
+
+// AppliedMigrations returns the record of every migration that has been applied, in version order
+func (mc *MemoryClient) AppliedMigrations(ctx context.Context) (migrations []models.AppliedMigration, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	for _, migration := range mc.migrations {
+		migrations = append(migrations, migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b models.AppliedMigration) int {
+		return a.Version - b.Version
+	})
+
+	return migrations, nil
+}
+
+// RecordMigration marks a migration as applied
+func (mc *MemoryClient) RecordMigration(ctx context.Context, migration models.AppliedMigration) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.migrations[migration.Version] = migration
+
+	return nil
+}
+
+// RemoveMigration marks a migration as no longer applied
+func (mc *MemoryClient) RemoveMigration(ctx context.Context, version int) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	delete(mc.migrations, version)
+
+	return nil
+}
+
+// AcquireMigrationLock takes or extends the migration lock for owner, unless someone else holds an unexpired one
+func (mc *MemoryClient) AcquireMigrationLock(ctx context.Context, owner string, until time.Time) (acquired bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if mc.lockOwner != "" && mc.lockOwner != owner && time.Now().Before(mc.lockExpiry) {
+		return false, nil
+	}
+
+	mc.lockOwner, mc.lockExpiry = owner, until
+
+	return true, nil
+}
+
+// ReleaseMigrationLock gives up the migration lock if owner holds it
+func (mc *MemoryClient) ReleaseMigrationLock(ctx context.Context, owner string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if mc.lockOwner == owner {
+		mc.lockOwner, mc.lockExpiry = "", time.Time{}
+	}
+
+	return nil
+}
+
+// insert stores a new language at revision 1, generating an id for it if it doesn't have one. Callers must hold
+// the write lock.
+func (mc *MemoryClient) insert(language models.Language) (insertedId string, err error) {
+	language = clone(language)
+	if language.Id.IsZero() {
+		language.Id = primitive.NewObjectID()
+	}
+	language.Revision = 1
+
+	if _, ok := mc.languages[language.Id]; ok {
+		return "", models.ErrDuplicateId
+	}
+
+	if err := mc.conflict(language); err != nil {
+		return "", err
+	}
+
+	mc.put(language)
+
+	return language.Id.Hex(), nil
+}
+
+// replace stores language in place of the language with the given id, or as a new language if there isn't one
+// and revision is 0. Callers must hold the write lock.
+func (mc *MemoryClient) replace(id primitive.ObjectID, language models.Language, revision int64) (written int64, isUpserted bool, err error) {
+	language = clone(language)
+	if !language.Id.IsZero() && language.Id != id {
+		return 0, false, models.ErrIdMismatch
+	}
+	language.Id = id
+
+	stored, exists := mc.languages[id]
+	if revision != 0 && (!exists || revision > 0 && stored.Revision != revision) {
+		return 0, false, models.ErrPreconditionFailed
+	}
+
+	if err := mc.conflict(language); err != nil {
+		return 0, false, err
+	}
+
+	language.Revision = stored.Revision + 1
+	mc.put(language)
+
+	return language.Revision, !exists, nil
+}
+
+// update applies the fields set in update, which is either a models.Language or a models.Patch, to the language with
+// the given id. Callers must hold the write lock.
+func (mc *MemoryClient) update(id primitive.ObjectID, update interface{}, revision int64) (written int64, err error) {
+	stored, ok := mc.languages[id]
+	if !ok && revision == models.AnyRevision {
+		return 0, models.ErrPreconditionFailed
+	}
+
+	if !ok {
+		return 0, models.ErrNotFound
+	}
+
+	if revision > 0 && stored.Revision != revision {
+		return 0, models.ErrPreconditionFailed
+	}
+
+	var updated models.Language
+	switch update := update.(type) {
+	case models.Patch:
+		updated = query.MergePatch(clone(stored), update)
+	case models.JSONPatch:
+		patched, err := query.ApplyPatch(stored, update)
+		if err != nil {
+			return 0, err
+		}
+		updated = patched
+	default:
+		updated = apply(stored, clone(update.(models.Language)))
+	}
+
+	if err := mc.conflict(updated); err != nil {
+		return 0, err
+	}
+	updated.Revision++
+
+	mc.put(updated)
+
+	return updated.Revision, nil
+}
+
+// remove deletes the language with the given id. Callers must hold the write lock.
+func (mc *MemoryClient) remove(id primitive.ObjectID, revision int64) error {
+	stored, ok := mc.languages[id]
+	if !ok && revision == models.AnyRevision {
+		return models.ErrPreconditionFailed
+	}
+
+	if !ok {
+		return models.ErrNotFound
+	}
+
+	if revision > 0 && stored.Revision != revision {
+		return models.ErrPreconditionFailed
+	}
+
+	delete(mc.languages, id)
+	mc.order = slices.DeleteFunc(mc.order, func(o primitive.ObjectID) bool {
+		return o == id
+	})
+
+	return nil
+}
+
+// put stores the language, keeping its position if it already exists. Callers must hold the write lock.
+func (mc *MemoryClient) put(language models.Language) {
+	if _, ok := mc.languages[language.Id]; !ok {
+		mc.order = append(mc.order, language.Id)
+	}
+
+	mc.languages[language.Id] = language
+}
+
+// conflict mirrors the unique name index MongoClient.EnsureIndexes creates, returning a models.ConflictError
+// if a language other than the given one already has its folded name. Callers must hold the lock.
+func (mc *MemoryClient) conflict(language models.Language) error {
+	name := query.Fold(language.Name)
+	for id, stored := range mc.languages {
+		if id != language.Id && query.Fold(stored.Name) == name {
+			return models.ConflictError{Id: id.Hex()}
+		}
+	}
+
+	return nil
+}
+
+// MemoryConnector implements the mgo.Connector interface
+type MemoryConnector struct{}
+
+// Connect creates a new MemoryClient, seeding it from the configured seed file if there is one
+func (mc MemoryConnector) Connect(cfg config.Config) (mgo.Client, error) {
+	client := NewMemoryClient()
+	if cfg.Memory.SeedFile == "" {
+		return client, nil
+	}
+
+	data, err := os.ReadFile(cfg.Memory.SeedFile)
+	if err != nil {
+		return client, err
+	}
+
+	var seed models.Languages
+	err = json.Unmarshal(data, &seed)
+	if err != nil {
+		return client, err
+	}
+
+	return client, client.Load(seed.Languages)
+}
+
+// matches mirrors the conditions MongoClient.Find builds from a filter language
+func matches(language models.Language, filter models.Filter) bool {
+	if filter.Name != "" && !query.Match(language.Name, filter.Name, filter.NameMatch) {
+		return false
+	}
+
+	if len(filter.Creators) > 0 && !query.MatchSet(language.Creators, filter.Creators, filter.CreatorsSet, func(creator string, pattern string) bool {
+		return query.Match(creator, pattern, filter.CreatorsMatch)
+	}) {
+		return false
+	}
+
+	if len(filter.Extensions) > 0 && !query.MatchSet(language.Extensions, filter.Extensions, filter.ExtensionsSet, func(extension string, pattern string) bool {
+		return extension == pattern
+	}) {
+		return false
+	}
+
+	if !inRange(filter.FirstAppeared, language.FirstAppeared, time.Time.Compare) {
+		return false
+	}
+
+	if !inRange(filter.Year, &language.Year, cmp.Compare[int32]) {
+		return false
+	}
+
+	if filter.Wiki != "" && language.Wiki != filter.Wiki {
+		return false
+	}
+
+	if filter.Expr != nil && !query.Eval(filter.Expr, language) {
+		return false
+	}
+
+	if len(filter.Terms) > 0 && !query.MatchTerms(language, filter.Terms) {
+		return false
+	}
+
+	return true
+}
+
+// inRange mirrors the comparisons MongoClient.Find builds from r, which never match a missing value
+func inRange[T any](r models.Range[T], value *T, compare func(a, b T) int) bool {
+	if r.IsZero() {
+		return true
+	}
+
+	if value == nil {
+		return false
+	}
+
+	for _, bound := range []struct {
+		limit *T
+		ok    func(c int) bool
+	}{
+		{r.Eq, func(c int) bool { return c == 0 }},
+		{r.Gt, func(c int) bool { return c > 0 }},
+		{r.Gte, func(c int) bool { return c >= 0 }},
+		{r.Lt, func(c int) bool { return c < 0 }},
+		{r.Lte, func(c int) bool { return c <= 0 }},
+	} {
+		if bound.limit != nil && !bound.ok(compare(*value, *bound.limit)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// page mirrors the page MongoClient.Find selects from languages sorted by compare
+func page(languages []models.Language, opts models.FindOptions, compare func(a, b models.Language) int) []models.Language {
+	if opts.After != nil {
+		i, found := slices.BinarySearchFunc(languages, *opts.After, compare)
+		if found {
+			i++
+		}
+		languages = languages[i:]
+	}
+
+	if opts.Before == nil {
+		languages = languages[min(opts.Offset, int64(len(languages))):]
+		if opts.Limit > 0 && opts.Limit < int64(len(languages)) {
+			languages = languages[:opts.Limit]
+		}
+
+		return languages
+	}
+
+	// Before pages are counted back from the end
+	i, _ := slices.BinarySearchFunc(languages, *opts.Before, compare)
+	languages = languages[:i]
+	languages = languages[:int64(len(languages))-min(opts.Offset, int64(len(languages)))]
+	if opts.Limit > 0 && opts.Limit < int64(len(languages)) {
+		languages = languages[int64(len(languages))-opts.Limit:]
+	}
+
+	return languages
+}
+
+// apply mirrors the $set MongoClient.UpdateOne builds, copying only the non-zero fields of update
+func apply(language models.Language, update models.Language) models.Language {
+	if update.Name != "" {
+		language.Name = update.Name
+	}
+
+	if len(update.Creators) > 0 {
+		language.Creators = update.Creators
+	}
+
+	if len(update.Extensions) > 0 {
+		language.Extensions = update.Extensions
+	}
+
+	if update.FirstAppeared != nil {
+		language.FirstAppeared = update.FirstAppeared
+	}
+
+	if update.Year != 0 {
+		language.Year = update.Year
+	}
+
+	if update.Wiki != "" {
+		language.Wiki = update.Wiki
+	}
+
+	if update.Heuristics != nil {
+		language.Heuristics = update.Heuristics
+	}
+
+	return language
+}
+
+// clone deep copies a language so callers can't modify what is stored
+func clone(language models.Language) models.Language {
+	language.Creators = slices.Clone(language.Creators)
+	language.Extensions = slices.Clone(language.Extensions)
+
+	if language.FirstAppeared != nil {
+		firstAppeared := *language.FirstAppeared
+		language.FirstAppeared = &firstAppeared
+	}
+
+	if language.Heuristics != nil {
+		heuristics := models.Heuristics{
+			Interpreters: slices.Clone(language.Heuristics.Interpreters),
+			Modes:        slices.Clone(language.Heuristics.Modes),
+			Keywords:     slices.Clone(language.Heuristics.Keywords),
+		}
+		language.Heuristics = &heuristics
+	}
+
+	return language
+}
@@ -0,0 +1,199 @@
+package mem
+
+import (
+	"languages-api/internal/config"
+	"languages-api/internal/mgo"
+	"languages-api/internal/mgo/mgotest"
+	"languages-api/internal/models"
+
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_MemoryClient_ShouldMeetTheClientContract(t *testing.T) {
+	mgotest.Run(t, func(t *testing.T) mgo.Client {
+		return NewMemoryClient()
+	})
+}
+
+func Test_Ping_ShouldReturnNil(t *testing.T) {
+	err := NewMemoryClient().Ping(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error pinging client: %v", err)
+	}
+}
+
+func Test_Disconnect_ShouldReturnNil(t *testing.T) {
+	err := NewMemoryClient().Disconnect(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error disconnecting client: %v", err)
+	}
+}
+
+func Test_Load_ShouldGenerateMissingIds(t *testing.T) {
+	mc := NewMemoryClient()
+
+	err := mc.Load([]models.Language{mgotest.NewGolang(t)})
+	if err != nil {
+		t.Error("Error loading languages:", err)
+	}
+
+	if mc.Snapshot()[0].Id.IsZero() {
+		t.Error("Load should generate an id for languages without one")
+	}
+}
+
+func Test_Load_ShouldReturnErrDuplicateIdOnRepeatedId(t *testing.T) {
+	lang := mgotest.NewGolang(t)
+	lang.Id = primitive.NewObjectID()
+
+	err := NewMemoryClient().Load([]models.Language{lang, lang})
+	if !errors.Is(err, models.ErrDuplicateId) {
+		t.Errorf("Load should return ErrDuplicateId, but got %v", err)
+	}
+}
+
+func Test_Load_ShouldStartLanguagesWithoutRevisionAtOne(t *testing.T) {
+	mc := NewMemoryClient()
+
+	err := mc.Load([]models.Language{{Name: "Golang"}, {Name: "C", Revision: 7}})
+	if err != nil {
+		t.Error("Error loading languages:", err)
+	}
+
+	langs := mc.Snapshot()
+	if langs[0].Revision != 1 || langs[1].Revision != 7 {
+		t.Errorf("Load should only set missing revisions, but got %v", langs)
+	}
+}
+
+func Test_Find_ShouldReturnAllLanguagesWithNoFilter(t *testing.T) {
+	mc := NewMemoryClient()
+
+	err := mc.Load([]models.Language{mgotest.NewGolang(t), {Name: "C", Extensions: []string{".c", ".h"}, Year: 1972}})
+	if err != nil {
+		t.Error("Error loading languages:", err)
+	}
+
+	langs, _ := mc.Find(context.Background(), models.Filter{}, models.FindOptions{})
+	if !reflect.DeepEqual(langs.Languages, mc.Snapshot()) {
+		t.Errorf("Find should return %v, but got %v", mc.Snapshot(), langs.Languages)
+	}
+}
+
+func Test_FindOne_ShouldReturnCopyOfStoredLanguage(t *testing.T) {
+	mc := NewMemoryClient()
+
+	id, err := mc.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	lang, err := mc.FindOne(context.Background(), id, nil)
+	if err != nil {
+		t.Error("Error finding language:", err)
+	}
+
+	lang.Creators[0] = "Someone Else"
+
+	stored, _ := mc.FindOne(context.Background(), id, nil)
+	if stored.Creators[0] != "Robert Griesemer" {
+		t.Errorf("Modifying a returned language should not change the store, but got %v", stored.Creators)
+	}
+}
+
+func Test_ReplaceOne_ShouldReturnErrIdMismatchIfDocumentIdDiffers(t *testing.T) {
+	lang := mgotest.NewGolang(t)
+	lang.Id = primitive.NewObjectID()
+
+	_, _, err := NewMemoryClient().ReplaceOne(context.Background(), primitive.NewObjectID().Hex(), lang, 0)
+	if !errors.Is(err, models.ErrIdMismatch) {
+		t.Errorf("Unexpected error in ReplaceOne: %v", err)
+	}
+}
+
+func Test_ReplaceOne_ShouldNotConflictWithItself(t *testing.T) {
+	mc := NewMemoryClient()
+
+	id, err := mc.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, _, err = mc.ReplaceOne(context.Background(), id, mgotest.NewGolang(t), 0)
+	if err != nil {
+		t.Errorf("ReplaceOne should allow a language to keep its own name, but got %v", err)
+	}
+}
+
+func Test_DeleteOne_ShouldRemoveStoredLanguage(t *testing.T) {
+	mc := NewMemoryClient()
+
+	id, err := mc.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	err = mc.DeleteOne(context.Background(), id, 0)
+	if err != nil {
+		t.Error("Error deleting language:", err)
+	}
+
+	_, err = mc.FindOne(context.Background(), id, nil)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("FindOne after DeleteOne should return ErrNotFound, but got %v", err)
+	}
+}
+
+func Test_Connect_ShouldReturnEmptyClientWithoutSeedFile(t *testing.T) {
+	c, err := MemoryConnector{}.Connect(config.Config{})
+	if err != nil {
+		t.Error("Unexpected error returned from Connect():", err)
+	}
+
+	if len(c.(*MemoryClient).Snapshot()) != 0 {
+		t.Error("Connect() should return an empty client when no seed file is configured")
+	}
+}
+
+func Test_Connect_ShouldReturnReadError(t *testing.T) {
+	_, err := MemoryConnector{}.Connect(config.Config{Memory: config.MemoryConfig{SeedFile: "missing.json"}})
+
+	var pathError *fs.PathError
+
+	if !errors.As(err, &pathError) {
+		t.Errorf("Error should be of type fs.PathError, got %v", err)
+	}
+}
+
+func Test_Connect_ShouldSeedFromSeedFile(t *testing.T) {
+	c, err := MemoryConnector{}.Connect(config.Config{Memory: config.MemoryConfig{SeedFile: "../../mockData.json"}})
+	if err != nil {
+		t.Error("Unexpected error returned from Connect():", err)
+	}
+
+	langs, _ := c.Find(context.Background(), models.Filter{Extensions: []string{".h"}}, models.FindOptions{})
+	if len(langs.Languages) != 2 {
+		t.Errorf("Expected the seeded C and C++ to share .h, but got %v", langs.Languages)
+	}
+}
+
+func Test_InsertOne_ShouldNotStoreLanguageIfCancelled(t *testing.T) {
+	mc := NewMemoryClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := mc.InsertOne(ctx, mgotest.NewGolang(t))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("InsertOne should return context.Canceled, but got %v", err)
+	}
+
+	if len(mc.Snapshot()) != 0 {
+		t.Errorf("InsertOne should not store anything once cancelled, but got %v", mc.Snapshot())
+	}
+}
@@ -0,0 +1,412 @@
+package mgo
+
+import (
+	"languages-api/internal/models"
+
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errRollback aborts the transaction of an atomic batch in which an operation failed
+var errRollback = errors.New("an operation of the batch failed")
+
+const (
+	// illegalOperation is the code of the error a standalone server returns when asked to run a transaction
+	illegalOperation = 20
+	// transientTransactionError labels the errors after which a transaction can be retried from the start
+	transientTransactionError = "TransientTransactionError"
+)
+
+// ApplyBatch makes each operation with write in order, for drivers that write one operation at a time. If atomic
+// is set, it stops at the first operation that fails and marks every other operation models.ErrNotApplied,
+// reporting that the driver must roll back the operations it has already made.
+func ApplyBatch(operations []models.BatchOperation, atomic bool, write func(operation models.BatchOperation) models.BatchResult) (results []models.BatchResult, rollback bool) {
+	results = make([]models.BatchResult, len(operations))
+	for i, operation := range operations {
+		results[i] = write(operation)
+		if atomic && results[i].Err != nil {
+			NotApplied(results)
+			return results, true
+		}
+	}
+
+	return results, false
+}
+
+// NotApplied marks every operation of a batch that didn't fail models.ErrNotApplied, for when it is rolled back
+func NotApplied(results []models.BatchResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = models.ErrNotApplied
+		}
+	}
+}
+
+// BulkWrite sends the operations to Mongo in ordered bulk writes, each bounded by the write timeout. An atomic batch
+// is made in a transaction, which needs a replica set.
+//
+// Before each bulk write the languages it writes are read, so that operations on languages that don't exist or are
+// at another revision fail without being sent. A bulk write only counts what it matched, so the rest are sent such
+// that each reports its own failure: replacements and patches are upserts, which fail with a duplicate _id if the
+// language was written in between and are found among the upserted ids if it was deleted in between. Only a delete
+// can match nothing without failing, and whether it did is read back once the write is made.
+func (mc MongoClient) BulkWrite(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error) {
+	operations = withIds(operations)
+
+	if !atomic {
+		return mc.writeBatch(ctx, operations, false)
+	}
+
+	session, err := mc.Client.StartSession()
+	if err != nil {
+		return nil, TimeoutError(err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		results, err = mc.writeBatch(ctx, operations, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.ContainsFunc(results, failed) {
+			return nil, nil
+		}
+
+		NotApplied(results)
+
+		return nil, errRollback
+	})
+	if err == nil {
+		return results, nil
+	}
+
+	if !errors.Is(err, errRollback) {
+		return nil, transactionError(err)
+	}
+
+	// A write error aborts the transaction, so the language that caused a conflict can only be read once it has
+	// been rolled back
+	for i, result := range results {
+		var we mongo.WriteError
+		if errors.As(result.Err, &we) {
+			results[i].Err = mc.batchError(ctx, operations[i], we)
+		}
+	}
+
+	return results, nil
+}
+
+// writeBatch makes the operations a round at a time, each round ending before an operation on a language written
+// earlier in it, so that what was read before the round holds for every operation in it. Write errors are resolved
+// as they happen unless atomic is set, in which case they are left for once the transaction has been rolled back,
+// and no more rounds are made after one with an operation that failed.
+func (mc MongoClient) writeBatch(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error) {
+	results = make([]models.BatchResult, len(operations))
+
+	for start := 0; start < len(operations); {
+		next, err := mc.writeRound(ctx, operations, results, start, atomic)
+		if err != nil {
+			return nil, err
+		}
+
+		if atomic && slices.ContainsFunc(results[start:next], failed) {
+			break
+		}
+
+		start = next
+	}
+
+	return results, nil
+}
+
+// writeRound makes the operations from start in a single bulk write, returning the operation the next round starts
+// at. A write error stops an ordered bulk write, so the round then ends after the operation that failed.
+func (mc MongoClient) writeRound(ctx context.Context, operations []models.BatchOperation, results []models.BatchResult, start int, atomic bool) (next int, err error) {
+	var ids []primitive.ObjectID
+	next = start
+	for ; next < len(operations); next++ {
+		id, err := primitive.ObjectIDFromHex(operations[next].Id)
+		if err != nil {
+			results[next] = models.BatchResult{Err: models.ErrInvalidId}
+			continue
+		}
+
+		if slices.Contains(ids, id) {
+			break
+		}
+
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return next, nil
+	}
+
+	revisions, err := mc.storedRevisions(ctx, ids)
+	if err != nil {
+		return next, err
+	}
+
+	var writes []mongo.WriteModel
+	var indexes []int
+	for i := start; i < next; i++ {
+		if results[i].Err != nil {
+			continue
+		}
+
+		revision, exists := revisions[operations[i].Id]
+		results[i].Id = operations[i].Id
+
+		write, err := batchWrite(operations[i], revision, exists)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		writes, indexes = append(writes, write), append(indexes, i)
+	}
+
+	if len(writes) == 0 || (atomic && slices.ContainsFunc(results[start:next], failed)) {
+		return next, nil
+	}
+
+	writeCtx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
+	defer cancel()
+
+	bwr, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).BulkWrite(writeCtx, writes, options.BulkWrite().SetOrdered(true))
+
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && len(bwe.WriteErrors) > 0 && !bwe.HasErrorLabel(transientTransactionError) {
+		sent := bwe.WriteErrors[0].Index
+		failedAt := indexes[sent]
+		results[failedAt].Err = bwe.WriteErrors[0]
+		if !atomic {
+			results[failedAt].Err = mc.batchError(ctx, operations[failedAt], bwe.WriteErrors[0])
+		}
+
+		// The operations after the one that failed weren't sent, so they are made in the next round
+		clear(results[failedAt+1 : next])
+
+		return failedAt + 1, mc.settleRound(ctx, operations, results, revisions, indexes[:sent], bwr, atomic)
+	}
+
+	if err != nil {
+		return next, TimeoutError(err)
+	}
+
+	return next, mc.settleRound(ctx, operations, results, revisions, indexes, bwr, atomic)
+}
+
+// settleRound works out the results of the operations at indexes that a bulk write made without a write error: those
+// it upserted, which either created the language or found it had been deleted after it was read, and the deletes
+// that matched nothing, if the bulk write matched fewer than it was sent
+func (mc MongoClient) settleRound(ctx context.Context, operations []models.BatchOperation, results []models.BatchResult, revisions map[string]int64, indexes []int, bwr *mongo.BulkWriteResult, atomic bool) error {
+	if bwr == nil {
+		return nil
+	}
+
+	for index := range bwr.UpsertedIDs {
+		if int(index) >= len(indexes) {
+			continue
+		}
+
+		i := indexes[index]
+		operation := operations[i]
+		if operation.Kind == models.BatchReplace && operation.Revision == 0 {
+			results[i].Upserted = true
+			continue
+		}
+
+		results[i].Err = upsertedError(operation)
+		if !atomic {
+			mc.removeUpserted(ctx, operation, revisions[operation.Id])
+		}
+	}
+
+	if bwr.MatchedCount+bwr.DeletedCount+bwr.InsertedCount+bwr.UpsertedCount < int64(len(indexes)) {
+		return mc.checkDeleted(ctx, operations, results, indexes)
+	}
+
+	return nil
+}
+
+// withIds returns a copy of the operations with each valid id written as Mongo reads it back, and an id generated for
+// each language to be created without one, so that each round and each retry of a transaction creates it with the
+// same id
+func withIds(operations []models.BatchOperation) []models.BatchOperation {
+	operations = slices.Clone(operations)
+	for i, operation := range operations {
+		if operation.Kind != models.BatchCreate {
+			if id, err := primitive.ObjectIDFromHex(operation.Id); err == nil {
+				operations[i].Id = id.Hex()
+			}
+			continue
+		}
+
+		if operation.Language.Id.IsZero() {
+			operations[i].Language.Id = primitive.NewObjectID()
+		}
+		operations[i].Id = operations[i].Language.Id.Hex()
+	}
+
+	return operations
+}
+
+// storedRevisions reads the revision of each of the languages with the given ids that is stored, keyed by id
+func (mc MongoClient) storedRevisions(ctx context.Context, ids []primitive.ObjectID) (revisions map[string]int64, err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Read)
+	defer cancel()
+
+	cursor, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"revision": 1}))
+	if err != nil {
+		return nil, TimeoutError(err)
+	}
+
+	var stored []models.Language
+	err = cursor.All(ctx, &stored)
+	if err != nil {
+		return nil, TimeoutError(err)
+	}
+
+	revisions = make(map[string]int64, len(stored))
+	for _, language := range stored {
+		revisions[language.Id.Hex()] = language.Revision
+	}
+
+	return revisions, nil
+}
+
+// batchWrite is the write an operation makes to a language stored at revision, if it exists, or the error the write
+// of the same kind on its own would fail with
+func batchWrite(operation models.BatchOperation, revision int64, exists bool) (mongo.WriteModel, error) {
+	id, _ := primitive.ObjectIDFromHex(operation.Id)
+
+	switch operation.Kind {
+	case models.BatchCreate:
+		if exists {
+			return nil, models.ErrDuplicateId
+		}
+
+		return mongo.NewInsertOneModel().SetDocument(stored(operation.Language)), nil
+	case models.BatchReplace:
+		if !operation.Language.Id.IsZero() && operation.Language.Id != id {
+			return nil, models.ErrIdMismatch
+		}
+
+		if operation.Revision != 0 && (!exists || (operation.Revision > 0 && operation.Revision != revision)) {
+			return nil, models.ErrPreconditionFailed
+		}
+
+		return mongo.NewUpdateOneModel().SetFilter(revisionFilter(id, operation.Revision)).SetUpdate(replacement(operation.Language)).SetUpsert(true), nil
+	}
+
+	if !exists {
+		return nil, upsertedError(operation)
+	}
+
+	if operation.Revision > 0 && operation.Revision != revision {
+		return nil, models.ErrPreconditionFailed
+	}
+
+	if operation.Kind == models.BatchPatch {
+		return mongo.NewUpdateOneModel().SetFilter(revisionFilter(id, operation.Revision)).SetUpdate(changes(operation.Language)).SetUpsert(true), nil
+	}
+
+	return mongo.NewDeleteOneModel().SetFilter(revisionFilter(id, operation.Revision)), nil
+}
+
+// upsertedError is the error an operation fails with when the language it writes doesn't exist, as found when one of
+// its upserts creates the language instead
+func upsertedError(operation models.BatchOperation) error {
+	if operation.Kind == models.BatchReplace || operation.Revision == models.AnyRevision {
+		return models.ErrPreconditionFailed
+	}
+
+	return models.ErrNotFound
+}
+
+// removeUpserted deletes a language that an operation's upsert created because it had been deleted after it was read,
+// as long as nothing has written it since
+func (mc MongoClient) removeUpserted(ctx context.Context, operation models.BatchOperation, revision int64) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
+	defer cancel()
+
+	id, _ := primitive.ObjectIDFromHex(operation.Id)
+	upserted := int64(1)
+	if operation.Revision > 0 {
+		upserted = revision + 1
+	}
+
+	_, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).DeleteOne(ctx, bson.M{"_id": id, "revision": upserted})
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to remove language %s upserted by a batch", operation.Id)
+	}
+}
+
+// checkDeleted fails each delete of the round, whose operations are at indexes, that matched nothing because its
+// language was written after it was read. Each language is written at most once a round, so a language that is still
+// stored wasn't deleted.
+func (mc MongoClient) checkDeleted(ctx context.Context, operations []models.BatchOperation, results []models.BatchResult, indexes []int) error {
+	var ids []primitive.ObjectID
+	for _, i := range indexes {
+		if operations[i].Kind == models.BatchDelete && results[i].Err == nil {
+			id, _ := primitive.ObjectIDFromHex(operations[i].Id)
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	revisions, err := mc.storedRevisions(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range indexes {
+		if _, ok := revisions[operations[i].Id]; ok && operations[i].Kind == models.BatchDelete {
+			results[i].Err = models.ErrPreconditionFailed
+		}
+	}
+
+	return nil
+}
+
+// batchError resolves the write error an operation failed with: a duplicate _id is models.ErrDuplicateId for a
+// language being created, or models.ErrPreconditionFailed for an upsert whose language was written after it was
+// read, and a duplicate name is a models.ConflictError
+func (mc MongoClient) batchError(ctx context.Context, operation models.BatchOperation, err error) error {
+	id, _ := primitive.ObjectIDFromHex(operation.Id)
+
+	err = duplicateIdError(err)
+	if errors.Is(err, models.ErrDuplicateId) && operation.Kind != models.BatchCreate {
+		return models.ErrPreconditionFailed
+	}
+
+	return TimeoutError(mc.conflictError(ctx, err, operation.Language.Name, id))
+}
+
+// failed reports whether an operation of a batch failed
+func failed(result models.BatchResult) bool {
+	return result.Err != nil
+}
+
+// transactionError turns the error a standalone server returns when asked to run a transaction into
+// models.ErrTransactionsUnsupported
+func transactionError(err error) error {
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorCode(illegalOperation) {
+		return models.ErrTransactionsUnsupported
+	}
+
+	return TimeoutError(err)
+}
@@ -0,0 +1,157 @@
+package mgo
+
+import (
+	"languages-api/internal/models"
+
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_ApplyBatch_ShouldMakeEveryOperationUnlessAtomic(t *testing.T) {
+	operations := []models.BatchOperation{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+	write := func(operation models.BatchOperation) models.BatchResult {
+		if operation.Id == "b" {
+			return models.BatchResult{Id: operation.Id, Err: models.ErrNotFound}
+		}
+		return models.BatchResult{Id: operation.Id}
+	}
+
+	results, rollback := ApplyBatch(operations, false, write)
+	expected := []models.BatchResult{{Id: "a"}, {Id: "b", Err: models.ErrNotFound}, {Id: "c"}}
+	if rollback || !reflect.DeepEqual(results, expected) {
+		t.Errorf("ApplyBatch should make every operation, but got %+v, rollback %t", results, rollback)
+	}
+
+	results, rollback = ApplyBatch(operations, true, write)
+	expected = []models.BatchResult{{Id: "a", Err: models.ErrNotApplied}, {Id: "b", Err: models.ErrNotFound}, {Err: models.ErrNotApplied}}
+	if !rollback || !reflect.DeepEqual(results, expected) {
+		t.Errorf("ApplyBatch should stop at the failed operation, but got %+v, rollback %t", results, rollback)
+	}
+}
+
+func Test_BulkWrite_ShouldReturnReadErrorWhenDisconnected(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
+	_, err = mc.BulkWrite(context.Background(), []models.BatchOperation{{Kind: models.BatchDelete, Id: primitive.NewObjectID().Hex()}, {Kind: models.BatchPatch, Id: "1"}}, false)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("BulkWrite should return the error reading the languages it writes, but got %v", err)
+	}
+}
+
+func Test_batchWrite_ShouldOnlySendOperationsThatCanBeMade(t *testing.T) {
+	id := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	for _, test := range []struct {
+		name      string
+		operation models.BatchOperation
+		revision  int64
+		exists    bool
+		expected  error
+	}{
+		{"create", models.BatchOperation{Kind: models.BatchCreate, Id: id.Hex(), Language: models.Language{Id: id}}, 0, false, nil},
+		{"create existing", models.BatchOperation{Kind: models.BatchCreate, Id: id.Hex(), Language: models.Language{Id: id}}, 1, true, models.ErrDuplicateId},
+		{"replace missing", models.BatchOperation{Kind: models.BatchReplace, Id: id.Hex()}, 0, false, nil},
+		{"replace with other id", models.BatchOperation{Kind: models.BatchReplace, Id: id.Hex(), Language: models.Language{Id: other}}, 1, true, models.ErrIdMismatch},
+		{"replace missing at revision", models.BatchOperation{Kind: models.BatchReplace, Id: id.Hex(), Revision: 2}, 0, false, models.ErrPreconditionFailed},
+		{"replace missing at any revision", models.BatchOperation{Kind: models.BatchReplace, Id: id.Hex(), Revision: models.AnyRevision}, 0, false, models.ErrPreconditionFailed},
+		{"replace at stale revision", models.BatchOperation{Kind: models.BatchReplace, Id: id.Hex(), Revision: 2}, 3, true, models.ErrPreconditionFailed},
+		{"patch", models.BatchOperation{Kind: models.BatchPatch, Id: id.Hex(), Revision: 3}, 3, true, nil},
+		{"patch missing", models.BatchOperation{Kind: models.BatchPatch, Id: id.Hex()}, 0, false, models.ErrNotFound},
+		{"patch missing at any revision", models.BatchOperation{Kind: models.BatchPatch, Id: id.Hex(), Revision: models.AnyRevision}, 0, false, models.ErrPreconditionFailed},
+		{"patch at stale revision", models.BatchOperation{Kind: models.BatchPatch, Id: id.Hex(), Revision: 2}, 3, true, models.ErrPreconditionFailed},
+		{"delete", models.BatchOperation{Kind: models.BatchDelete, Id: id.Hex()}, 3, true, nil},
+		{"delete missing", models.BatchOperation{Kind: models.BatchDelete, Id: id.Hex(), Revision: 2}, 0, false, models.ErrNotFound},
+	} {
+		write, err := batchWrite(test.operation, test.revision, test.exists)
+		if !errors.Is(err, test.expected) || (err == nil) == (write == nil) {
+			t.Errorf("%s should return %v, but got %v with write %v", test.name, test.expected, err, write)
+		}
+	}
+}
+
+func Test_batchWrite_ShouldUpsertReplacementsAndPatches(t *testing.T) {
+	id := primitive.NewObjectID().Hex()
+
+	for _, kind := range []models.BatchKind{models.BatchReplace, models.BatchPatch} {
+		write, _ := batchWrite(models.BatchOperation{Kind: kind, Id: id}, 1, true)
+
+		update, ok := write.(*mongo.UpdateOneModel)
+		if !ok || update.Upsert == nil || !*update.Upsert {
+			t.Errorf("Operation of kind %d should be sent as an upsert, but got %+v", kind, write)
+		}
+	}
+}
+
+func Test_withIds_ShouldGenerateIdsForCreatedLanguagesAndNormaliseTheRest(t *testing.T) {
+	given := primitive.NewObjectID()
+	operations := []models.BatchOperation{
+		{Kind: models.BatchCreate},
+		{Kind: models.BatchCreate, Language: models.Language{Id: given}},
+		{Kind: models.BatchDelete, Id: "1"},
+		{Kind: models.BatchPatch, Id: strings.ToUpper(given.Hex())},
+	}
+
+	withIds := withIds(operations)
+	if withIds[0].Language.Id.IsZero() || withIds[0].Id != withIds[0].Language.Id.Hex() {
+		t.Errorf("A created language without an id should be given one, but got %+v", withIds[0])
+	}
+
+	if withIds[1].Id != given.Hex() || withIds[2].Id != "1" || withIds[3].Id != given.Hex() || !operations[0].Language.Id.IsZero() {
+		t.Errorf("Only the copies of the operations should change, with valid ids as Mongo reads them, but got %+v from %+v", withIds, operations)
+	}
+}
+
+func Test_BulkWrite_ShouldReturnClientSessionErrorWhenAtomic(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
+	_, err = mc.BulkWrite(context.Background(), []models.BatchOperation{{Kind: models.BatchDelete, Id: primitive.NewObjectID().Hex()}}, true)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("Unexpected error in BulkWrite: %v", err)
+	}
+}
+
+func Test_BulkWrite_ShouldNotReadWhenEveryIdIsInvalid(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
+	results, err := mc.BulkWrite(context.Background(), []models.BatchOperation{{Kind: models.BatchPatch, Id: "1"}, {Kind: models.BatchDelete, Id: "2"}}, false)
+	if err != nil {
+		t.Fatal("Unexpected error in BulkWrite:", err)
+	}
+
+	for i, result := range results {
+		if !errors.Is(result.Err, models.ErrInvalidId) {
+			t.Errorf("Operation %d should fail with ErrInvalidId, but got %v", i, result.Err)
+		}
+	}
+}
+
+func Test_transactionError_ShouldReturnErrTransactionsUnsupportedOnStandaloneServer(t *testing.T) {
+	err := transactionError(mongo.CommandError{Code: illegalOperation, Message: "Transaction numbers are only allowed on a replica set member or mongos"})
+	if !errors.Is(err, models.ErrTransactionsUnsupported) {
+		t.Errorf("transactionError should return ErrTransactionsUnsupported, but got %v", err)
+	}
+
+	err = transactionError(mongo.ErrClientDisconnected)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("transactionError should return other errors as is, but got %v", err)
+	}
+}
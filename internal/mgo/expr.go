@@ -0,0 +1,76 @@
+package mgo
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// comparisonOperators are the Mongo operators each models.Operator compiles to
+var comparisonOperators = map[models.Operator]string{
+	models.OpEq:  "$eq",
+	models.OpNe:  "$ne",
+	models.OpLt:  "$lt",
+	models.OpLte: "$lte",
+	models.OpGt:  "$gt",
+	models.OpGte: "$gte",
+}
+
+// exprCondition compiles a filter expression into the query conditions that match it. Names and creators are
+// compared through their search keys, so that they match ignoring case and diacritics.
+func exprCondition(e models.Expr) bson.M {
+	switch e := e.(type) {
+	case models.And:
+		conditions := bson.A{}
+		for _, operand := range e {
+			conditions = append(conditions, exprCondition(operand))
+		}
+		return bson.M{"$and": conditions}
+	case models.Or:
+		conditions := bson.A{}
+		for _, operand := range e {
+			conditions = append(conditions, exprCondition(operand))
+		}
+		return bson.M{"$or": conditions}
+	case models.Not:
+		return bson.M{"$nor": bson.A{exprCondition(e.Expr)}}
+	case models.Comparison:
+		return bson.M{exprField(e.Field): bson.M{comparisonOperators[e.Operator]: exprValue(e.Field, e.Value)}}
+	case models.In:
+		values := bson.A{}
+		for _, value := range e.Values {
+			values = append(values, exprValue(e.Field, value))
+		}
+		return bson.M{exprField(e.Field): bson.M{"$in": values}}
+	case models.Has:
+		values := bson.A{}
+		for _, value := range e.Values {
+			values = append(values, exprValue(e.Field, value))
+		}
+		return setCondition(exprField(e.Field), values, e.Set)
+	default:
+		panic("mgo: unknown expression")
+	}
+}
+
+// exprField is the stored field a field of an expression is compared through
+func exprField(field string) string {
+	switch field {
+	case query.FieldName:
+		return "nameKey"
+	case query.FieldCreators:
+		return "creatorKeys"
+	default:
+		return field
+	}
+}
+
+// exprValue is value as it is compared with the stored field of exprField
+func exprValue(field string, value interface{}) interface{} {
+	if field == query.FieldName || field == query.FieldCreators {
+		return query.Fold(value.(string))
+	}
+
+	return value
+}
@@ -0,0 +1,56 @@
+package mgo
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_exprCondition_ShouldCompileExpressions(t *testing.T) {
+	e, err := query.ParseExpr(`year >= 1990 and (creators has "Anders Hejlsberg" or extensions has any [".ts", ".tsx"])`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing expression: %v", err)
+	}
+
+	expected := bson.M{"$and": bson.A{
+		bson.M{"year": bson.M{"$gte": int32(1990)}},
+		bson.M{"$or": bson.A{
+			bson.M{"creatorKeys": bson.M{"$all": bson.A{"anders hejlsberg"}}},
+			bson.M{"extensions": bson.M{"$in": bson.A{".ts", ".tsx"}}},
+		}},
+	}}
+
+	if condition := exprCondition(e); !reflect.DeepEqual(condition, expected) {
+		t.Errorf("exprCondition should return %v, but got %v", expected, condition)
+	}
+}
+
+func Test_exprCondition_ShouldCompareNamesThroughSearchKeys(t *testing.T) {
+	expected := bson.M{"$nor": bson.A{bson.M{"nameKey": bson.M{"$in": bson.A{"jose", "c++"}}}}}
+
+	condition := exprCondition(models.Not{Expr: models.In{Field: query.FieldName, Values: []interface{}{"José", "C++"}}})
+	if !reflect.DeepEqual(condition, expected) {
+		t.Errorf("exprCondition should return %v, but got %v", expected, condition)
+	}
+}
+
+func Test_exprCondition_ShouldCompareDatesAndNull(t *testing.T) {
+	date := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := bson.M{"$or": bson.A{
+		bson.M{"firstAppeared": bson.M{"$lt": date}},
+		bson.M{"firstAppeared": bson.M{"$eq": nil}},
+	}}
+
+	condition := exprCondition(models.Or{
+		models.Comparison{Field: query.FieldFirstAppeared, Operator: models.OpLt, Value: date},
+		models.Comparison{Field: query.FieldFirstAppeared, Operator: models.OpEq, Value: nil},
+	})
+	if !reflect.DeepEqual(condition, expected) {
+		t.Errorf("exprCondition should return %v, but got %v", expected, condition)
+	}
+}
@@ -0,0 +1,120 @@
+package mgo
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"context"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// facetResult is the single document the facet pipeline produces, with the page of languages, the total and
+// the counts of each facet that was asked for
+type facetResult struct {
+	Languages []models.Language `bson:"languages"`
+	Total     []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+	models.Facets `bson:",inline"`
+}
+
+// facetPipeline finds the page of languages matching conditions that Find would, in the same pass over them as it
+// totals and counts them by opts.Facets, with a $facet for each. The page is narrowed by keysetConditions, which
+// the total and counts ignore, and read in the order of sort with only the selected fields, or every field if nil.
+func facetPipeline(conditions bson.M, keysetConditions bson.M, sort bson.D, selected []string, opts models.FindOptions) mongo.Pipeline {
+	var page bson.A
+	if keysetConditions != nil {
+		page = append(page, bson.D{{Key: "$match", Value: keysetConditions}})
+	}
+
+	page = append(page, bson.D{{Key: "$sort", Value: sort}})
+
+	if opts.Offset > 0 {
+		page = append(page, bson.D{{Key: "$skip", Value: opts.Offset}})
+	}
+
+	if opts.Limit > 0 {
+		page = append(page, bson.D{{Key: "$limit", Value: opts.Limit}})
+	}
+
+	if selected != nil {
+		page = append(page, bson.D{{Key: "$project", Value: projection(selected)}})
+	}
+
+	facets := bson.D{
+		{Key: "languages", Value: page},
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+	}
+
+	counts := countStages()
+	for _, facet := range opts.Facets {
+		facets = append(facets, bson.E{Key: facet, Value: counts[facet]})
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: conditions}},
+		{{Key: "$facet", Value: facets}},
+	}
+}
+
+// findFaceted runs a pipeline from facetPipeline, with the collation the sort needs if any. The stages inside $facet
+// can't use indexes, so the page is sorted in memory and disk use is allowed for when that sort outgrows the server's
+// memory limit. The page is reversed afterwards if it was found by walking backwards from a before cursor.
+func (mc MongoClient) findFaceted(ctx context.Context, pipeline mongo.Pipeline, collation *options.Collation, reverse bool, facets []string) (languages models.Languages, errs []error) {
+	languages.Languages = []models.Language{}
+
+	aggregateOptions := options.Aggregate().SetAllowDiskUse(true)
+	if collation != nil {
+		aggregateOptions.SetCollation(collation)
+	}
+
+	findCtx, cancel := WithTimeout(ctx, mc.Timeouts.Read)
+	defer cancel()
+
+	cursor, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Aggregate(findCtx, pipeline, aggregateOptions)
+	if err != nil {
+		return languages, []error{TimeoutError(err)}
+	}
+
+	drainCtx, cancelDrain := WithTimeout(ctx, mc.Timeouts.CursorDrain)
+	defer cancelDrain()
+
+	defer func() {
+		err := cursor.Close(drainCtx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to close database cursor")
+		}
+	}()
+
+	var results []facetResult
+	err = cursor.All(drainCtx, &results)
+	if err != nil {
+		return languages, []error{TimeoutError(err)}
+	}
+
+	var result facetResult
+	if len(results) > 0 {
+		result = results[0]
+	}
+
+	if len(result.Languages) > 0 {
+		languages.Languages = result.Languages
+	}
+
+	if reverse {
+		slices.Reverse(languages.Languages)
+	}
+
+	if len(result.Total) > 0 {
+		languages.Total = result.Total[0].Count
+	}
+
+	languages.Facets = query.CompleteFacets(result.Facets, facets)
+
+	return languages, nil
+}
@@ -0,0 +1,90 @@
+package mgo
+
+import (
+	"languages-api/internal/models"
+
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_Find_ShouldReturnClientAggregateErrorWithFacets(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
+	languages, errs := mc.Find(context.Background(), models.Filter{}, models.FindOptions{Facets: []string{"year"}})
+	if len(errs) != 1 || !errors.Is(errs[0], mongo.ErrClientDisconnected) {
+		t.Errorf("Unexpected errors in Find: %v", errs)
+	}
+
+	if languages.Languages == nil {
+		t.Error("Find should return an empty page rather than nil")
+	}
+}
+
+func Test_facetPipeline_ShouldPageAndCountInOneFacetStage(t *testing.T) {
+	conditions := bson.M{"year": bson.M{"$gte": int32(1990)}}
+	keysetConditions := bson.M{"_id": bson.M{"$gt": "anchor"}}
+	sort := bson.D{{Key: "_id", Value: 1}}
+
+	pipeline := facetPipeline(conditions, keysetConditions, sort, []string{"_id", "revision", "name"},
+		models.FindOptions{Limit: 5, Offset: 10, Facets: []string{"creators", "decade"}})
+
+	if len(pipeline) != 2 || !reflect.DeepEqual(pipeline[0], bson.D{{Key: "$match", Value: conditions}}) {
+		t.Fatalf("facetPipeline should match the conditions before its $facet stage, but got %v", pipeline)
+	}
+
+	var facets []string
+	for _, facet := range pipeline[1][0].Value.(bson.D) {
+		facets = append(facets, facet.Key)
+	}
+
+	expected := []string{"languages", "total", "creators", "decade"}
+	if !reflect.DeepEqual(facets, expected) {
+		t.Errorf("facetPipeline should have the facets %v, but got %v", expected, facets)
+	}
+
+	page := bson.A{
+		bson.D{{Key: "$match", Value: keysetConditions}},
+		bson.D{{Key: "$sort", Value: sort}},
+		bson.D{{Key: "$skip", Value: int64(10)}},
+		bson.D{{Key: "$limit", Value: int64(5)}},
+		bson.D{{Key: "$project", Value: bson.M{"_id": 1, "revision": 1, "name": 1}}},
+	}
+	if languages := pipeline[1][0].Value.(bson.D)[0].Value; !reflect.DeepEqual(languages, page) {
+		t.Errorf("facetPipeline should find the page with %v, but got %v", page, languages)
+	}
+}
+
+func Test_facetResult_ShouldDecodeFacetsNamedAsQueryFacets(t *testing.T) {
+	data, err := bson.Marshal(bson.D{
+		{Key: "languages", Value: bson.A{bson.D{{Key: "name", Value: "Go"}}}},
+		{Key: "total", Value: bson.A{bson.D{{Key: "count", Value: int32(3)}}}},
+		{Key: "year", Value: bson.A{bson.D{{Key: "year", Value: int32(2009)}, {Key: "count", Value: int32(1)}}}},
+		{Key: "extensions", Value: bson.A{bson.D{{Key: "name", Value: ".go"}, {Key: "count", Value: int32(1)}}}},
+	})
+	if err != nil {
+		t.Fatal("Error marshalling result:", err)
+	}
+
+	var result facetResult
+	err = bson.Unmarshal(data, &result)
+	if err != nil {
+		t.Fatal("Error unmarshalling result:", err)
+	}
+
+	expected := models.Facets{
+		Year:       []models.YearCount{{Year: 2009, Count: 1}},
+		Extensions: []models.NameCount{{Name: ".go", Count: 1}},
+	}
+	if len(result.Languages) != 1 || result.Total[0].Count != 3 || !reflect.DeepEqual(result.Facets, expected) {
+		t.Errorf("facetResult should decode %+v, but got %+v", expected, result)
+	}
+}
@@ -3,9 +3,15 @@ package mgo
 import (
 	"languages-api/internal/config"
 	"languages-api/internal/models"
+	"languages-api/internal/query"
 
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -17,20 +23,131 @@ import (
 )
 
 const (
-	FiveSeconds = 5 * time.Second
-	TenSeconds  = 10 * time.Second
+	// DriverName is the Config.Driver value that selects MongoConnector
+	DriverName = "mongo"
+	// MigrationsCollectionName is the collection applied migrations are recorded in, next to the languages collection
+	MigrationsCollectionName = "migrations"
+	// MigrationLockCollectionName is the collection holding the migration lock, a single document whose _id is migrationLockId
+	MigrationLockCollectionName = "migrationLock"
+	// migrationLockId is the _id of the migration lock document
+	migrationLockId = "migrations"
 )
 
+func init() {
+	Register(DriverName, MongoConnector{})
+}
+
 // Client is for wrappers of mongo.Client
 type Client interface {
-	Ping() error
-	Disconnect() error
-	Find(filter interface{}) (languages models.Languages, errors []error)
-	FindOne(id string) (language models.Language, err error)
-	InsertOne(document interface{}) (insertedId string, err error)
-	ReplaceOne(id string, document interface{}) (isUpserted bool, err error)
-	UpdateOne(id string, update interface{}) (err error)
-	DeleteOne(id string) (err error)
+	Ping(ctx context.Context) error
+	Disconnect(ctx context.Context) error
+	EnsureIndexes(ctx context.Context) error
+	Find(ctx context.Context, filter interface{}, opts models.FindOptions) (languages models.Languages, errors []error)
+	// Stream calls send with each language Find would return, in the same order, as it is read, so that they are
+	// never all held at once. It doesn't total or count facets, and returns models.ErrStreamBefore if opts.Before
+	// is set. Streaming stops at the first error send returns, which Stream returns.
+	Stream(ctx context.Context, filter interface{}, opts models.FindOptions, send func(models.Language) error) (err error)
+	// FindOne returns only the given fields of the language, and its id and revision, or every field if fields is nil
+	FindOne(ctx context.Context, id string, fields []string) (language models.Language, err error)
+	InsertOne(ctx context.Context, document interface{}) (insertedId string, err error)
+	// ReplaceOne, UpdateOne and DeleteOne only write if the stored revision is the given one, returning
+	// models.ErrPreconditionFailed otherwise. A revision of 0 writes unconditionally, upserting in ReplaceOne, and
+	// models.AnyRevision writes whatever the stored revision as long as the language exists. ReplaceOne and
+	// UpdateOne return the revision they wrote the language at.
+	ReplaceOne(ctx context.Context, id string, document interface{}, revision int64) (written int64, isUpserted bool, err error)
+	UpdateOne(ctx context.Context, id string, update interface{}, revision int64) (written int64, err error)
+	DeleteOne(ctx context.Context, id string, revision int64) (err error)
+	// BulkWrite makes each of the operations in order, returning the result of each. Unless atomic is set, an
+	// operation failing doesn't stop the rest from being made. If it is, either every operation is made or none
+	// are, and those that could have been made are marked models.ErrNotApplied. err is only set if the batch as
+	// a whole failed, in which case some of the operations may have been made unless atomic is set.
+	BulkWrite(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error)
+	// Stats summarises every stored language, keeping the topCreators most prolific creators, or all of them if
+	// topCreators is 0
+	Stats(ctx context.Context, topCreators int64) (stats models.Stats, err error)
+}
+
+// Indexes are the indexes EnsureIndexes creates on the languages collection: a unique index on the folded name, so
+// that names differing only in case or diacritics conflict, and indexes on the other fields Find filters by
+var Indexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "nameKey", Value: 1}}, Options: options.Index().SetName(NameIndex).SetUnique(true)},
+	{Keys: bson.D{{Key: "year", Value: 1}}, Options: options.Index().SetName("year")},
+	{Keys: bson.D{{Key: "creators", Value: 1}}, Options: options.Index().SetName("creators")},
+	{Keys: bson.D{{Key: "extensions", Value: 1}}, Options: options.Index().SetName("extensions")},
+	{Keys: bson.D{{Key: "creatorKeys", Value: 1}}, Options: options.Index().SetName("creatorKeys")},
+}
+
+const (
+	// NameIndex is the name of the unique index on the folded name
+	NameIndex = "nameKey_unique"
+	// keyIndex is the index earlier versions created on the folded name, which the unique index on the same key
+	// can't be built alongside
+	keyIndex = "nameKey"
+	// rawNameIndex is the unique index earlier versions created on the name as it was given, which is dropped once
+	// NameIndex has been built in its place
+	rawNameIndex = "name_unique"
+)
+
+// Codes of the errors dropping an index returns if there is nothing to drop
+const (
+	namespaceNotFound = 26
+	indexNotFound     = 27
+)
+
+// storedLanguage is a language as it is stored in Mongo, along with the folded copies of its name and creators
+// that the name and creators filters are matched against, and of its extensions and wiki that searches also look in
+type storedLanguage struct {
+	models.Language `bson:",inline"`
+	NameKey         string   `bson:"nameKey"`
+	CreatorKeys     []string `bson:"creatorKeys"`
+	ExtensionKeys   []string `bson:"extensionKeys"`
+	WikiKey         string   `bson:"wikiKey"`
+}
+
+// SearchKeys returns the folded copies of the name, creators, extensions and wiki to store alongside a language.
+// Every write that sets one of those fields must also set its key.
+func SearchKeys(language models.Language) bson.M {
+	keys := bson.M{}
+
+	if language.Name != "" {
+		keys["nameKey"] = query.Fold(language.Name)
+	}
+
+	if len(language.Creators) > 0 {
+		keys["creatorKeys"] = foldAll(language.Creators)
+	}
+
+	if len(language.Extensions) > 0 {
+		keys["extensionKeys"] = foldAll(language.Extensions)
+	}
+
+	if language.Wiki != "" {
+		keys["wikiKey"] = query.Fold(language.Wiki)
+	}
+
+	return keys
+}
+
+// stored is a new language as InsertOne stores it, at revision 1
+func stored(lang models.Language) storedLanguage {
+	lang.Revision = 1
+	return storedLanguage{
+		Language:      lang,
+		NameKey:       query.Fold(lang.Name),
+		CreatorKeys:   foldAll(lang.Creators),
+		ExtensionKeys: foldAll(lang.Extensions),
+		WikiKey:       query.Fold(lang.Wiki),
+	}
+}
+
+// foldAll folds each of values, as the keys of an array field are stored
+func foldAll(values []string) []string {
+	keys := make([]string, len(values))
+	for i, value := range values {
+		keys[i] = query.Fold(value)
+	}
+
+	return keys
 }
 
 // MongoClient implements the Client interface
@@ -38,20 +155,18 @@ type MongoClient struct {
 	*mongo.Client
 	DatabaseName   string
 	CollectionName string
+	Timeouts       config.TimeoutConfig
 }
 
 type MongoDatabase struct {
 	*mongo.Database
 }
 
-func (mc MongoCursor) DecodeAll() (languages models.Languages, err error) {
+func (mc MongoCursor) DecodeAll(ctx context.Context) (languages models.Languages, err error) {
 	if mc.Cursor == nil {
 		return languages, models.ErrCursorNil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), FiveSeconds)
-	defer cancel()
-
 	defer func() {
 		err := mc.Cursor.Close(ctx)
 		if err != nil {
@@ -59,7 +174,7 @@ func (mc MongoCursor) DecodeAll() (languages models.Languages, err error) {
 		}
 	}()
 
-	err = mc.Cursor.All(ctx, &languages.Languages)
+	err = TimeoutError(mc.Cursor.All(ctx, &languages.Languages))
 
 	return
 }
@@ -89,59 +204,79 @@ type MongoDeleteResult struct {
 }
 
 // Ping checks the connection to mongo
-func (mc MongoClient) Ping() error {
-	ctx, cancel := context.WithTimeout(context.Background(), TenSeconds)
+func (mc MongoClient) Ping(ctx context.Context) error {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Ping)
 	defer cancel()
 
-	return mc.Client.Ping(ctx, readpref.Primary())
+	return TimeoutError(mc.Client.Ping(ctx, readpref.Primary()))
 }
 
 // Disconnect terminates the connection to mongo
-func (mc MongoClient) Disconnect() error {
-	ctx, cancel := context.WithTimeout(context.Background(), TenSeconds)
+func (mc MongoClient) Disconnect(ctx context.Context) error {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Connect)
 	defer cancel()
 
-	return mc.Client.Disconnect(ctx)
+	return TimeoutError(mc.Client.Disconnect(ctx))
 }
 
-func (mc MongoClient) Find(filter interface{}) (languages models.Languages, errs []error) {
-	conditions := bson.M{}
-
-	language := filter.(models.Language)
+// EnsureIndexes creates any of Indexes that don't exist yet, returning models.ErrDuplicateNames if the unique name
+// index can't be built because languages already share a folded name. The indexes on the name that earlier versions
+// created are dropped, the unique one only once the index replacing it has been built.
+func (mc MongoClient) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Index)
+	defer cancel()
 
-	if language.Name != "" {
-		conditions["name"] = bson.M{"$eq": language.Name}
+	err := mc.DropIndex(ctx, keyIndex)
+	if err != nil {
+		return err
 	}
 
-	if len(language.Creators) > 0 {
-		conditions["creators"] = bson.M{"$all": language.Creators}
+	_, err = mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Indexes().CreateMany(ctx, Indexes)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", models.ErrDuplicateNames, err)
 	}
 
-	if len(language.Extensions) > 0 {
-		conditions["extensions"] = bson.M{"$all": language.Extensions}
+	if err != nil {
+		return TimeoutError(err)
 	}
 
-	if language.FirstAppeared != nil {
-		conditions["firstAppeared"] = bson.M{"$eq": language.FirstAppeared}
-	}
+	return mc.DropIndex(ctx, rawNameIndex)
+}
 
-	if language.Year != 0 {
-		conditions["year"] = bson.M{"$eq": language.Year}
+// DropIndex drops the index with the given name from the languages collection, if there is one
+func (mc MongoClient) DropIndex(ctx context.Context, name string) error {
+	_, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Indexes().DropOne(ctx, name)
+
+	var se mongo.ServerError
+	if errors.As(err, &se) && (se.HasErrorCode(indexNotFound) || se.HasErrorCode(namespaceNotFound)) {
+		return nil
 	}
 
-	if language.Wiki != "" {
-		conditions["wiki"] = bson.M{"$eq": language.Wiki}
+	return TimeoutError(err)
+}
+
+func (mc MongoClient) Find(ctx context.Context, filter interface{}, opts models.FindOptions) (languages models.Languages, errs []error) {
+	conditions, keysetConditions, sort := findQuery(filter, opts)
+
+	// Before pages are found by walking backwards from the anchor, then put back in order
+	reverse := opts.Before != nil
+
+	if len(opts.Facets) > 0 {
+		return mc.findFaceted(ctx, facetPipeline(conditions, keysetConditions, sort, query.Selected(opts.Fields, opts.Sort), opts), collation(opts.Sort), reverse, opts.Facets)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), FiveSeconds)
+	findCtx, cancel := WithTimeout(ctx, mc.Timeouts.Read)
 	defer cancel()
 
-	cursor, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Find(ctx, conditions)
+	cursor, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Find(findCtx, pageConditions(conditions, keysetConditions), findOptions(sort, opts))
 	if err != nil {
-		errs = append(errs, err)
+		errs = append(errs, TimeoutError(err))
 	}
 
-	languages, err = MongoCursor{Cursor: cursor}.DecodeAll()
+	drainCtx, cancelDrain := WithTimeout(ctx, mc.Timeouts.CursorDrain)
+	defer cancelDrain()
+
+	languages, err = MongoCursor{Cursor: cursor}.DecodeAll(drainCtx)
 	if err != nil {
 		errs = append(errs, err)
 	}
@@ -150,87 +285,303 @@ func (mc MongoClient) Find(filter interface{}) (languages models.Languages, errs
 		languages.Languages = []models.Language{}
 	}
 
+	if reverse {
+		slices.Reverse(languages.Languages)
+	}
+
+	if len(errs) > 0 {
+		return
+	}
+
+	// A first page that isn't full already holds every matching language
+	if opts.Offset == 0 && opts.After == nil && opts.Before == nil && (opts.Limit == 0 || int64(len(languages.Languages)) < opts.Limit) {
+		languages.Total = int64(len(languages.Languages))
+		return
+	}
+
+	languages.Total, err = mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).CountDocuments(findCtx, conditions)
+	if err != nil {
+		errs = append(errs, TimeoutError(err))
+	}
+
 	return
 }
 
-func (mc MongoClient) FindOne(id string) (language models.Language, err error) {
+func (mc MongoClient) Stream(ctx context.Context, filter interface{}, opts models.FindOptions, send func(models.Language) error) (err error) {
+	if opts.Before != nil {
+		return models.ErrStreamBefore
+	}
+
+	conditions, keysetConditions, sort := findQuery(filter, opts)
+
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Stream)
+	defer cancel()
+
+	cursor, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Find(ctx, pageConditions(conditions, keysetConditions), findOptions(sort, opts))
+	if err != nil {
+		return TimeoutError(err)
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to close database cursor")
+		}
+	}()
+
+	for cursor.Next(ctx) {
+		var language models.Language
+		err = cursor.Decode(&language)
+		if err != nil {
+			return err
+		}
+
+		err = send(language)
+		if err != nil {
+			return err
+		}
+	}
+
+	return TimeoutError(cursor.Err())
+}
+
+// findQuery builds the conditions languages must meet to match filter, the conditions that narrow them to the
+// languages past the cursor in opts, if there is one, and the order to read them in. Languages before a cursor are
+// read in reverse, walking backwards from it.
+func findQuery(filter interface{}, opts models.FindOptions) (conditions bson.M, keysetConditions bson.M, sort bson.D) {
+	conditions = bson.M{}
+
+	f := filter.(models.Filter)
+
+	if f.Name != "" {
+		conditions["nameKey"] = matchCondition(f.Name, f.NameMatch)
+	}
+
+	// Conditions that may repeat a field are joined with $and
+	var arrays bson.A
+
+	if len(f.Creators) > 0 {
+		var creators bson.A
+		for _, creator := range f.Creators {
+			creators = append(creators, matchValue(creator, f.CreatorsMatch))
+		}
+		arrays = append(arrays, setCondition("creatorKeys", creators, f.CreatorsSet))
+	}
+
+	if len(f.Extensions) > 0 {
+		var extensions bson.A
+		for _, extension := range f.Extensions {
+			extensions = append(extensions, extension)
+		}
+		arrays = append(arrays, setCondition("extensions", extensions, f.ExtensionsSet))
+	}
+
+	if f.Expr != nil {
+		arrays = append(arrays, exprCondition(f.Expr))
+	}
+
+	for _, term := range f.Terms {
+		arrays = append(arrays, termCondition(term))
+	}
+
+	if len(arrays) > 0 {
+		conditions["$and"] = arrays
+	}
+
+	if !f.FirstAppeared.IsZero() {
+		conditions["firstAppeared"] = rangeConditions(f.FirstAppeared)
+	}
+
+	if !f.Year.IsZero() {
+		conditions["year"] = rangeConditions(f.Year)
+	}
+
+	if f.Wiki != "" {
+		conditions["wiki"] = bson.M{"$eq": f.Wiki}
+	}
+
+	reverse := opts.Before != nil
+
+	if opts.After != nil {
+		keysetConditions = keyset(*opts.After, opts.Sort, false)
+	}
+
+	if opts.Before != nil {
+		keysetConditions = keyset(*opts.Before, opts.Sort, true)
+	}
+
+	sort = bson.D{}
+	for _, f := range opts.Sort {
+		sort = append(sort, bson.E{Key: f.Field, Value: direction(f.Descending != reverse)})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: direction(reverse)})
+
+	return conditions, keysetConditions, sort
+}
+
+// pageConditions joins the conditions of a filter with those of a cursor, if there are any
+func pageConditions(conditions bson.M, keysetConditions bson.M) bson.M {
+	if keysetConditions == nil {
+		return conditions
+	}
+
+	return bson.M{"$and": bson.A{conditions, keysetConditions}}
+}
+
+// findOptions reads the page of languages opts asks for in the order of sort, with only the fields it selects
+func findOptions(sort bson.D, opts models.FindOptions) *options.FindOptions {
+	findOptions := options.Find().SetSort(sort).SetSkip(opts.Offset)
+	if opts.Limit > 0 {
+		findOptions.SetLimit(opts.Limit)
+	}
+
+	if selected := query.Selected(opts.Fields, opts.Sort); selected != nil {
+		findOptions.SetProjection(projection(selected))
+	}
+
+	if c := collation(opts.Sort); c != nil {
+		findOptions.SetCollation(c)
+	}
+
+	return findOptions
+}
+
+// collation compares names by the rules of query.Locale if sort orders languages by name, and is nil otherwise
+func collation(sort []models.SortField) *options.Collation {
+	if slices.ContainsFunc(sort, func(f models.SortField) bool { return f.Field == query.FieldName }) {
+		return &options.Collation{Locale: query.Locale}
+	}
+
+	return nil
+}
+
+func (mc MongoClient) FindOne(ctx context.Context, id string, fields []string) (language models.Language, err error) {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return models.Language{}, models.ErrInvalidId
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), FiveSeconds)
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Read)
 	defer cancel()
 
-	err = MongoSingleResult{SingleResult: mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).FindOne(ctx, bson.M{"_id": objectId})}.Decode(&language)
+	findOptions := options.FindOne()
+	if selected := query.Selected(fields, nil); selected != nil {
+		findOptions.SetProjection(projection(selected))
+	}
+
+	err = MongoSingleResult{SingleResult: mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).FindOne(ctx, bson.M{"_id": objectId}, findOptions)}.Decode(&language)
+	err = TimeoutError(err)
 
 	return
 }
 
-func (mc MongoClient) InsertOne(document interface{}) (insertedId string, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), FiveSeconds)
+func (mc MongoClient) InsertOne(ctx context.Context, document interface{}) (insertedId string, err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
 	defer cancel()
 
+	lang, isLanguage := document.(models.Language)
+	if isLanguage {
+		document = stored(lang)
+	}
+
 	ior, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).InsertOne(ctx, document)
+	if isLanguage {
+		err = duplicateIdError(mc.conflictError(ctx, err, lang.Name, lang.Id))
+	}
+	err = TimeoutError(err)
 
 	insertedId = MongoInsertOneResult{InsertOneResult: ior}.GetId()
 
 	return
 }
 
-func (mc MongoClient) ReplaceOne(id string, document interface{}) (isUpserted bool, err error) {
+// ReplaceOne sets every field of the stored language, so that the revision can be incremented in the same write. The
+// language is read as it was before the write, as only a language that wasn't there was upserted, whatever revision
+// it is written at: one written before revisions existed is also written at 1.
+func (mc MongoClient) ReplaceOne(ctx context.Context, id string, document interface{}, revision int64) (written int64, isUpserted bool, err error) {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return false, models.ErrInvalidId
+		return 0, false, models.ErrInvalidId
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), FiveSeconds)
+	lang := document.(models.Language)
+	if !lang.Id.IsZero() && lang.Id != objectId {
+		return 0, false, models.ErrIdMismatch
+	}
+
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
 	defer cancel()
 
-	upsert := options.ReplaceOptions{}
-	ur, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).ReplaceOne(ctx, bson.M{"_id": objectId}, document, upsert.SetUpsert(true))
+	var before struct {
+		Revision int64 `bson:"revision"`
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before).SetProjection(bson.M{"revision": 1}).SetUpsert(revision == 0)
+	err = mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).FindOneAndUpdate(ctx, revisionFilter(objectId, revision), replacement(lang), opts).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) && revision == 0 {
+		return 1, true, nil
+	}
 
-	isUpserted = MongoUpdateResult{UpdateResult: ur}.GetIsUpserted()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, false, models.ErrPreconditionFailed
+	}
 
-	return
+	err = mc.conflictError(ctx, err, lang.Name, objectId)
+	if err != nil {
+		return 0, false, TimeoutError(err)
+	}
+
+	return before.Revision + 1, false, nil
 }
 
-func (mc MongoClient) UpdateOne(id string, update interface{}) (err error) {
+func (mc MongoClient) UpdateOne(ctx context.Context, id string, update interface{}, revision int64) (written int64, err error) {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return models.ErrInvalidId
+		return 0, models.ErrInvalidId
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), FiveSeconds)
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
 	defer cancel()
 
-	lang := update.(models.Language)
-
-	ur, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).UpdateOne(ctx, bson.M{"_id": objectId}, bson.M{"$set": buildMap(lang)})
-
-	modifiedCount, matchedCount := MongoUpdateResult{UpdateResult: ur}.GetUpdateCounts()
+	var document bson.M
+	var name string
+	switch update := update.(type) {
+	case models.JSONPatch:
+		return mc.applyPatch(ctx, objectId, update, revision)
+	case models.Patch:
+		document = mergeChanges(update)
+		if update.Name.Value != nil {
+			name = *update.Name.Value
+		}
+	default:
+		lang := update.(models.Language)
+		document, name = changes(lang), lang.Name
+	}
 
-	if err == nil && modifiedCount == 0 && matchedCount == 0 {
-		err = models.ErrNotFound
+	written, err = mc.findAndUpdate(ctx, revisionFilter(objectId, revision), document, false)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, mc.missingError(ctx, objectId, revision)
 	}
 
-	return
+	err = mc.conflictError(ctx, err, name, objectId)
+
+	return written, TimeoutError(err)
 }
 
-func (mc MongoClient) DeleteOne(id string) (err error) {
+func (mc MongoClient) DeleteOne(ctx context.Context, id string, revision int64) (err error) {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return models.ErrInvalidId
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), FiveSeconds)
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
 	defer cancel()
 
-	dr, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).DeleteOne(ctx, bson.M{"_id": objectId})
+	dr, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).DeleteOne(ctx, revisionFilter(objectId, revision))
+	err = TimeoutError(err)
 
 	deletedCount := MongoDeleteResult{DeleteResult: dr}.GetDeletedCount()
 	if err == nil && deletedCount == 0 {
-		err = models.ErrNotFound
+		err = mc.missingError(ctx, objectId, revision)
 	}
 
 	return
@@ -289,17 +640,506 @@ type Connector interface {
 	Connect(cfg config.Config) (Client, error)
 }
 
+// AppliedMigrations returns the record of every migration that has been applied, in version order
+func (mc MongoClient) AppliedMigrations(ctx context.Context) (migrations []models.AppliedMigration, err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Read)
+	defer cancel()
+
+	cursor, err := mc.Client.Database(mc.DatabaseName).Collection(MigrationsCollectionName).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, TimeoutError(err)
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to close database cursor")
+		}
+	}()
+
+	err = cursor.All(ctx, &migrations)
+
+	return migrations, TimeoutError(err)
+}
+
+// RecordMigration marks a migration as applied
+func (mc MongoClient) RecordMigration(ctx context.Context, migration models.AppliedMigration) (err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
+	defer cancel()
+
+	_, err = mc.Client.Database(mc.DatabaseName).Collection(MigrationsCollectionName).InsertOne(ctx, migration)
+
+	return TimeoutError(err)
+}
+
+// RemoveMigration marks a migration as no longer applied
+func (mc MongoClient) RemoveMigration(ctx context.Context, version int) (err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
+	defer cancel()
+
+	_, err = mc.Client.Database(mc.DatabaseName).Collection(MigrationsCollectionName).DeleteOne(ctx, bson.M{"_id": version})
+
+	return TimeoutError(err)
+}
+
+// AcquireMigrationLock takes or extends the migration lock for owner, unless someone else holds an unexpired one.
+// The upsert only matches the lock document if owner holds it or it has expired, so when someone else holds it
+// the upsert tries to insert a second document with the same _id and fails with a duplicate key error.
+func (mc MongoClient) AcquireMigrationLock(ctx context.Context, owner string, until time.Time) (acquired bool, err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
+	defer cancel()
+
+	filter := bson.M{"_id": migrationLockId, "$or": bson.A{bson.M{"owner": owner}, bson.M{"expiresAt": bson.M{"$lt": time.Now()}}}}
+	update := bson.M{"$set": bson.M{"owner": owner, "expiresAt": until}}
+
+	_, err = mc.Client.Database(mc.DatabaseName).Collection(MigrationLockCollectionName).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, TimeoutError(err)
+	}
+
+	return true, nil
+}
+
+// ReleaseMigrationLock gives up the migration lock if owner holds it
+func (mc MongoClient) ReleaseMigrationLock(ctx context.Context, owner string) (err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Write)
+	defer cancel()
+
+	_, err = mc.Client.Database(mc.DatabaseName).Collection(MigrationLockCollectionName).DeleteOne(ctx, bson.M{"_id": migrationLockId, "owner": owner})
+
+	return TimeoutError(err)
+}
+
+// missingError works out why a write matched nothing: models.ErrPreconditionFailed if the language exists
+// but has a different revision to the one given, or doesn't exist and was required to by models.AnyRevision,
+// and models.ErrNotFound otherwise
+func (mc MongoClient) missingError(ctx context.Context, id primitive.ObjectID, revision int64) error {
+	if revision == models.AnyRevision {
+		return models.ErrPreconditionFailed
+	}
+
+	if revision == 0 {
+		return models.ErrNotFound
+	}
+
+	count, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return TimeoutError(err)
+	}
+
+	if count > 0 {
+		return models.ErrPreconditionFailed
+	}
+
+	return models.ErrNotFound
+}
+
+// patchAttempts is how many times applyPatch reads a language that keeps being written in between before it gives up
+const patchAttempts = 5
+
+// applyPatch applies a JSON patch to the stored language and replaces it with the result, pinned to the revision that
+// was read so that the patch is applied to the language as a whole. Without a revision to match, the language is read
+// again if it was written in between, up to patchAttempts times, after which models.ErrPreconditionFailed is returned.
+func (mc MongoClient) applyPatch(ctx context.Context, id primitive.ObjectID, patch models.JSONPatch, revision int64) (written int64, err error) {
+	collection := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName)
+
+	for range patchAttempts {
+		var stored models.Language
+		err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&stored)
+		if errors.Is(err, mongo.ErrNoDocuments) && revision == models.AnyRevision {
+			return 0, models.ErrPreconditionFailed
+		}
+
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, models.ErrNotFound
+		}
+
+		if err != nil {
+			return 0, TimeoutError(err)
+		}
+
+		if revision > 0 && stored.Revision != revision {
+			return 0, models.ErrPreconditionFailed
+		}
+
+		patched, err := query.ApplyPatch(stored, patch)
+		if err != nil {
+			return 0, err
+		}
+
+		written, err = mc.findAndUpdate(ctx, revisionFilter(id, stored.Revision), replacement(patched), false)
+		if err == nil {
+			return written, nil
+		}
+
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, TimeoutError(mc.conflictError(ctx, err, patched.Name, id))
+		}
+
+		if revision > 0 {
+			return 0, mc.missingError(ctx, id, revision)
+		}
+	}
+
+	return 0, models.ErrPreconditionFailed
+}
+
+// findAndUpdate makes update to the language filter matches, upserting it if upsert is set, and returns the revision
+// it was written at. It returns mongo.ErrNoDocuments if filter matched nothing.
+func (mc MongoClient) findAndUpdate(ctx context.Context, filter bson.M, update bson.M, upsert bool) (written int64, err error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"revision": 1}).SetUpsert(upsert)
+
+	var result struct {
+		Revision int64 `bson:"revision"`
+	}
+	err = mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
+
+	return result.Revision, err
+}
+
+// revisionFilter matches the language with the given id, as long as it has the given revision if that isn't 0 or
+// models.AnyRevision
+func revisionFilter(id primitive.ObjectID, revision int64) bson.M {
+	filter := bson.M{"_id": id}
+	if revision > 0 {
+		filter["revision"] = revision
+	}
+
+	return filter
+}
+
+// replacement is the update ReplaceOne makes, setting every field of the stored language so that the revision can be
+// incremented in the same write. Missing heuristics are unset rather than stored as null, as InsertOne leaves them
+// out, so that a merge patch can always set heuristics one at a time.
+func replacement(lang models.Language) bson.M {
+	update := bson.M{
+		"$set": bson.M{
+			"name":          lang.Name,
+			"creators":      lang.Creators,
+			"extensions":    lang.Extensions,
+			"firstAppeared": lang.FirstAppeared,
+			"year":          lang.Year,
+			"wiki":          lang.Wiki,
+			"nameKey":       query.Fold(lang.Name),
+			"creatorKeys":   foldAll(lang.Creators),
+			"extensionKeys": foldAll(lang.Extensions),
+			"wikiKey":       query.Fold(lang.Wiki),
+		},
+		"$inc": bson.M{"revision": 1},
+	}
+
+	if lang.Heuristics != nil {
+		update["$set"].(bson.M)["heuristics"] = lang.Heuristics
+	} else {
+		update["$unset"] = bson.M{"heuristics": ""}
+	}
+
+	return update
+}
+
+// changes is the update UpdateOne makes, setting the fields that are set in lang and incrementing the revision
+func changes(lang models.Language) bson.M {
+	changes := bson.M{"$inc": bson.M{"revision": 1}}
+	if set := buildMap(lang); len(set) > 0 {
+		for key, value := range SearchKeys(lang) {
+			set[key] = value
+		}
+		changes["$set"] = set
+	}
+
+	return changes
+}
+
+// mergeChanges is the update UpdateOne makes for a merge patch, setting the fields the patch sets and unsetting those
+// it sets to null, along with their search keys. Heuristics are set one at a time so that the rest are kept, and are
+// created if they don't exist yet, as RFC 7396 merges into an empty object.
+func mergeChanges(patch models.Patch) bson.M {
+	set, unset := bson.M{}, bson.M{}
+
+	mergeField(set, unset, "name", patch.Name)
+	mergeField(set, unset, "creators", patch.Creators)
+	mergeField(set, unset, "extensions", patch.Extensions)
+	mergeField(set, unset, "firstAppeared", patch.FirstAppeared)
+	mergeField(set, unset, "year", patch.Year)
+	mergeField(set, unset, "wiki", patch.Wiki)
+
+	if patch.Name.Value != nil {
+		set["nameKey"] = query.Fold(*patch.Name.Value)
+	}
+
+	if patch.Creators.Value != nil {
+		set["creatorKeys"] = foldAll(*patch.Creators.Value)
+	} else if patch.Creators.Set {
+		unset["creatorKeys"] = ""
+	}
+
+	if patch.Extensions.Value != nil {
+		set["extensionKeys"] = foldAll(*patch.Extensions.Value)
+	} else if patch.Extensions.Set {
+		unset["extensionKeys"] = ""
+	}
+
+	if patch.Wiki.Value != nil {
+		set["wikiKey"] = query.Fold(*patch.Wiki.Value)
+	} else if patch.Wiki.Set {
+		unset["wikiKey"] = ""
+	}
+
+	update := bson.M{"$inc": bson.M{"revision": 1}}
+
+	if heuristics := patch.Heuristics.Value; heuristics != nil {
+		mergeHeuristics(update, set, unset, *heuristics)
+	} else if patch.Heuristics.Set {
+		unset["heuristics"] = ""
+	}
+
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	return update
+}
+
+// mergeHeuristics adds what a merge patch of heuristics changes to update. Setting a member creates the heuristics if
+// they don't exist, but unsetting one doesn't, so a patch that gives no member a value stores its null members as
+// null, which reads the same as a missing member. A patch without members creates empty heuristics with $max, as an
+// empty object sorts below any other, and leaves existing heuristics alone.
+func mergeHeuristics(update bson.M, set bson.M, unset bson.M, patch models.HeuristicsPatch) {
+	members, removed := bson.M{}, bson.M{}
+	mergeField(members, removed, "heuristics.interpreters", patch.Interpreters)
+	mergeField(members, removed, "heuristics.modes", patch.Modes)
+	mergeField(members, removed, "heuristics.keywords", patch.Keywords)
+
+	switch {
+	case len(members) > 0:
+		maps.Copy(set, members)
+		maps.Copy(unset, removed)
+	case len(removed) > 0:
+		for key := range removed {
+			set[key] = nil
+		}
+	default:
+		update["$max"] = bson.M{"heuristics": bson.M{}}
+	}
+}
+
+// mergeField sets key to the value of a member of a merge patch, or unsets it if the member is null
+func mergeField[T any](set bson.M, unset bson.M, key string, field models.Field[T]) {
+	if !field.Set {
+		return
+	}
+
+	if field.Value == nil {
+		unset[key] = ""
+		return
+	}
+
+	set[key] = *field.Value
+}
+
+// projection includes only the given fields in the documents Mongo returns, so that the rest never leave the database
+func projection(fields []string) bson.M {
+	p := bson.M{}
+	for _, field := range fields {
+		p[field] = 1
+	}
+
+	return p
+}
+
+// matchCondition matches a folded search key against the folded value in the given mode
+func matchCondition(value string, mode models.MatchMode) interface{} {
+	value = query.Fold(value)
+
+	switch mode {
+	case models.MatchPrefix:
+		return bson.M{"$regex": "^" + regexp.QuoteMeta(value)}
+	case models.MatchContains:
+		return bson.M{"$regex": regexp.QuoteMeta(value)}
+	default:
+		return bson.M{"$eq": value}
+	}
+}
+
+// matchValue is the value an array element is compared with to match value in the given mode,
+// which is a regular expression for the modes that match part of the element
+func matchValue(value string, mode models.MatchMode) interface{} {
+	value = query.Fold(value)
+
+	switch mode {
+	case models.MatchPrefix:
+		return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(value)}
+	case models.MatchContains:
+		return primitive.Regex{Pattern: regexp.QuoteMeta(value)}
+	default:
+		return value
+	}
+}
+
+// setCondition matches the languages whose array field holds values in the way mode asks for.
+// $all, $in and $nin take regular expressions as well as values, so each element of values may be either.
+func setCondition(field string, values bson.A, mode models.SetMode) bson.M {
+	switch mode {
+	case models.SetAny:
+		return bson.M{field: bson.M{"$in": values}}
+	case models.SetNone:
+		return bson.M{field: bson.M{"$nin": values}}
+	case models.SetExact:
+		return bson.M{"$and": bson.A{
+			bson.M{field: bson.M{"$all": values}},
+			bson.M{field: bson.M{"$not": bson.M{"$elemMatch": bson.M{"$nin": values}}}},
+		}}
+	default:
+		return bson.M{field: bson.M{"$all": values}}
+	}
+}
+
+// termCondition matches the languages holding a folded search term in any of the fields query.Search looks in,
+// through the folded keys stored for each of them, so that it matches what Search would. Extensions are matched
+// without the term's leading dot, as Search compares them so.
+func termCondition(term string) bson.M {
+	pattern := regexp.QuoteMeta(term)
+
+	return bson.M{"$or": bson.A{
+		bson.M{"nameKey": primitive.Regex{Pattern: pattern}},
+		bson.M{"creatorKeys": primitive.Regex{Pattern: pattern}},
+		bson.M{"extensionKeys": primitive.Regex{Pattern: regexp.QuoteMeta(strings.TrimPrefix(term, "."))}},
+		bson.M{"wikiKey": primitive.Regex{Pattern: pattern}},
+	}}
+}
+
+// rangeConditions translates the bounds of r into comparison operators
+func rangeConditions[T any](r models.Range[T]) bson.M {
+	conditions := bson.M{}
+	for operator, bound := range map[string]*T{"$eq": r.Eq, "$gt": r.Gt, "$gte": r.Gte, "$lt": r.Lt, "$lte": r.Lte} {
+		if bound != nil {
+			conditions[operator] = *bound
+		}
+	}
+
+	return conditions
+}
+
+// keyset matches the languages that come after anchor when sorted by sort and then id, or before it if reverse is set.
+// Mongo sorts null before every date, but comparison operators never match null, so it is matched explicitly.
+func keyset(anchor models.Language, sort []models.SortField, reverse bool) bson.M {
+	fields := append(slices.Clone(sort), models.SortField{Field: "_id"})
+
+	var alternatives bson.A
+	for i, f := range fields {
+		var value interface{} = anchor.Id
+		if f.Field != "_id" {
+			value = query.Value(anchor, f.Field)
+		}
+
+		var beyond bson.M
+		switch {
+		case f.Descending == reverse && value == nil:
+			beyond = bson.M{f.Field: bson.M{"$ne": nil}}
+		case f.Descending == reverse:
+			beyond = bson.M{f.Field: bson.M{"$gt": value}}
+		case value == nil:
+			// Nothing comes before null
+			beyond = nil
+		default:
+			beyond = bson.M{"$or": bson.A{bson.M{f.Field: bson.M{"$lt": value}}, bson.M{f.Field: nil}}}
+		}
+
+		if beyond != nil {
+			// Languages that tie with the anchor on every more significant field
+			conditions := bson.A{}
+			for _, prev := range fields[:i] {
+				conditions = append(conditions, bson.M{prev.Field: query.Value(anchor, prev.Field)})
+			}
+
+			alternatives = append(alternatives, bson.M{"$and": append(conditions, beyond)})
+		}
+	}
+
+	return bson.M{"$or": alternatives}
+}
+
+// direction is the mongo sort direction for ascending or descending order
+func direction(descending bool) int {
+	if descending {
+		return -1
+	}
+
+	return 1
+}
+
+// conflictError turns a duplicate key error caused by the unique name index into a models.ConflictError
+// identifying the language that already has that folded name. Other errors, including duplicate ids, are returned
+// as is.
+func (mc MongoClient) conflictError(ctx context.Context, err error, name string, id primitive.ObjectID) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	var existing models.Language
+	findErr := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).FindOne(ctx, bson.M{"nameKey": query.Fold(name)}, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&existing)
+	if findErr != nil || existing.Id == id {
+		return err
+	}
+
+	return models.ConflictError{Id: existing.Id.Hex()}
+}
+
+// duplicateIdError turns a duplicate key error caused by the _id index into models.ErrDuplicateId. Other errors are
+// returned as is.
+func duplicateIdError(err error) error {
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorCodeWithMessage(11000, "index: _id_ ") {
+		return models.ErrDuplicateId
+	}
+
+	return err
+}
+
 // MongoConnector implements the Connector interface
 type MongoConnector struct{}
 
 // Connect establishes the connection to mongo
 func (mc MongoConnector) Connect(cfg config.Config) (Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), TenSeconds)
+	ctx, cancel := WithTimeout(context.Background(), cfg.Timeouts.Connect)
 	defer cancel()
-	opts := options.Client().ApplyURI(cfg.DBURL)
+	opts := options.Client().ApplyURI(cfg.Mongo.URL)
+	if cfg.Timeouts.Connect > 0 {
+		opts.SetConnectTimeout(cfg.Timeouts.Connect)
+	}
 
 	client, err := mongo.Connect(ctx, opts)
-	return &MongoClient{Client: client, DatabaseName: cfg.Database, CollectionName: cfg.Collection}, err
+	return &MongoClient{Client: client, DatabaseName: cfg.Mongo.Database, CollectionName: cfg.Mongo.Collection, Timeouts: cfg.Timeouts}, TimeoutError(err)
+}
+
+// WithTimeout bounds ctx by d, leaving it unbounded if d isn't positive
+func WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, d)
+}
+
+// TimeoutError wraps err in models.ErrTimeout if it was caused by a deadline expiring
+func TimeoutError(err error) error {
+	if err == nil || errors.Is(err, models.ErrTimeout) {
+		return err
+	}
+
+	if mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", models.ErrTimeout, err)
+	}
+
+	return err
 }
 
 func buildMap(language models.Language) bson.M {
@@ -329,5 +1169,9 @@ func buildMap(language models.Language) bson.M {
 		update["wiki"] = language.Wiki
 	}
 
+	if language.Heuristics != nil {
+		update["heuristics"] = language.Heuristics
+	}
+
 	return update
 }
@@ -4,6 +4,7 @@ import (
 	"languages-api/internal/config"
 	"languages-api/internal/models"
 
+	"context"
 	"errors"
 	"reflect"
 	"testing"
@@ -15,7 +16,7 @@ import (
 )
 
 func Test_DecodeAll_ShouldReturnErrCursorNilIfCursorIsNil(t *testing.T) {
-	_, err := MongoCursor{Cursor: nil}.DecodeAll()
+	_, err := MongoCursor{Cursor: nil}.DecodeAll(context.Background())
 	if !errors.Is(err, models.ErrCursorNil) {
 		t.Errorf("Cursor expected to be ErrCursorNil got %v", err)
 	}
@@ -58,7 +59,7 @@ func Test_DecodeAll_ShouldReturnLanguages(t *testing.T) {
 		t.Error("Error creating cursor:", err)
 	}
 
-	results, err := MongoCursor{Cursor: mcur}.DecodeAll()
+	results, err := MongoCursor{Cursor: mcur}.DecodeAll(context.Background())
 	if err != nil {
 		t.Error("Error decoding results:", err)
 
@@ -76,7 +77,7 @@ func Test_Ping_ShouldReturnClientPing(t *testing.T) {
 	}
 
 	mc := MongoClient{Client: c}
-	err = mc.Ping()
+	err = mc.Ping(context.Background())
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error pinging client: %v", err)
 	}
@@ -89,7 +90,7 @@ func Test_Disconnect_ShouldReturnClientDisconnect(t *testing.T) {
 	}
 
 	mc := MongoClient{Client: c}
-	err = mc.Disconnect()
+	err = mc.Disconnect(context.Background())
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error pinging client: %v", err)
 	}
@@ -102,7 +103,7 @@ func Test_Find_ShouldReturnClientFindErrorWithNoFilter(t *testing.T) {
 	}
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
-	_, errs := mc.Find(models.Language{})
+	_, errs := mc.Find(context.Background(), models.Filter{}, models.FindOptions{})
 	if !errors.Is(errs[0], mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error in Find: %v", errs[0])
 	}
@@ -120,8 +121,9 @@ func Test_Find_ShouldReturnClientFindErrorWithFilter(t *testing.T) {
 		t.Error("Error parsing timestamp:", err)
 	}
 
-	_, errs := mc.Find(models.Language{
-		Id:   primitive.NewObjectID(),
+	year := int32(2009)
+
+	_, errs := mc.Find(context.Background(), models.Filter{
 		Name: "Golang",
 		Creators: []string{
 			"Robert Griesemer",
@@ -131,15 +133,41 @@ func Test_Find_ShouldReturnClientFindErrorWithFilter(t *testing.T) {
 		Extensions: []string{
 			".go",
 		},
-		FirstAppeared: &firstAppeared,
-		Year:          2009,
+		FirstAppeared: models.Range[time.Time]{Eq: &firstAppeared},
+		Year:          models.Range[int32]{Gte: &year},
 		Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
-	})
+	}, models.FindOptions{})
 	if !errors.Is(errs[0], mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error in Find: %v", errs[0])
 	}
 }
 
+func Test_Stream_ShouldReturnClientFindError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
+	err = mc.Stream(context.Background(), models.Filter{}, models.FindOptions{}, func(models.Language) error {
+		t.Error("Stream should not send anything")
+		return nil
+	})
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("Unexpected error in Stream: %v", err)
+	}
+}
+
+func Test_Stream_ShouldReturnErrStreamBeforeIfGivenBefore(t *testing.T) {
+	mc := MongoClient{}
+	err := mc.Stream(context.Background(), models.Filter{}, models.FindOptions{Before: &models.Language{Id: primitive.NewObjectID()}}, func(models.Language) error {
+		return nil
+	})
+	if !errors.Is(err, models.ErrStreamBefore) {
+		t.Errorf("Expected ErrStreamBefore but got %v", err)
+	}
+}
+
 func Test_FindOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T) {
 	c, err := mongo.NewClient()
 	if err != nil {
@@ -148,7 +176,7 @@ func Test_FindOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	_, err = mc.FindOne("1")
+	_, err = mc.FindOne(context.Background(), "1", nil)
 	if !errors.Is(err, models.ErrInvalidId) {
 		t.Errorf("Unexpected error in FindOne: %v", err)
 	}
@@ -162,7 +190,7 @@ func Test_FindOne_ShouldReturnBlankLanguageIfGivenInvalidId(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	lang, err := mc.FindOne("1")
+	lang, err := mc.FindOne(context.Background(), "1", nil)
 	if !errors.Is(err, models.ErrInvalidId) {
 		t.Errorf("Unexpected error in FindOne: %v", err)
 	}
@@ -180,7 +208,7 @@ func Test_FindOne_ShouldReturnDecodeError(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	_, err = mc.FindOne(primitive.NewObjectID().Hex())
+	_, err = mc.FindOne(context.Background(), primitive.NewObjectID().Hex(), nil)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error in FindOne: %v", err)
 	}
@@ -194,7 +222,7 @@ func Test_InsertOne_ShouldReturnInsertOneError(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	_, err = mc.InsertOne(models.Language{})
+	_, err = mc.InsertOne(context.Background(), models.Language{})
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error in InsertOne: %v", err)
 	}
@@ -208,7 +236,7 @@ func Test_ReplaceOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	_, err = mc.ReplaceOne("1", models.Language{})
+	_, _, err = mc.ReplaceOne(context.Background(), "1", models.Language{}, 0)
 	if !errors.Is(err, models.ErrInvalidId) {
 		t.Errorf("Unexpected error in ReplaceOne: %v", err)
 	}
@@ -222,7 +250,7 @@ func Test_ReplaceOne_ShouldReturnFalseIfGivenInvalidId(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	isUpserted, err := mc.ReplaceOne("1", models.Language{})
+	_, isUpserted, err := mc.ReplaceOne(context.Background(), "1", models.Language{}, 0)
 	if !errors.Is(err, models.ErrInvalidId) {
 		t.Errorf("Unexpected error in ReplaceOne: %v", err)
 	}
@@ -240,7 +268,7 @@ func Test_ReplaceOne_ShouldReturnReplaceOneError(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	_, err = mc.ReplaceOne(primitive.NewObjectID().Hex(), models.Language{})
+	_, _, err = mc.ReplaceOne(context.Background(), primitive.NewObjectID().Hex(), models.Language{}, 0)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error in ReplaceOne: %v", err)
 	}
@@ -254,7 +282,7 @@ func Test_UpdateOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	err = mc.UpdateOne("1", models.Language{})
+	_, err = mc.UpdateOne(context.Background(), "1", models.Language{}, 0)
 	if !errors.Is(err, models.ErrInvalidId) {
 		t.Errorf("Unexpected error in UpdateOne: %v", err)
 	}
@@ -268,7 +296,21 @@ func Test_UpdateOne_ShouldReturnUpdateOneError(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	err = mc.UpdateOne(primitive.NewObjectID().Hex(), models.Language{})
+	_, err = mc.UpdateOne(context.Background(), primitive.NewObjectID().Hex(), models.Language{}, 0)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("Unexpected error in UpdateOne: %v", err)
+	}
+}
+
+func Test_UpdateOne_ShouldReturnFindOneErrorForJSONPatch(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
+
+	_, err = mc.UpdateOne(context.Background(), primitive.NewObjectID().Hex(), models.JSONPatch{}, 0)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error in UpdateOne: %v", err)
 	}
@@ -282,7 +324,7 @@ func Test_DeleteOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	err = mc.DeleteOne("1")
+	err = mc.DeleteOne(context.Background(), "1", 0)
 	if !errors.Is(err, models.ErrInvalidId) {
 		t.Errorf("Unexpected error in DeleteOne: %v", err)
 	}
@@ -296,7 +338,7 @@ func Test_DeleteOne_ShouldReturnUpdateOneError(t *testing.T) {
 
 	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
 
-	err = mc.DeleteOne(primitive.NewObjectID().Hex())
+	err = mc.DeleteOne(context.Background(), primitive.NewObjectID().Hex(), 0)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Unexpected error in DeleteOne: %v", err)
 	}
@@ -517,14 +559,14 @@ func Test_GetDeletedCount_ShouldReturnZeroIfResultIsNil(t *testing.T) {
 }
 
 func Test_Connect_ShouldReturnMongoConnectError(t *testing.T) {
-	_, err := MongoConnector{}.Connect(config.Config{DBURL: "mongodb://fake"})
+	_, err := MongoConnector{}.Connect(config.Config{Mongo: config.MongoConfig{URL: "mongodb://fake"}})
 	if err != nil {
 		t.Error("Unexpected error returned from Connect():", err)
 	}
 }
 
 func Test_Connect_ShouldReturnMongoClient(t *testing.T) {
-	c, err := MongoConnector{}.Connect(config.Config{DBURL: "mongodb://fake"})
+	c, err := MongoConnector{}.Connect(config.Config{Mongo: config.MongoConfig{URL: "mongodb://fake"}})
 	if err != nil {
 		t.Error("Unexpected error returned from Connect():", err)
 	}
@@ -564,6 +606,7 @@ func Test_buildMap_ShouldReturnFullMapIfGivenFullLanguage(t *testing.T) {
 		FirstAppeared: &firstAppeared,
 		Year:          2009,
 		Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
+		Heuristics:    &models.Heuristics{Modes: []string{"go"}, Keywords: []string{"package main"}},
 	}
 
 	expected := make(bson.M)
@@ -573,6 +616,7 @@ func Test_buildMap_ShouldReturnFullMapIfGivenFullLanguage(t *testing.T) {
 	expected["firstAppeared"] = lang.FirstAppeared
 	expected["year"] = lang.Year
 	expected["wiki"] = lang.Wiki
+	expected["heuristics"] = lang.Heuristics
 
 	result := buildMap(lang)
 
@@ -580,3 +624,189 @@ func Test_buildMap_ShouldReturnFullMapIfGivenFullLanguage(t *testing.T) {
 		t.Errorf("buildMap() should return %v, but got %v", expected, result)
 	}
 }
+
+func Test_TimeoutError_ShouldWrapDeadlineExceeded(t *testing.T) {
+	err := TimeoutError(context.DeadlineExceeded)
+	if !errors.Is(err, models.ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Error should wrap both ErrTimeout and context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func Test_TimeoutError_ShouldLeaveOtherErrorsAlone(t *testing.T) {
+	err := TimeoutError(models.ErrNotFound)
+	if err != models.ErrNotFound {
+		t.Errorf("Error should be ErrNotFound, got %v", err)
+	}
+}
+
+func Test_WithTimeout_ShouldNotSetDeadlineIfTimeoutIsNotPositive(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Context should not have a deadline")
+	}
+}
+
+func Test_keyset_ShouldMatchMissingDatesAfterAnchorWhenDescending(t *testing.T) {
+	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
+	if err != nil {
+		t.Error("Error parsing timestamp:", err)
+	}
+
+	anchor := models.Language{Id: primitive.NewObjectID(), FirstAppeared: &firstAppeared}
+
+	expected := bson.M{"$or": bson.A{
+		bson.M{"$and": bson.A{bson.M{"$or": bson.A{bson.M{"firstAppeared": bson.M{"$lt": firstAppeared}}, bson.M{"firstAppeared": nil}}}}},
+		bson.M{"$and": bson.A{bson.M{"firstAppeared": firstAppeared}, bson.M{"_id": bson.M{"$gt": anchor.Id}}}},
+	}}
+
+	filter := keyset(anchor, []models.SortField{{Field: "firstAppeared", Descending: true}}, false)
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("keyset should return %v, but got %v", expected, filter)
+	}
+}
+
+func Test_SearchKeys_ShouldFoldEverySearchedField(t *testing.T) {
+	expected := bson.M{"nameKey": "elixir", "creatorKeys": []string{"jose valim"}, "extensionKeys": []string{".ex", ".exs"}, "wikiKey": "https://fr.wikipedia.org/wiki/elixir_(langage)"}
+
+	keys := SearchKeys(models.Language{Name: "Elixir", Creators: []string{"José Valim"}, Extensions: []string{".EX", ".exs"}, Year: 2012, Wiki: "https://fr.wikipedia.org/wiki/Elixir_(langage)"})
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("SearchKeys should return %v, but got %v", expected, keys)
+	}
+}
+
+func Test_projection_ShouldIncludeOnlyGivenFields(t *testing.T) {
+	expected := bson.M{"_id": 1, "revision": 1, "name": 1}
+
+	if p := projection([]string{"_id", "revision", "name"}); !reflect.DeepEqual(p, expected) {
+		t.Errorf("projection should return %v, but got %v", expected, p)
+	}
+}
+
+func Test_matchCondition_ShouldEscapePatterns(t *testing.T) {
+	expected := bson.M{"$regex": "^c\\+\\+"}
+
+	condition := matchCondition("C++", models.MatchPrefix)
+	if !reflect.DeepEqual(condition, expected) {
+		t.Errorf("matchCondition should return %v, but got %v", expected, condition)
+	}
+}
+
+func Test_matchValue_ShouldReturnRegexForPartialModes(t *testing.T) {
+	if value := matchValue("José", models.MatchExact); value != "jose" {
+		t.Errorf("matchValue should return jose, but got %v", value)
+	}
+
+	expected := primitive.Regex{Pattern: "^c\\+\\+"}
+	if value := matchValue("C++", models.MatchPrefix); !reflect.DeepEqual(value, expected) {
+		t.Errorf("matchValue should return %v, but got %v", expected, value)
+	}
+}
+
+func Test_termCondition_ShouldMatchTheTermInEverySearchedField(t *testing.T) {
+	expected := bson.M{"$or": bson.A{
+		bson.M{"nameKey": primitive.Regex{Pattern: "\\.c\\+\\+"}},
+		bson.M{"creatorKeys": primitive.Regex{Pattern: "\\.c\\+\\+"}},
+		bson.M{"extensionKeys": primitive.Regex{Pattern: "c\\+\\+"}},
+		bson.M{"wikiKey": primitive.Regex{Pattern: "\\.c\\+\\+"}},
+	}}
+
+	if condition := termCondition(".c++"); !reflect.DeepEqual(condition, expected) {
+		t.Errorf("termCondition should return %v, but got %v", expected, condition)
+	}
+}
+
+func Test_setCondition_ShouldTranslateEachSetMode(t *testing.T) {
+	values := bson.A{".h", ".hpp"}
+
+	for mode, expected := range map[models.SetMode]bson.M{
+		models.SetAll:  {"extensions": bson.M{"$all": values}},
+		models.SetAny:  {"extensions": bson.M{"$in": values}},
+		models.SetNone: {"extensions": bson.M{"$nin": values}},
+		models.SetExact: {"$and": bson.A{
+			bson.M{"extensions": bson.M{"$all": values}},
+			bson.M{"extensions": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$nin": values}}}},
+		}},
+	} {
+		if condition := setCondition("extensions", values, mode); !reflect.DeepEqual(condition, expected) {
+			t.Errorf("setCondition in mode %d should return %v, but got %v", mode, expected, condition)
+		}
+	}
+}
+
+func Test_replacement_ShouldUnsetMissingHeuristics(t *testing.T) {
+	update := replacement(models.Language{Name: "Go"})
+
+	if _, ok := update["$set"].(bson.M)["heuristics"]; ok {
+		t.Error("replacement should not set missing heuristics")
+	}
+
+	if !reflect.DeepEqual(update["$unset"], bson.M{"heuristics": ""}) {
+		t.Errorf("replacement should unset missing heuristics, but got %v", update["$unset"])
+	}
+}
+
+func Test_replacement_ShouldSetTheFoldedKeysSearchesMatch(t *testing.T) {
+	set := replacement(models.Language{Name: "Go", Extensions: []string{".GO"}, Wiki: "https://fr.wikipedia.org/wiki/Go_(langage)"})["$set"].(bson.M)
+
+	if !reflect.DeepEqual(set["extensionKeys"], []string{".go"}) || set["wikiKey"] != "https://fr.wikipedia.org/wiki/go_(langage)" {
+		t.Errorf("replacement should set the folded extensions and wiki, but got %v and %v", set["extensionKeys"], set["wikiKey"])
+	}
+}
+
+func Test_mergeChanges_ShouldSetAndUnsetTheMembersOfThePatch(t *testing.T) {
+	name, year, keywords := "Go", int32(2009), []string{"func"}
+	patch := models.Patch{
+		Name:     models.Field[string]{Set: true, Value: &name},
+		Creators: models.Field[[]string]{Set: true},
+		Year:     models.Field[int32]{Set: true, Value: &year},
+		Wiki:     models.Field[string]{Set: true},
+		Heuristics: models.Field[models.HeuristicsPatch]{Set: true, Value: &models.HeuristicsPatch{
+			Modes:    models.Field[[]string]{Set: true},
+			Keywords: models.Field[[]string]{Set: true, Value: &keywords},
+		}},
+	}
+
+	expected := bson.M{
+		"$set":   bson.M{"name": "Go", "nameKey": "go", "year": int32(2009), "heuristics.keywords": []string{"func"}},
+		"$unset": bson.M{"creators": "", "creatorKeys": "", "wiki": "", "wikiKey": "", "heuristics.modes": ""},
+		"$inc":   bson.M{"revision": 1},
+	}
+	if update := mergeChanges(patch); !reflect.DeepEqual(update, expected) {
+		t.Errorf("mergeChanges should return %v, but got %v", expected, update)
+	}
+}
+
+func Test_mergeChanges_ShouldUnsetNullHeuristics(t *testing.T) {
+	expected := bson.M{"$unset": bson.M{"heuristics": ""}, "$inc": bson.M{"revision": 1}}
+	if update := mergeChanges(models.Patch{Heuristics: models.Field[models.HeuristicsPatch]{Set: true}}); !reflect.DeepEqual(update, expected) {
+		t.Errorf("mergeChanges should return %v, but got %v", expected, update)
+	}
+}
+
+func Test_mergeChanges_ShouldCreateHeuristicsForAPatchThatSetsNoMember(t *testing.T) {
+	expected := bson.M{"$max": bson.M{"heuristics": bson.M{}}, "$inc": bson.M{"revision": 1}}
+	patch := models.Patch{Heuristics: models.Field[models.HeuristicsPatch]{Set: true, Value: &models.HeuristicsPatch{}}}
+	if update := mergeChanges(patch); !reflect.DeepEqual(update, expected) {
+		t.Errorf("mergeChanges should return %v, but got %v", expected, update)
+	}
+
+	expected = bson.M{"$set": bson.M{"heuristics.modes": nil}, "$inc": bson.M{"revision": 1}}
+	patch.Heuristics.Value.Modes.Set = true
+	if update := mergeChanges(patch); !reflect.DeepEqual(update, expected) {
+		t.Errorf("mergeChanges should return %v, but got %v", expected, update)
+	}
+}
+
+func Test_duplicateIdError_ShouldOnlyReturnErrDuplicateIdForTheIdIndex(t *testing.T) {
+	idError := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error collection: test.test index: _id_ dup key: { _id: ObjectId('5f1e7a4c2b3d4e5f6a7b8c9d') }"}}}
+	if err := duplicateIdError(idError); !errors.Is(err, models.ErrDuplicateId) {
+		t.Errorf("duplicateIdError should return ErrDuplicateId, but got %v", err)
+	}
+
+	nameError := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error collection: test.test index: nameKey_unique dup key: { nameKey: \"golang\" }"}}}
+	if err := duplicateIdError(nameError); errors.Is(err, models.ErrDuplicateId) {
+		t.Errorf("duplicateIdError should return errors on other indexes as is, but got %v", err)
+	}
+}
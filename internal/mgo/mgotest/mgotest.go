@@ -0,0 +1,1376 @@
+// Package mgotest checks that an mgo.Client behaves the way the repository
+// expects of every backend, whatever it stores its languages in.
+package mgotest
+
+import (
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// migrationLocker is the part of migrate.Store that takes and gives up the migration lock
+type migrationLocker interface {
+	AcquireMigrationLock(ctx context.Context, owner string, until time.Time) (acquired bool, err error)
+	ReleaseMigrationLock(ctx context.Context, owner string) (err error)
+}
+
+// Connector returns a new, empty client, disconnected when the test ends
+type Connector func(t *testing.T) mgo.Client
+
+// Run tests the client returned by connect against the contract every driver
+// shares, with each test given a client of its own
+func Run(t *testing.T, connect Connector) {
+	for _, test := range []struct {
+		name string
+		run  func(t *testing.T, connect Connector)
+	}{
+		{"BulkWrite_ShouldApplyEachOperationInOrder", testBulkWrite_ShouldApplyEachOperationInOrder},
+		{"BulkWrite_ShouldApplyNothingFromAnAtomicBatchWhenAnOperationFails", testBulkWrite_ShouldApplyNothingFromAnAtomicBatchWhenAnOperationFails},
+		{"BulkWrite_ShouldRequireLanguagesGivenAnyRevisionToExist", testBulkWrite_ShouldRequireLanguagesGivenAnyRevisionToExist},
+		{"DeleteOne_ShouldReturnErrInvalidIdIfGivenInvalidId", testDeleteOne_ShouldReturnErrInvalidIdIfGivenInvalidId},
+		{"DeleteOne_ShouldReturnErrNotFoundIfNotStored", testDeleteOne_ShouldReturnErrNotFoundIfNotStored},
+		{"DeleteOne_ShouldReturnErrPreconditionFailedOnStaleRevision", testDeleteOne_ShouldReturnErrPreconditionFailedOnStaleRevision},
+		{"FindOne_ShouldReturnErrInvalidIdIfGivenInvalidId", testFindOne_ShouldReturnErrInvalidIdIfGivenInvalidId},
+		{"FindOne_ShouldReturnErrNotFoundIfNotStored", testFindOne_ShouldReturnErrNotFoundIfNotStored},
+		{"FindOne_ShouldReturnOnlySelectedFields", testFindOne_ShouldReturnOnlySelectedFields},
+		{"Find_ShouldCountFacetsAcrossEveryMatchingLanguage", testFind_ShouldCountFacetsAcrossEveryMatchingLanguage},
+		{"Find_ShouldMatchArraysInEachSetMode", testFind_ShouldMatchArraysInEachSetMode},
+		{"Find_ShouldMatchEveryGivenField", testFind_ShouldMatchEveryGivenField},
+		{"Find_ShouldMatchFilterExpressions", testFind_ShouldMatchFilterExpressions},
+		{"Find_ShouldMatchNamesAndCreatorsIgnoringCaseAndDiacritics", testFind_ShouldMatchNamesAndCreatorsIgnoringCaseAndDiacritics},
+		{"Find_ShouldMatchRanges", testFind_ShouldMatchRanges},
+		{"Find_ShouldOnlyReturnLanguagesHoldingEverySearchTerm", testFind_ShouldOnlyReturnLanguagesHoldingEverySearchTerm},
+		{"Find_ShouldPageThroughEverySortWithoutGapsOrRepeats", testFind_ShouldPageThroughEverySortWithoutGapsOrRepeats},
+		{"Find_ShouldRequireAllFilterCreators", testFind_ShouldRequireAllFilterCreators},
+		{"Find_ShouldReturnContextErrorIfCancelled", testFind_ShouldReturnContextErrorIfCancelled},
+		{"Find_ShouldReturnEmptySliceWhenNothingIsStored", testFind_ShouldReturnEmptySliceWhenNothingIsStored},
+		{"Find_ShouldReturnRequestedPageWithTotal", testFind_ShouldReturnRequestedPageWithTotal},
+		{"Find_ShouldReturnSelectedAndSortFields", testFind_ShouldReturnSelectedAndSortFields},
+		{"MigrationLock_ShouldOnlyBeHeldByOneOwnerUntilItExpires", testMigrationLock_ShouldOnlyBeHeldByOneOwnerUntilItExpires},
+		{"InsertOne_ShouldReturnConflictErrorOnNameDifferingInCaseOrDiacritics", testInsertOne_ShouldReturnConflictErrorOnNameDifferingInCaseOrDiacritics},
+		{"InsertOne_ShouldReturnConflictErrorOnRepeatedName", testInsertOne_ShouldReturnConflictErrorOnRepeatedName},
+		{"InsertOne_ShouldReturnErrDuplicateIdOnRepeatedId", testInsertOne_ShouldReturnErrDuplicateIdOnRepeatedId},
+		{"ReplaceOne_ShouldReplaceIfStored", testReplaceOne_ShouldReplaceIfStored},
+		{"ReplaceOne_ShouldReturnErrInvalidIdIfGivenInvalidId", testReplaceOne_ShouldReturnErrInvalidIdIfGivenInvalidId},
+		{"ReplaceOne_ShouldReturnErrPreconditionFailedIfNotStoredAndGivenRevision", testReplaceOne_ShouldReturnErrPreconditionFailedIfNotStoredAndGivenRevision},
+		{"ReplaceOne_ShouldReturnWrittenRevision", testReplaceOne_ShouldReturnWrittenRevision},
+		{"ReplaceOne_ShouldUpsertIfNotStored", testReplaceOne_ShouldUpsertIfNotStored},
+		{"Stats_ShouldReturnEmptyPartsWhenNothingIsStored", testStats_ShouldReturnEmptyPartsWhenNothingIsStored},
+		{"Stats_ShouldSummariseEveryLanguage", testStats_ShouldSummariseEveryLanguage},
+		{"Stream_ShouldSendWhatFindReturnsInOrder", testStream_ShouldSendWhatFindReturnsInOrder},
+		{"Stream_ShouldStopAtFirstSendError", testStream_ShouldStopAtFirstSendError},
+		{"UpdateOne_ShouldApplyJSONPatchAsAWhole", testUpdateOne_ShouldApplyJSONPatchAsAWhole},
+		{"UpdateOne_ShouldApplyMergePatch", testUpdateOne_ShouldApplyMergePatch},
+		{"UpdateOne_ShouldCreateHeuristicsFromAnEmptyMergePatch", testUpdateOne_ShouldCreateHeuristicsFromAnEmptyMergePatch},
+		{"UpdateOne_ShouldOnlyReplaceHeuristicsIfGiven", testUpdateOne_ShouldOnlyReplaceHeuristicsIfGiven},
+		{"UpdateOne_ShouldOnlySetNonZeroFields", testUpdateOne_ShouldOnlySetNonZeroFields},
+		{"UpdateOne_ShouldReturnErrConflictWhenRenamingToExistingName", testUpdateOne_ShouldReturnErrConflictWhenRenamingToExistingName},
+		{"UpdateOne_ShouldReturnErrInvalidIdIfGivenInvalidId", testUpdateOne_ShouldReturnErrInvalidIdIfGivenInvalidId},
+		{"UpdateOne_ShouldReturnErrNotFoundForJSONPatchIfNotStored", testUpdateOne_ShouldReturnErrNotFoundForJSONPatchIfNotStored},
+		{"UpdateOne_ShouldReturnErrNotFoundForMergePatchIfNotStored", testUpdateOne_ShouldReturnErrNotFoundForMergePatchIfNotStored},
+		{"UpdateOne_ShouldReturnErrNotFoundIfNotStored", testUpdateOne_ShouldReturnErrNotFoundIfNotStored},
+		{"UpdateOne_ShouldReturnErrPreconditionFailedOnStaleRevision", testUpdateOne_ShouldReturnErrPreconditionFailedOnStaleRevision},
+		{"UpdateOne_ShouldReturnWrittenRevision", testUpdateOne_ShouldReturnWrittenRevision},
+		{"Writes_ShouldAllBeMadeWhenConcurrent", testWrites_ShouldAllBeMadeWhenConcurrent},
+		{"Writes_ShouldRequireLanguageToExistGivenAnyRevision", testWrites_ShouldRequireLanguageToExistGivenAnyRevision},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, connect)
+		})
+	}
+}
+
+// NewGolang returns a language with every field but its id and revision set
+func NewGolang(t *testing.T) models.Language {
+	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
+	if err != nil {
+		t.Error("Error parsing timestamp:", err)
+	}
+
+	return models.Language{
+		Name: "Golang",
+		Creators: []string{
+			"Robert Griesemer",
+			"Rob Pike",
+			"Ken Thompson",
+		},
+		Extensions: []string{
+			".go",
+		},
+		FirstAppeared: &firstAppeared,
+		Year:          2009,
+		Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
+	}
+}
+
+// seeded returns a client holding the languages in mockData.json, found
+// relative to the driver's package under internal
+func seeded(t *testing.T, connect Connector) mgo.Client {
+	c := connect(t)
+
+	data, err := os.ReadFile("../../mockData.json")
+	if err != nil {
+		t.Fatal("Error reading mock data:", err)
+	}
+
+	var mock models.Languages
+	err = json.Unmarshal(data, &mock)
+	if err != nil {
+		t.Fatal("Error unmarshalling mock data:", err)
+	}
+
+	for _, l := range mock.Languages {
+		if _, err := c.InsertOne(context.Background(), l); err != nil {
+			t.Error("Error inserting language:", err)
+		}
+	}
+
+	return c
+}
+
+func testBulkWrite_ShouldApplyEachOperationInOrder(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	created := NewGolang(t)
+	created.Id = primitive.NewObjectID()
+	upserted := primitive.NewObjectID()
+
+	results, err := c.BulkWrite(context.Background(), []models.BatchOperation{
+		{Kind: models.BatchCreate, Language: created},
+		{Kind: models.BatchPatch, Id: created.Id.Hex(), Language: models.Language{Year: 2012}, Revision: 1},
+		{Kind: models.BatchReplace, Id: upserted.Hex(), Language: models.Language{Name: "Carbon", Year: 2022}},
+		{Kind: models.BatchDelete, Id: primitive.NewObjectID().Hex()},
+		{Kind: models.BatchPatch, Id: "1", Language: models.Language{Year: 2013}},
+		{Kind: models.BatchDelete, Id: upserted.Hex(), Revision: 1},
+	}, false)
+	if err != nil {
+		t.Fatal("Unexpected error in BulkWrite:", err)
+	}
+
+	expected := []models.BatchResult{
+		{Id: created.Id.Hex()},
+		{Id: created.Id.Hex()},
+		{Id: upserted.Hex(), Upserted: true},
+		{Err: models.ErrNotFound},
+		{Err: models.ErrInvalidId},
+		{Id: upserted.Hex()},
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, expected[i].Err) || expected[i].Id != "" && result.Id != expected[i].Id || result.Upserted != expected[i].Upserted {
+			t.Errorf("Operation %d should have result %+v, but got %+v", i, expected[i], result)
+		}
+	}
+
+	lang, err := c.FindOne(context.Background(), created.Id.Hex(), nil)
+	if err != nil || lang.Year != 2012 || lang.Revision != 2 {
+		t.Errorf("BulkWrite should create and then patch the language, but got %v, %v", lang, err)
+	}
+
+	_, err = c.FindOne(context.Background(), upserted.Hex(), nil)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("BulkWrite should upsert and then delete the language, but got %v", err)
+	}
+}
+
+func testBulkWrite_ShouldApplyNothingFromAnAtomicBatchWhenAnOperationFails(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	results, err := c.BulkWrite(context.Background(), []models.BatchOperation{
+		{Kind: models.BatchPatch, Id: id, Language: models.Language{Year: 2012}},
+		{Kind: models.BatchCreate, Language: models.Language{Name: "Carbon"}},
+		{Kind: models.BatchCreate, Language: models.Language{Name: "Golang"}},
+	}, true)
+	if err != nil {
+		t.Fatal("Unexpected error in BulkWrite:", err)
+	}
+
+	var conflict models.ConflictError
+	if !errors.Is(results[0].Err, models.ErrNotApplied) || !errors.Is(results[1].Err, models.ErrNotApplied) || !errors.As(results[2].Err, &conflict) || conflict.Id != id {
+		t.Errorf("BulkWrite should only fail the conflicting create and mark the rest not applied, but got %+v", results)
+	}
+
+	lang, err := c.FindOne(context.Background(), id, nil)
+	if err != nil || lang.Year != 2009 || lang.Revision != 1 {
+		t.Errorf("BulkWrite should roll back the patch, but got %v, %v", lang, err)
+	}
+
+	languages, _ := c.Find(context.Background(), models.Filter{}, models.FindOptions{})
+	if len(languages.Languages) != 1 {
+		t.Errorf("BulkWrite should roll back the create, but found %d languages", len(languages.Languages))
+	}
+}
+
+func testBulkWrite_ShouldRequireLanguagesGivenAnyRevisionToExist(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+	missing := primitive.NewObjectID().Hex()
+
+	results, err := c.BulkWrite(context.Background(), []models.BatchOperation{
+		{Kind: models.BatchReplace, Id: missing, Language: models.Language{Name: "C"}, Revision: models.AnyRevision},
+		{Kind: models.BatchPatch, Id: missing, Language: models.Language{Year: 1972}, Revision: models.AnyRevision},
+		{Kind: models.BatchDelete, Id: missing, Revision: models.AnyRevision},
+		{Kind: models.BatchPatch, Id: id, Language: models.Language{Year: 2012}, Revision: models.AnyRevision},
+	}, false)
+	if err != nil {
+		t.Fatal("Error writing batch:", err)
+	}
+
+	for i, result := range results[:3] {
+		if !errors.Is(result.Err, models.ErrPreconditionFailed) {
+			t.Errorf("Operation %d should fail with ErrPreconditionFailed, but got %v", i, result.Err)
+		}
+	}
+
+	if results[3].Err != nil {
+		t.Errorf("Unexpected error patching stored language: %v", results[3].Err)
+	}
+
+	if _, err := c.FindOne(context.Background(), missing, nil); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("BulkWrite should not upsert a language given AnyRevision, but got %v", err)
+	}
+}
+
+func testDeleteOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T, connect Connector) {
+	err := connect(t).DeleteOne(context.Background(), "1", 0)
+	if !errors.Is(err, models.ErrInvalidId) {
+		t.Errorf("Unexpected error in DeleteOne: %v", err)
+	}
+}
+
+func testDeleteOne_ShouldReturnErrNotFoundIfNotStored(t *testing.T, connect Connector) {
+	err := connect(t).DeleteOne(context.Background(), primitive.NewObjectID().Hex(), 0)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Unexpected error in DeleteOne: %v", err)
+	}
+}
+
+func testDeleteOne_ShouldReturnErrPreconditionFailedOnStaleRevision(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	err = c.DeleteOne(context.Background(), id, 2)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("DeleteOne should return ErrPreconditionFailed, but got %v", err)
+	}
+
+	err = c.DeleteOne(context.Background(), id, 1)
+	if err != nil {
+		t.Errorf("DeleteOne should delete the language at its current revision, but got %v", err)
+	}
+}
+
+func testFindOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T, connect Connector) {
+	_, err := connect(t).FindOne(context.Background(), "1", nil)
+	if !errors.Is(err, models.ErrInvalidId) {
+		t.Errorf("Unexpected error in FindOne: %v", err)
+	}
+}
+
+func testFindOne_ShouldReturnErrNotFoundIfNotStored(t *testing.T, connect Connector) {
+	_, err := connect(t).FindOne(context.Background(), primitive.NewObjectID().Hex(), nil)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Unexpected error in FindOne: %v", err)
+	}
+}
+
+func testFindOne_ShouldReturnOnlySelectedFields(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	expected := models.Language{Name: "Golang", Year: 2009, Revision: 1}
+	expected.Id, _ = primitive.ObjectIDFromHex(id)
+
+	lang, err := c.FindOne(context.Background(), id, []string{"name", "year"})
+	if err != nil {
+		t.Error("Error finding language:", err)
+	}
+
+	if !reflect.DeepEqual(lang, expected) {
+		t.Errorf("FindOne should return %v, but got %v", expected, lang)
+	}
+}
+
+func testFind_ShouldCountFacetsAcrossEveryMatchingLanguage(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	gte := int32(2000)
+	langs, errs := c.Find(context.Background(), models.Filter{Year: models.Range[int32]{Gte: &gte}},
+		models.FindOptions{Limit: 2, Facets: []string{"decade", "creators"}})
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors in Find: %v", errs)
+	}
+
+	if len(langs.Languages) != 2 || langs.Total != 7 {
+		t.Errorf("Find should return 2 of 7 languages, but got %d of %d", len(langs.Languages), langs.Total)
+	}
+
+	expectedDecades := []models.YearCount{{Year: 2000, Count: 3}, {Year: 2010, Count: 4}}
+	if langs.Facets == nil || !reflect.DeepEqual(langs.Facets.Decade, expectedDecades) {
+		t.Fatalf("Find should count decades %v, but got %+v", expectedDecades, langs.Facets)
+	}
+
+	expectedCreators := []models.NameCount{{Name: "Anders Hejlsberg", Count: 2}, {Name: "Chris Lattner", Count: 1}}
+	if len(langs.Facets.Creators) != 12 || !reflect.DeepEqual(langs.Facets.Creators[:2], expectedCreators) {
+		t.Errorf("Find should count 12 creators starting with %v, but got %v", expectedCreators, langs.Facets.Creators)
+	}
+
+	if langs.Facets.Year != nil || langs.Facets.Extensions != nil {
+		t.Errorf("Find should only count the given facets, but got %+v", langs.Facets)
+	}
+
+	langs, errs = c.Find(context.Background(), models.Filter{Creators: []string{"anders hejlsberg"}},
+		models.FindOptions{Facets: []string{"extensions"}})
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors in Find: %v", errs)
+	}
+
+	expectedExtensions := []models.NameCount{
+		{Name: ".cs", Count: 1}, {Name: ".csx", Count: 1}, {Name: ".cts", Count: 1},
+		{Name: ".mts", Count: 1}, {Name: ".ts", Count: 1}, {Name: ".tsx", Count: 1},
+	}
+	if langs.Facets == nil || !reflect.DeepEqual(langs.Facets.Extensions, expectedExtensions) {
+		t.Errorf("Find should count extensions %v, but got %+v", expectedExtensions, langs.Facets)
+	}
+
+	langs, errs = c.Find(context.Background(), models.Filter{Name: "Nothing"}, models.FindOptions{Facets: []string{"year"}})
+	if len(errs) > 0 || langs.Facets == nil || langs.Facets.Year == nil || len(langs.Facets.Year) != 0 {
+		t.Errorf("Find should count no years when nothing matches, but got %+v, %v", langs.Facets, errs)
+	}
+}
+
+func testFind_ShouldMatchArraysInEachSetMode(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	for _, test := range []struct {
+		filter   models.Filter
+		expected []string
+	}{
+		{models.Filter{Extensions: []string{".h", ".hpp"}}, []string{"C++"}},
+		{models.Filter{Extensions: []string{".h", ".hpp"}, ExtensionsSet: models.SetAny}, []string{"C", "C++"}},
+		{models.Filter{Extensions: []string{".c", ".h"}, ExtensionsSet: models.SetExact}, []string{"C"}},
+		{models.Filter{Extensions: []string{".c"}, ExtensionsSet: models.SetExact}, nil},
+		{models.Filter{Name: "c", NameMatch: models.MatchPrefix, Creators: []string{"dennis", "bjarne"}, CreatorsMatch: models.MatchPrefix, CreatorsSet: models.SetNone}, []string{"C#", "COBOL"}},
+		{models.Filter{Creators: []string{"anders", "tim"}, CreatorsMatch: models.MatchPrefix, CreatorsSet: models.SetAny}, []string{"C#", "HTML", "TypeScript", "XML"}},
+		{models.Filter{Creators: []string{"rob pike", "ken thompson", "robert griesemer"}, CreatorsSet: models.SetExact}, []string{"Golang"}},
+		{models.Filter{Creators: []string{"rob", "ken"}, CreatorsMatch: models.MatchPrefix, CreatorsSet: models.SetExact}, []string{"Golang"}},
+		{models.Filter{Creators: []string{"rob pike", "ken thompson"}, CreatorsSet: models.SetExact}, nil},
+	} {
+		langs, errs := c.Find(context.Background(), test.filter, models.FindOptions{Sort: []models.SortField{{Field: "name"}}})
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors in Find: %v", errs)
+		}
+
+		var names []string
+		for _, l := range langs.Languages {
+			names = append(names, l.Name)
+		}
+
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Find with %+v should return %v, but got %v", test.filter, test.expected, names)
+		}
+	}
+}
+
+func testFind_ShouldMatchEveryGivenField(t *testing.T, connect Connector) {
+	c := connect(t)
+	golang := NewGolang(t)
+
+	for _, l := range []models.Language{golang, {Name: "C", Extensions: []string{".c", ".h"}, Year: 1972}} {
+		if _, err := c.InsertOne(context.Background(), l); err != nil {
+			t.Error("Error inserting language:", err)
+		}
+	}
+
+	langs, errs := c.Find(context.Background(), models.Filter{
+		Name:          golang.Name,
+		Creators:      golang.Creators,
+		Extensions:    golang.Extensions,
+		FirstAppeared: models.Range[time.Time]{Eq: golang.FirstAppeared},
+		Year:          models.Range[int32]{Eq: &golang.Year},
+		Wiki:          golang.Wiki,
+	}, models.FindOptions{})
+	if len(errs) > 0 {
+		t.Errorf("Unexpected errors in Find: %v", errs)
+	}
+
+	if len(langs.Languages) != 1 || langs.Languages[0].Name != golang.Name {
+		t.Errorf("Find should only return %s, but got %v", golang.Name, langs.Languages)
+	}
+}
+
+func testFind_ShouldMatchFilterExpressions(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	for _, test := range []struct {
+		expr     string
+		expected []string
+	}{
+		{`year>=1990 and (creators has "Anders Hejlsberg" or extensions has ".ts")`, []string{"C#", "TypeScript"}},
+		{`firstAppeared = null and year > 1980`, []string{"C#", "C++", "Elixir", "HTML", "Ruby"}},
+		{`not firstAppeared < "1990-01-01" and year < 1990`, []string{"Assembly", "C", "C++", "COBOL", "Fortran", "SQL"}},
+		{`firstAppeared != "1995-05-23" and year = 1995`, []string{"JavaScript", "PHP", "Ruby"}},
+		{`name in ["java", "PYTHON"] or creators has any ["guido van rossum", "jose valim"]`, []string{"Elixir", "Java", "Python"}},
+		{`extensions has only [".c", ".h"] or not creators has none ["ken thompson"]`, []string{"C", "Golang"}},
+	} {
+		e, err := query.ParseExpr(test.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %v", test.expr, err)
+		}
+
+		langs, errs := c.Find(context.Background(), models.Filter{Expr: e}, models.FindOptions{Sort: []models.SortField{{Field: "name"}}})
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors in Find: %v", errs)
+		}
+
+		var names []string
+		for _, l := range langs.Languages {
+			names = append(names, l.Name)
+		}
+
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Find with %s should return %v, but got %v", test.expr, test.expected, names)
+		}
+	}
+}
+
+func testFind_ShouldMatchNamesAndCreatorsIgnoringCaseAndDiacritics(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	for _, test := range []struct {
+		filter   models.Filter
+		expected []string
+	}{
+		{models.Filter{Name: "golang"}, []string{"Golang"}},
+		{models.Filter{Name: "JAVA", NameMatch: models.MatchPrefix}, []string{"Java", "JavaScript"}},
+		{models.Filter{Name: "script", NameMatch: models.MatchContains}, []string{"JavaScript", "TypeScript"}},
+		{models.Filter{Creators: []string{"jose valim"}}, []string{"Elixir"}},
+		{models.Filter{Creators: []string{"francois", "tim"}, CreatorsMatch: models.MatchPrefix}, []string{"XML"}},
+	} {
+		langs, errs := c.Find(context.Background(), test.filter, models.FindOptions{Sort: []models.SortField{{Field: "name"}}})
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors in Find: %v", errs)
+		}
+
+		var names []string
+		for _, l := range langs.Languages {
+			names = append(names, l.Name)
+		}
+
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Find with %+v should return %v, but got %v", test.filter, test.expected, names)
+		}
+	}
+}
+
+func testFind_ShouldMatchRanges(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	gte, lt := int32(1990), int32(2000)
+	after := time.Date(1995, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		filter   models.Filter
+		expected int
+	}{
+		{models.Filter{Year: models.Range[int32]{Gte: &gte, Lt: &lt}}, 7},
+		// Languages without a firstAppeared date are never in a date range
+		{models.Filter{Year: models.Range[int32]{Gte: &gte, Lt: &lt}, FirstAppeared: models.Range[time.Time]{Gt: &after}}, 3},
+	} {
+		langs, errs := c.Find(context.Background(), test.filter, models.FindOptions{})
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors in Find: %v", errs)
+		}
+
+		if len(langs.Languages) != test.expected {
+			t.Errorf("Find should return %d languages, but got %v", test.expected, langs.Languages)
+		}
+	}
+}
+
+func testFind_ShouldOnlyReturnLanguagesHoldingEverySearchTerm(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	all, errs := c.Find(context.Background(), models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		t.Fatal("Unexpected errors in Find:", errs)
+	}
+
+	for _, q := range []string{"java", "JOSÉ", "py", ".c++", "hejlsberg script", "wikipedia"} {
+		var expected []string
+		for _, result := range query.Search(all.Languages, q) {
+			expected = append(expected, result.Language.Name)
+		}
+
+		langs, errs := c.Find(context.Background(), models.Filter{Terms: query.Terms(q)}, models.FindOptions{})
+		if len(errs) > 0 {
+			t.Fatal("Unexpected errors in Find:", errs)
+		}
+
+		var names []string
+		for _, lang := range langs.Languages {
+			names = append(names, lang.Name)
+		}
+
+		if !reflect.DeepEqual(sortedNames(names), sortedNames(expected)) {
+			t.Errorf("Find given the terms of %q should return %v, but got %v", q, expected, names)
+		}
+	}
+}
+
+// sortedNames sorts names so that lists of languages can be compared whatever order they were found in
+func sortedNames(names []string) []string {
+	slices.Sort(names)
+	return names
+}
+
+func testFind_ShouldPageThroughEverySortWithoutGapsOrRepeats(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	for _, sort := range [][]models.SortField{
+		{{Field: "year", Descending: true}, {Field: "name"}},
+		{{Field: "firstAppeared"}},
+		{{Field: "firstAppeared", Descending: true}, {Field: "name", Descending: true}},
+	} {
+		all, _ := c.Find(context.Background(), models.Filter{}, models.FindOptions{Sort: sort})
+
+		var forward []models.Language
+		opts := models.FindOptions{Limit: 4, Sort: sort}
+		for {
+			langs, errs := c.Find(context.Background(), models.Filter{}, opts)
+			if len(errs) > 0 || len(langs.Languages) == 0 {
+				break
+			}
+
+			forward = append(forward, langs.Languages...)
+			opts.After = &langs.Languages[len(langs.Languages)-1]
+		}
+
+		var backward []models.Language
+		opts = models.FindOptions{Limit: 4, Sort: sort, Before: &all.Languages[len(all.Languages)-1]}
+		for {
+			langs, errs := c.Find(context.Background(), models.Filter{}, opts)
+			if len(errs) > 0 || len(langs.Languages) == 0 {
+				break
+			}
+
+			backward = append(langs.Languages, backward...)
+			opts.Before = &langs.Languages[0]
+		}
+
+		if !reflect.DeepEqual(forward, all.Languages) || !reflect.DeepEqual(backward, all.Languages[:len(all.Languages)-1]) {
+			t.Errorf("Paging by %v should return every language once in order, expected %v, got %v forwards and %v backwards", sort, all.Languages, forward, backward)
+		}
+	}
+}
+
+func testFind_ShouldRequireAllFilterCreators(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	if _, err := c.InsertOne(context.Background(), NewGolang(t)); err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	langs, _ := c.Find(context.Background(), models.Filter{Creators: []string{"Rob Pike", "Dennis Ritchie"}}, models.FindOptions{})
+	if len(langs.Languages) != 0 {
+		t.Errorf("Find should not match when only some creators match, but got %v", langs.Languages)
+	}
+
+	langs, _ = c.Find(context.Background(), models.Filter{Creators: []string{"Rob Pike", "Ken Thompson"}}, models.FindOptions{})
+	if len(langs.Languages) != 1 {
+		t.Errorf("Find should match when all creators match, but got %v", langs.Languages)
+	}
+}
+
+func testFind_ShouldReturnContextErrorIfCancelled(t *testing.T, connect Connector) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, errs := connect(t).Find(ctx, models.Filter{}, models.FindOptions{})
+	if len(errs) == 0 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("Find should return context.Canceled, but got %v", errs)
+	}
+}
+
+func testFind_ShouldReturnEmptySliceWhenNothingIsStored(t *testing.T, connect Connector) {
+	langs, errs := connect(t).Find(context.Background(), models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		t.Errorf("Unexpected errors in Find: %v", errs)
+	}
+
+	if langs.Languages == nil || len(langs.Languages) != 0 {
+		t.Errorf("Find should return an empty slice, but got %v", langs.Languages)
+	}
+}
+
+func testFind_ShouldReturnRequestedPageWithTotal(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	var languages []models.Language
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		languages = append(languages, models.Language{Id: primitive.NewObjectID(), Name: name})
+	}
+
+	// Insert them out of order to show that Find orders by id
+	for _, i := range []int{4, 2, 0, 3, 1} {
+		if _, err := c.InsertOne(context.Background(), languages[i]); err != nil {
+			t.Error("Error inserting language:", err)
+		}
+	}
+
+	for _, test := range []struct {
+		opts     models.FindOptions
+		expected []string
+	}{
+		{models.FindOptions{}, []string{"A", "B", "C", "D", "E"}},
+		{models.FindOptions{Limit: 2}, []string{"A", "B"}},
+		{models.FindOptions{Limit: 2, Offset: 1}, []string{"B", "C"}},
+		{models.FindOptions{Offset: 3}, []string{"D", "E"}},
+		{models.FindOptions{Limit: 2, After: &languages[1]}, []string{"C", "D"}},
+		{models.FindOptions{Limit: 2, After: &languages[3]}, []string{"E"}},
+		{models.FindOptions{Limit: 2, Before: &languages[3]}, []string{"B", "C"}},
+		{models.FindOptions{Limit: 2, Before: &languages[1]}, []string{"A"}},
+	} {
+		langs, errs := c.Find(context.Background(), models.Filter{}, test.opts)
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors in Find: %v", errs)
+		}
+
+		var names []string
+		for _, l := range langs.Languages {
+			names = append(names, l.Name)
+		}
+
+		if !reflect.DeepEqual(names, test.expected) || langs.Total != 5 {
+			t.Errorf("Find with %+v should return %v of 5, but got %v of %d", test.opts, test.expected, names, langs.Total)
+		}
+	}
+}
+
+func testFind_ShouldReturnSelectedAndSortFields(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	_, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	langs, _ := c.Find(context.Background(), models.Filter{}, models.FindOptions{Fields: []string{"name"}, Sort: []models.SortField{{Field: "year"}}})
+	if len(langs.Languages) != 1 {
+		t.Fatalf("Find should return 1 language, but got %v", langs.Languages)
+	}
+
+	lang := langs.Languages[0]
+	if lang.Name != "Golang" || lang.Year != 2009 || lang.Id.IsZero() || lang.Revision != 1 || lang.Creators != nil || lang.Wiki != "" {
+		t.Errorf("Find should return only the name, year, id and revision, but got %+v", lang)
+	}
+}
+
+func testInsertOne_ShouldReturnConflictErrorOnNameDifferingInCaseOrDiacritics(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), models.Language{Name: "Élan", Year: 1990})
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, err = c.InsertOne(context.Background(), models.Language{Name: "ELAN", Year: 1974})
+
+	var conflict models.ConflictError
+
+	if !errors.As(err, &conflict) || conflict.Id != id {
+		t.Errorf("InsertOne should return a ConflictError for %s, but got %v", id, err)
+	}
+}
+
+func testInsertOne_ShouldReturnConflictErrorOnRepeatedName(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, err = c.InsertOne(context.Background(), NewGolang(t))
+
+	var conflict models.ConflictError
+
+	if !errors.As(err, &conflict) || conflict.Id != id {
+		t.Errorf("InsertOne should return a ConflictError for %s, but got %v", id, err)
+	}
+}
+
+func testInsertOne_ShouldReturnErrDuplicateIdOnRepeatedId(t *testing.T, connect Connector) {
+	c := connect(t)
+	lang := NewGolang(t)
+	lang.Id = primitive.NewObjectID()
+
+	if _, err := c.InsertOne(context.Background(), lang); err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, err := c.InsertOne(context.Background(), lang)
+	if !errors.Is(err, models.ErrDuplicateId) {
+		t.Errorf("InsertOne should return ErrDuplicateId, but got %v", err)
+	}
+}
+
+func testReplaceOne_ShouldReplaceIfStored(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, isUpserted, err := c.ReplaceOne(context.Background(), id, models.Language{Name: "Go", Creators: []string{"Rob Pike"}}, 0)
+	if err != nil {
+		t.Error("Error replacing language:", err)
+	}
+
+	if isUpserted {
+		t.Errorf("ReplaceOne should return false, but got %v", isUpserted)
+	}
+
+	expected := models.Language{Name: "Go", Creators: []string{"Rob Pike"}}
+	expected.Id, _ = primitive.ObjectIDFromHex(id)
+	expected.Revision = 2
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang, expected) {
+		t.Errorf("ReplaceOne should result in %v, but got %v", expected, lang)
+	}
+}
+
+func testReplaceOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T, connect Connector) {
+	_, _, err := connect(t).ReplaceOne(context.Background(), "1", models.Language{}, 0)
+	if !errors.Is(err, models.ErrInvalidId) {
+		t.Errorf("Unexpected error in ReplaceOne: %v", err)
+	}
+}
+
+func testReplaceOne_ShouldReturnErrPreconditionFailedIfNotStoredAndGivenRevision(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	_, _, err := c.ReplaceOne(context.Background(), primitive.NewObjectID().Hex(), NewGolang(t), 1)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("ReplaceOne should return ErrPreconditionFailed, but got %v", err)
+	}
+}
+
+func testReplaceOne_ShouldReturnWrittenRevision(t *testing.T, connect Connector) {
+	c := connect(t)
+	id := primitive.NewObjectID().Hex()
+
+	for _, test := range []struct {
+		revision   int64
+		written    int64
+		isUpserted bool
+	}{
+		{0, 1, true},
+		{1, 2, false},
+		{models.AnyRevision, 3, false},
+		{0, 4, false},
+	} {
+		written, isUpserted, err := c.ReplaceOne(context.Background(), id, NewGolang(t), test.revision)
+		if err != nil || written != test.written || isUpserted != test.isUpserted {
+			t.Errorf("ReplaceOne given revision %d should write revision %d, upserting %v, but got %d, %v and %v", test.revision, test.written, test.isUpserted, written, isUpserted, err)
+		}
+	}
+}
+
+func testReplaceOne_ShouldUpsertIfNotStored(t *testing.T, connect Connector) {
+	_, isUpserted, err := connect(t).ReplaceOne(context.Background(), primitive.NewObjectID().Hex(), NewGolang(t), 0)
+	if err != nil {
+		t.Error("Error replacing language:", err)
+	}
+
+	if !isUpserted {
+		t.Errorf("ReplaceOne should return true, but got %v", isUpserted)
+	}
+}
+
+func testStats_ShouldReturnEmptyPartsWhenNothingIsStored(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	stats, err := c.Stats(context.Background(), 0)
+	if err != nil {
+		t.Fatal("Unexpected error in Stats:", err)
+	}
+
+	expected := models.Stats{
+		ByYear:               []models.YearCount{},
+		ByDecade:             []models.YearCount{},
+		TopCreators:          []models.NameCount{},
+		Extensions:           []models.NameCount{},
+		MissingFirstAppeared: []models.LanguageRef{},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Stats should return %+v, but got %+v", expected, stats)
+	}
+}
+
+func testStats_ShouldSummariseEveryLanguage(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	stats, err := c.Stats(context.Background(), 3)
+	if err != nil {
+		t.Fatal("Unexpected error in Stats:", err)
+	}
+
+	expectedTotals := models.StatsTotals{Languages: 22, Creators: 38, Extensions: 72, MissingFirstAppeared: 10, EarliestYear: 1947, LatestYear: 2015}
+	if stats.Totals != expectedTotals {
+		t.Errorf("Stats should total %+v, but got %+v", expectedTotals, stats.Totals)
+	}
+
+	expectedDecades := []models.YearCount{{Year: 1940, Count: 1}, {Year: 1950, Count: 2}, {Year: 1970, Count: 2}, {Year: 1980, Count: 3}, {Year: 1990, Count: 7}, {Year: 2000, Count: 3}, {Year: 2010, Count: 4}}
+	if !reflect.DeepEqual(stats.ByDecade, expectedDecades) {
+		t.Errorf("Stats should count decades %v, but got %v", expectedDecades, stats.ByDecade)
+	}
+
+	if len(stats.ByYear) != 18 || stats.ByYear[10] != (models.YearCount{Year: 1995, Count: 4}) {
+		t.Errorf("Stats should count 18 years with 4 languages in 1995, but got %v", stats.ByYear)
+	}
+
+	expectedCreators := []models.NameCount{{Name: "Anders Hejlsberg", Count: 2}, {Name: "Bjarne Stroustrup", Count: 1}, {Name: "Brendan Eich", Count: 1}}
+	if !reflect.DeepEqual(stats.TopCreators, expectedCreators) {
+		t.Errorf("Stats should rank creators %v, but got %v", expectedCreators, stats.TopCreators)
+	}
+
+	expectedExtensions := []models.NameCount{{Name: ".h", Count: 2}, {Name: ",.hh", Count: 1}, {Name: ".C", Count: 1}, {Name: ".H", Count: 1}}
+	if len(stats.Extensions) != 72 || !reflect.DeepEqual(stats.Extensions[:4], expectedExtensions) {
+		t.Errorf("Stats should count extensions case-sensitively starting with %v, but got %v", expectedExtensions, stats.Extensions)
+	}
+
+	var missing []string
+	for _, l := range stats.MissingFirstAppeared {
+		missing = append(missing, l.Name)
+	}
+
+	expectedMissing := []string{"Assembly", "C", "C++", "C#", "COBOL", "Elixir", "Fortran", "HTML", "Ruby", "SQL"}
+	if !reflect.DeepEqual(missing, expectedMissing) {
+		t.Errorf("Stats should list %v as missing firstAppeared, but got %v", expectedMissing, missing)
+	}
+}
+
+func testStream_ShouldSendWhatFindReturnsInOrder(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	gte := int32(1990)
+	filter := models.Filter{Year: models.Range[int32]{Gte: &gte}}
+	opts := models.FindOptions{Sort: []models.SortField{{Field: "year", Descending: true}}, Fields: []string{"name"}}
+
+	found, errs := c.Find(context.Background(), filter, opts)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors in Find: %v", errs)
+	}
+
+	var streamed []models.Language
+	err := c.Stream(context.Background(), filter, opts, func(language models.Language) error {
+		streamed = append(streamed, language)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Unexpected error in Stream:", err)
+	}
+
+	if len(streamed) != 14 || !reflect.DeepEqual(streamed, found.Languages) {
+		t.Errorf("Stream should send the 14 languages Find returns, %v, but sent %v", found.Languages, streamed)
+	}
+
+	opts.After, opts.Limit = &streamed[2], 3
+	streamed = nil
+	err = c.Stream(context.Background(), filter, opts, func(language models.Language) error {
+		streamed = append(streamed, language)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(streamed, found.Languages[3:6]) {
+		t.Errorf("Stream should send the page after its cursor, %v, but sent %v, %v", found.Languages[3:6], streamed, err)
+	}
+}
+
+func testStream_ShouldStopAtFirstSendError(t *testing.T, connect Connector) {
+	c := seeded(t, connect)
+
+	expected := errors.New("send error")
+
+	var sent int
+	err := c.Stream(context.Background(), models.Filter{}, models.FindOptions{}, func(models.Language) error {
+		sent++
+		return expected
+	})
+	if !errors.Is(err, expected) || sent != 1 {
+		t.Errorf("Stream should stop with %v after 1 language, but got %v after %d", expected, err, sent)
+	}
+
+	err = c.Stream(context.Background(), models.Filter{}, models.FindOptions{Before: &models.Language{Id: primitive.NewObjectID()}}, func(models.Language) error {
+		return nil
+	})
+	if !errors.Is(err, models.ErrStreamBefore) {
+		t.Errorf("Stream should return ErrStreamBefore, but got %v", err)
+	}
+}
+
+func testUpdateOne_ShouldApplyJSONPatchAsAWhole(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	var patch models.JSONPatch
+	err = json.Unmarshal([]byte(`[
+		{"op": "add", "path": "/creators/-", "value": "Russ Cox"},
+		{"op": "remove", "path": "/creators/0"},
+		{"op": "replace", "path": "/extensions/0", "value": ".golang"}
+	]`), &patch)
+	if err != nil {
+		t.Fatal("Error unmarshalling patch:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, patch, 1)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	expected := NewGolang(t)
+	expected.Id, _ = primitive.ObjectIDFromHex(id)
+	expected.Revision = 2
+	expected.Creators = []string{"Rob Pike", "Ken Thompson", "Russ Cox"}
+	expected.Extensions = []string{".golang"}
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang, expected) {
+		t.Errorf("UpdateOne should result in %v, but got %v", expected, lang)
+	}
+
+	err = json.Unmarshal([]byte(`[
+		{"op": "replace", "path": "/year", "value": 2012},
+		{"op": "test", "path": "/wiki", "value": "https://go.dev"}
+	]`), &patch)
+	if err != nil {
+		t.Fatal("Error unmarshalling patch:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, patch, 0)
+	var patchError models.PatchError
+	if !errors.As(err, &patchError) || patchError.Operation != 1 {
+		t.Errorf("UpdateOne should fail the test operation, but got %v", err)
+	}
+
+	lang, _ = c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang, expected) {
+		t.Errorf("UpdateOne should apply none of a failed patch, but got %v", lang)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, models.JSONPatch{}, 1)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("UpdateOne should return ErrPreconditionFailed, but got %v", err)
+	}
+}
+
+func testUpdateOne_ShouldApplyMergePatch(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	language := NewGolang(t)
+	language.Heuristics = &models.Heuristics{Interpreters: []string{"go"}, Modes: []string{"go"}, Keywords: []string{"package"}}
+
+	id, err := c.InsertOne(context.Background(), language)
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	var patch models.Patch
+	err = json.Unmarshal([]byte(`{"creators": ["Rob Pike"], "firstAppeared": null, "wiki": null, "heuristics": {"keywords": ["func"]}}`), &patch)
+	if err != nil {
+		t.Fatal("Error unmarshalling patch:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, patch, 1)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	expected := NewGolang(t)
+	expected.Id, _ = primitive.ObjectIDFromHex(id)
+	expected.Revision = 2
+	expected.Creators = []string{"Rob Pike"}
+	expected.FirstAppeared = nil
+	expected.Wiki = ""
+	expected.Heuristics = &models.Heuristics{Interpreters: []string{"go"}, Modes: []string{"go"}, Keywords: []string{"func"}}
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang, expected) {
+		t.Errorf("UpdateOne should result in %v, but got %v", expected, lang)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, patch, 1)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("UpdateOne should return ErrPreconditionFailed, but got %v", err)
+	}
+}
+
+func testUpdateOne_ShouldCreateHeuristicsFromAnEmptyMergePatch(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	language := NewGolang(t)
+	id, err := c.InsertOne(context.Background(), language)
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	var patch models.Patch
+	err = json.Unmarshal([]byte(`{"heuristics": {}}`), &patch)
+	if err != nil {
+		t.Fatal("Error unmarshalling patch:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, patch, 1)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang.Heuristics, &models.Heuristics{}) {
+		t.Errorf("UpdateOne should create empty heuristics, but got %+v", lang.Heuristics)
+	}
+
+	heuristics := &models.Heuristics{Keywords: []string{"package"}}
+	_, err = c.UpdateOne(context.Background(), id, models.Language{Heuristics: heuristics}, 2)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, patch, 3)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	lang, _ = c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang.Heuristics, heuristics) {
+		t.Errorf("UpdateOne should leave existing heuristics alone, but got %+v", lang.Heuristics)
+	}
+}
+
+func testUpdateOne_ShouldOnlyReplaceHeuristicsIfGiven(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	language := NewGolang(t)
+	language.Heuristics = &models.Heuristics{Interpreters: []string{}, Modes: []string{"go"}, Keywords: []string{"package main"}}
+
+	id, err := c.InsertOne(context.Background(), language)
+	if err != nil {
+		t.Fatal("Error inserting language:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, models.Language{Name: "Go"}, 0)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang.Heuristics, language.Heuristics) {
+		t.Errorf("UpdateOne without heuristics should keep %v, but got %v", language.Heuristics, lang.Heuristics)
+	}
+
+	expected := &models.Heuristics{Interpreters: []string{"gorun"}, Modes: []string{"go"}, Keywords: []string{"func"}}
+	_, err = c.UpdateOne(context.Background(), id, models.Language{Heuristics: expected}, 0)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	lang, _ = c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang.Heuristics, expected) {
+		t.Errorf("UpdateOne should set heuristics to %v, but got %v", expected, lang.Heuristics)
+	}
+
+	_, _, err = c.ReplaceOne(context.Background(), id, models.Language{Name: "Go"}, 0)
+	if err != nil {
+		t.Error("Error replacing language:", err)
+	}
+
+	lang, _ = c.FindOne(context.Background(), id, nil)
+	if lang.Heuristics != nil {
+		t.Errorf("ReplaceOne without heuristics should remove them, but got %v", lang.Heuristics)
+	}
+}
+
+func testUpdateOne_ShouldOnlySetNonZeroFields(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, models.Language{Name: "Go", Extensions: []string{".go", ".mod"}}, 0)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	expected := NewGolang(t)
+	expected.Id, _ = primitive.ObjectIDFromHex(id)
+	expected.Revision = 2
+	expected.Name = "Go"
+	expected.Extensions = []string{".go", ".mod"}
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if !reflect.DeepEqual(lang, expected) {
+		t.Errorf("UpdateOne should result in %v, but got %v", expected, lang)
+	}
+}
+
+func testUpdateOne_ShouldReturnErrConflictWhenRenamingToExistingName(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	_, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	id, err := c.InsertOne(context.Background(), models.Language{Name: "C"})
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, models.Language{Name: "Golang"}, 0)
+	if !errors.Is(err, models.ErrConflict) {
+		t.Errorf("UpdateOne should return ErrConflict, but got %v", err)
+	}
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if lang.Name != "C" {
+		t.Errorf("UpdateOne should leave the language unchanged, but got %v", lang)
+	}
+}
+
+func testUpdateOne_ShouldReturnErrInvalidIdIfGivenInvalidId(t *testing.T, connect Connector) {
+	_, err := connect(t).UpdateOne(context.Background(), "1", models.Language{}, 0)
+	if !errors.Is(err, models.ErrInvalidId) {
+		t.Errorf("Unexpected error in UpdateOne: %v", err)
+	}
+}
+
+func testUpdateOne_ShouldReturnErrNotFoundForJSONPatchIfNotStored(t *testing.T, connect Connector) {
+	_, err := connect(t).UpdateOne(context.Background(), primitive.NewObjectID().Hex(), models.JSONPatch{}, 0)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Unexpected error in UpdateOne: %v", err)
+	}
+}
+
+func testUpdateOne_ShouldReturnErrNotFoundForMergePatchIfNotStored(t *testing.T, connect Connector) {
+	_, err := connect(t).UpdateOne(context.Background(), primitive.NewObjectID().Hex(), models.Patch{}, 0)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Unexpected error in UpdateOne: %v", err)
+	}
+}
+
+func testUpdateOne_ShouldReturnErrNotFoundIfNotStored(t *testing.T, connect Connector) {
+	_, err := connect(t).UpdateOne(context.Background(), primitive.NewObjectID().Hex(), models.Language{Name: "Go"}, 0)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Unexpected error in UpdateOne: %v", err)
+	}
+}
+
+func testUpdateOne_ShouldReturnErrPreconditionFailedOnStaleRevision(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, models.Language{Year: 2012}, 1)
+	if err != nil {
+		t.Error("Error updating language:", err)
+	}
+
+	_, err = c.UpdateOne(context.Background(), id, models.Language{Year: 2013}, 1)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("UpdateOne should return ErrPreconditionFailed, but got %v", err)
+	}
+
+	lang, _ := c.FindOne(context.Background(), id, nil)
+	if lang.Year != 2012 || lang.Revision != 2 {
+		t.Errorf("UpdateOne should only apply the first update, but got %v", lang)
+	}
+}
+
+func testUpdateOne_ShouldReturnWrittenRevision(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	var patch models.Patch
+	err = json.Unmarshal([]byte(`{"wiki": null}`), &patch)
+	if err != nil {
+		t.Fatal("Error unmarshalling patch:", err)
+	}
+
+	for i, test := range []struct {
+		update   interface{}
+		revision int64
+	}{
+		{models.Language{Year: 2012}, 1},
+		{patch, models.AnyRevision},
+		{models.JSONPatch{{Op: "replace", Path: "/name", Value: json.RawMessage(`"Go"`)}}, 0},
+	} {
+		written, err := c.UpdateOne(context.Background(), id, test.update, test.revision)
+		if err != nil || written != int64(i+2) {
+			t.Errorf("UpdateOne given %T should write revision %d, but got %d and %v", test.update, i+2, written, err)
+		}
+	}
+}
+
+func testWrites_ShouldAllBeMadeWhenConcurrent(t *testing.T, connect Connector) {
+	c := connect(t)
+
+	id, err := c.InsertOne(context.Background(), NewGolang(t))
+	if err != nil {
+		t.Fatal("Error inserting language:", err)
+	}
+
+	const writers = 8
+	errs := make(chan error, 2*writers)
+
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			_, err := c.InsertOne(context.Background(), models.Language{Name: "Language " + strconv.Itoa(i), Year: 2000})
+			errs <- err
+		}()
+
+		go func() {
+			defer wg.Done()
+			_, err := c.UpdateOne(context.Background(), id, models.JSONPatch{{Op: "replace", Path: "/year", Value: json.RawMessage(strconv.Itoa(2000 + i))}}, 0)
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Every concurrent write should be made, but got %v", err)
+		}
+	}
+
+	lang, err := c.FindOne(context.Background(), id, nil)
+	if err != nil || lang.Revision != writers+1 {
+		t.Errorf("Every patch should be applied to the language in turn, but got revision %d and %v", lang.Revision, err)
+	}
+}
+
+func testWrites_ShouldRequireLanguageToExistGivenAnyRevision(t *testing.T, connect Connector) {
+	c := connect(t)
+	id := primitive.NewObjectID().Hex()
+
+	_, _, err := c.ReplaceOne(context.Background(), id, NewGolang(t), models.AnyRevision)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("ReplaceOne should return ErrPreconditionFailed, but got %v", err)
+	}
+
+	for _, update := range []interface{}{models.Language{Year: 2012}, models.Patch{}, models.JSONPatch{}} {
+		_, err = c.UpdateOne(context.Background(), id, update, models.AnyRevision)
+		if !errors.Is(err, models.ErrPreconditionFailed) {
+			t.Errorf("UpdateOne given %T should return ErrPreconditionFailed, but got %v", update, err)
+		}
+	}
+
+	err = c.DeleteOne(context.Background(), id, models.AnyRevision)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("DeleteOne should return ErrPreconditionFailed, but got %v", err)
+	}
+
+	if _, err := c.FindOne(context.Background(), id, nil); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("ReplaceOne should not upsert a language given AnyRevision, but got %v", err)
+	}
+}
+
+func testMigrationLock_ShouldOnlyBeHeldByOneOwnerUntilItExpires(t *testing.T, connect Connector) {
+	locker, ok := connect(t).(migrationLocker)
+	if !ok {
+		t.Skip("Client does not record migrations")
+	}
+
+	ctx := context.Background()
+	later := time.Now().Add(time.Minute)
+
+	for _, step := range []struct {
+		owner    string
+		until    time.Time
+		expected bool
+	}{
+		{"first", later, true},
+		{"second", later, false},
+		{"first", time.Now().Add(-time.Second), true},
+		{"second", later, true},
+		{"first", later, false},
+	} {
+		acquired, err := locker.AcquireMigrationLock(ctx, step.owner, step.until)
+		if err != nil {
+			t.Fatal("Error acquiring migration lock:", err)
+		}
+
+		if acquired != step.expected {
+			t.Errorf("Acquiring the lock for %s until %v should return %t, got %t", step.owner, step.until, step.expected, acquired)
+		}
+	}
+
+	err := locker.ReleaseMigrationLock(ctx, "first")
+	if err != nil {
+		t.Fatal("Error releasing migration lock:", err)
+	}
+
+	acquired, _ := locker.AcquireMigrationLock(ctx, "first", later)
+	if acquired {
+		t.Error("Releasing a lock held by someone else should leave it held")
+	}
+
+	err = locker.ReleaseMigrationLock(ctx, "second")
+	if err != nil {
+		t.Fatal("Error releasing migration lock:", err)
+	}
+
+	acquired, _ = locker.AcquireMigrationLock(ctx, "first", later)
+	if !acquired {
+		t.Error("The lock should be free to acquire once its holder releases it")
+	}
+}
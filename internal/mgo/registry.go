@@ -0,0 +1,40 @@
+package mgo
+
+import (
+	"languages-api/internal/config"
+
+	"fmt"
+	"sync"
+)
+
+var (
+	connectorsMu sync.RWMutex
+	connectors   = make(map[string]Connector)
+)
+
+// Register makes a storage connector available under name, so that it can be selected with Config.Driver.
+// It is meant to be called from the init function of the package implementing the connector.
+func Register(name string, c Connector) {
+	connectorsMu.Lock()
+	defer connectorsMu.Unlock()
+
+	if _, ok := connectors[name]; ok {
+		panic("mgo: Register called twice for driver " + name)
+	}
+
+	connectors[name] = c
+	config.RegisterDriver(name)
+}
+
+// Lookup returns the connector registered under name
+func Lookup(name string) (Connector, error) {
+	connectorsMu.RLock()
+	defer connectorsMu.RUnlock()
+
+	c, ok := connectors[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", config.ErrUnknownDriver, name)
+	}
+
+	return c, nil
+}
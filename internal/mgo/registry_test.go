@@ -0,0 +1,45 @@
+package mgo
+
+import (
+	"languages-api/internal/config"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_Lookup_ShouldReturnMongoConnectorForDriverName(t *testing.T) {
+	c, err := Lookup(DriverName)
+	if err != nil {
+		t.Error("Unexpected error returned from Lookup():", err)
+	}
+
+	if !reflect.DeepEqual(c, MongoConnector{}) {
+		t.Errorf("Lookup() should return MongoConnector, but got %v", c)
+	}
+}
+
+func Test_Lookup_ShouldReturnErrUnknownDriverForUnregisteredName(t *testing.T) {
+	_, err := Lookup("cassandra")
+	if !errors.Is(err, config.ErrUnknownDriver) {
+		t.Errorf("Lookup() should return ErrUnknownDriver, but got %v", err)
+	}
+}
+
+func Test_Register_ShouldRegisterDriverWithConfig(t *testing.T) {
+	Register("registry-test", MongoConnector{})
+
+	if !config.IsDriverRegistered("registry-test") {
+		t.Error("Register() should make the driver name valid for config")
+	}
+}
+
+func Test_Register_ShouldPanicOnDuplicateName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() should panic when a name is registered twice")
+		}
+	}()
+
+	Register(DriverName, MongoConnector{})
+}
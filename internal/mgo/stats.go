@@ -0,0 +1,134 @@
+package mgo
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// statsResult is the single document the stats pipeline produces, with one array for each facet
+type statsResult struct {
+	Totals               []models.StatsTotals `bson:"totals"`
+	ByYear               []models.YearCount   `bson:"byYear"`
+	ByDecade             []models.YearCount   `bson:"byDecade"`
+	Creators             []models.NameCount   `bson:"creators"`
+	Extensions           []models.NameCount   `bson:"extensions"`
+	MissingFirstAppeared []models.LanguageRef `bson:"missingFirstAppeared"`
+}
+
+// countStages count languages by each facet, with stages that shape the counts as models.YearCount or
+// models.NameCount. Stats uses them for its parts, and Find for the facets it is asked for.
+func countStages() map[string]bson.A {
+	count := bson.D{{Key: "$sum", Value: 1}}
+	byCount := bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}}
+	asName := bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "name", Value: "$_id"}, {Key: "count", Value: 1}}}}
+	asYear := bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "year", Value: "$_id"}, {Key: "count", Value: 1}}}}
+	byId := bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}}
+
+	return map[string]bson.A{
+		query.FacetYear: {
+			bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$year"}, {Key: "count", Value: count}}}},
+			byId,
+			asYear,
+		},
+		query.FacetDecade: {
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$subtract", Value: bson.A{"$year", bson.D{{Key: "$mod", Value: bson.A{"$year", 10}}}}}}},
+				{Key: "count", Value: count},
+			}}},
+			byId,
+			asYear,
+		},
+		query.FacetCreators: {
+			bson.D{{Key: "$unwind", Value: "$creators"}},
+			bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$creators"}, {Key: "count", Value: count}}}},
+			byCount,
+			asName,
+		},
+		query.FacetExtensions: {
+			bson.D{{Key: "$unwind", Value: "$extensions"}},
+			bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$extensions"}, {Key: "count", Value: count}}}},
+			byCount,
+			asName,
+		},
+	}
+}
+
+// statsPipeline computes every part of models.Stats in one pass over the collection, with a $facet for each.
+// Every creator is counted so that the distinct creators can be totalled, and only the top are kept afterwards by
+// query.CompleteStats, which also counts the other totals the facets already hold.
+func statsPipeline() mongo.Pipeline {
+	counts := countStages()
+
+	return mongo.Pipeline{
+		{{Key: "$facet", Value: bson.D{
+			{Key: "totals", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "languages", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "earliestYear", Value: bson.D{{Key: "$min", Value: "$year"}}},
+					{Key: "latestYear", Value: bson.D{{Key: "$max", Value: "$year"}}},
+				}}},
+			}},
+			{Key: "byYear", Value: counts[query.FacetYear]},
+			{Key: "byDecade", Value: counts[query.FacetDecade]},
+			{Key: "creators", Value: counts[query.FacetCreators]},
+			{Key: "extensions", Value: counts[query.FacetExtensions]},
+			{Key: "missingFirstAppeared", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "firstAppeared", Value: nil}}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: 1}}}},
+			}},
+		}}},
+	}
+}
+
+// Stats summarises the stored languages with an aggregation pipeline, keeping the topCreators most prolific
+// creators, or all of them if topCreators is 0. Disk use is allowed for when the groups and sorts of a large
+// collection outgrow the server's memory limit.
+func (mc MongoClient) Stats(ctx context.Context, topCreators int64) (stats models.Stats, err error) {
+	ctx, cancel := WithTimeout(ctx, mc.Timeouts.Read)
+	defer cancel()
+
+	cursor, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).Aggregate(ctx, statsPipeline(), options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return models.Stats{}, TimeoutError(err)
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to close database cursor")
+		}
+	}()
+
+	var results []statsResult
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return models.Stats{}, TimeoutError(err)
+	}
+
+	var result statsResult
+	if len(results) > 0 {
+		result = results[0]
+	}
+
+	stats = models.Stats{
+		ByYear:               result.ByYear,
+		ByDecade:             result.ByDecade,
+		TopCreators:          result.Creators,
+		Extensions:           result.Extensions,
+		MissingFirstAppeared: result.MissingFirstAppeared,
+	}
+	if len(result.Totals) > 0 {
+		stats.Totals = result.Totals[0]
+	}
+
+	return query.CompleteStats(stats, topCreators), nil
+}
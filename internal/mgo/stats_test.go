@@ -0,0 +1,46 @@
+package mgo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func Test_Stats_ShouldReturnClientAggregateError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	mc := MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}
+	_, err = mc.Stats(context.Background(), 10)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("Unexpected error in Stats: %v", err)
+	}
+}
+
+func Test_statsPipeline_ShouldHaveAFacetForEachResultField(t *testing.T) {
+	pipeline := statsPipeline()
+	if len(pipeline) != 1 || pipeline[0][0].Key != "$facet" {
+		t.Fatalf("statsPipeline should be a single $facet stage, but got %v", pipeline)
+	}
+
+	var facets []string
+	for _, facet := range pipeline[0][0].Value.(bson.D) {
+		facets = append(facets, facet.Key)
+	}
+
+	var fields []string
+	resultType := reflect.TypeOf(statsResult{})
+	for i := range resultType.NumField() {
+		fields = append(fields, resultType.Field(i).Tag.Get("bson"))
+	}
+
+	if !reflect.DeepEqual(facets, fields) {
+		t.Errorf("statsPipeline should have the facets %v, but got %v", fields, facets)
+	}
+}
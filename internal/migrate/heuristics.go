@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+
+	"context"
+	"errors"
+)
+
+// defaultHeuristics are the detection heuristics addHeuristics gives the languages of mockData.json, by name
+var defaultHeuristics = map[string]models.Heuristics{
+	"Assembly": {
+		Interpreters: []string{},
+		Modes:        []string{"asm", "nasm"},
+		Keywords:     []string{"mov", "section .text", "global _start", "syscall"},
+	},
+	"Bash": {
+		Interpreters: []string{"bash", "sh"},
+		Modes:        []string{"sh", "bash"},
+		Keywords:     []string{"fi", "esac", "elif", "$(", "${"},
+	},
+	"C": {
+		Interpreters: []string{},
+		Modes:        []string{"c"},
+		Keywords:     []string{"#include <stdio.h>", "#include <stdlib.h>", "printf", "malloc", "typedef struct"},
+	},
+	"C++": {
+		Interpreters: []string{},
+		Modes:        []string{"cpp", "c++"},
+		Keywords:     []string{"#include <iostream>", "std::", "namespace", "template", "class", "public:", "nullptr"},
+	},
+	"C#": {
+		Interpreters: []string{},
+		Modes:        []string{"cs", "csharp"},
+		Keywords:     []string{"using System", "namespace", "Console.WriteLine", "async Task"},
+	},
+	"COBOL": {
+		Interpreters: []string{},
+		Modes:        []string{"cobol"},
+		Keywords:     []string{"IDENTIFICATION DIVISION", "PROCEDURE DIVISION", "WORKING-STORAGE SECTION", "PIC"},
+	},
+	"Elixir": {
+		Interpreters: []string{"elixir"},
+		Modes:        []string{"elixir"},
+		Keywords:     []string{"defmodule", "defp", "|>", "do:", "IO.puts"},
+	},
+	"Fortran": {
+		Interpreters: []string{},
+		Modes:        []string{"fortran"},
+		Keywords:     []string{"program", "end program", "subroutine", "implicit none", "integer ::"},
+	},
+	"Golang": {
+		Interpreters: []string{},
+		Modes:        []string{"go"},
+		Keywords:     []string{"package main", "func", "import (", "fmt.Println", ":="},
+	},
+	"HTML": {
+		Interpreters: []string{},
+		Modes:        []string{"html"},
+		Keywords:     []string{"<!DOCTYPE html>", "<html", "<head>", "<body>", "<div"},
+	},
+	"Java": {
+		Interpreters: []string{"java"},
+		Modes:        []string{"java"},
+		Keywords:     []string{"public static void main", "System.out.println", "import java.", "public class"},
+	},
+	"JavaScript": {
+		Interpreters: []string{"node", "nodejs"},
+		Modes:        []string{"javascript", "js"},
+		Keywords:     []string{"function", "const", "console.log", "=>", "require(", "export default"},
+	},
+	"Perl": {
+		Interpreters: []string{"perl"},
+		Modes:        []string{"perl"},
+		Keywords:     []string{"use strict", "use warnings", "my $", "sub"},
+	},
+	"PHP": {
+		Interpreters: []string{"php"},
+		Modes:        []string{"php"},
+		Keywords:     []string{"<?php", "echo", "$this->", "function"},
+	},
+	"Python": {
+		Interpreters: []string{"python"},
+		Modes:        []string{"python"},
+		Keywords:     []string{"def", "import", "elif", "self", "__init__"},
+	},
+	"Ruby": {
+		Interpreters: []string{"ruby"},
+		Modes:        []string{"ruby"},
+		Keywords:     []string{"def", "end", "require", "puts", "attr_accessor"},
+	},
+	"Rust": {
+		Interpreters: []string{},
+		Modes:        []string{"rust"},
+		Keywords:     []string{"fn main", "let mut", "impl", "pub fn", "use std::", "println!"},
+	},
+	"Scala": {
+		Interpreters: []string{"scala"},
+		Modes:        []string{"scala"},
+		Keywords:     []string{"object", "def", "val", "case class", "extends App"},
+	},
+	"SQL": {
+		Interpreters: []string{},
+		Modes:        []string{"sql"},
+		Keywords:     []string{"SELECT", "FROM", "WHERE", "INSERT INTO", "CREATE TABLE"},
+	},
+	"Swift": {
+		Interpreters: []string{"swift"},
+		Modes:        []string{"swift"},
+		Keywords:     []string{"func", "let", "var", "import Foundation", "guard let"},
+	},
+	"TypeScript": {
+		Interpreters: []string{"ts-node", "deno"},
+		Modes:        []string{"typescript"},
+		Keywords:     []string{"interface", "const", ": string", ": number", "export"},
+	},
+	"XML": {
+		Interpreters: []string{},
+		Modes:        []string{"xml"},
+		Keywords:     []string{"<?xml"},
+	},
+}
+
+// addHeuristics gives every language without detection heuristics the default ones for its name, if there are any
+func addHeuristics(ctx context.Context, client mgo.Client) error {
+	languages, errs := client.Find(ctx, models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, language := range languages.Languages {
+		heuristics, ok := defaultHeuristics[language.Name]
+		if language.Heuristics != nil || !ok {
+			continue
+		}
+
+		_, err := client.UpdateOne(ctx, language.Id.Hex(), models.Language{Heuristics: &heuristics}, language.Revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
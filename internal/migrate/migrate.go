@@ -0,0 +1,263 @@
+package migrate
+
+import (
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrUnsupported indicates that the storage driver has nowhere to record applied migrations
+	ErrUnsupported = errors.New("storage driver does not support migrations")
+	// ErrIrreversible indicates that an applied migration has no down step, so it can't be reverted
+	ErrIrreversible = errors.New("migration cannot be reverted")
+	// ErrInvalidMigrations indicates that the migrations given to New are not in strictly increasing version order
+	ErrInvalidMigrations = errors.New("migrations must have unique, positive versions in increasing order")
+)
+
+// Store is implemented by storage drivers that can record which migrations have been applied.
+// Each driver keeps these records apart from the languages themselves.
+//
+// AcquireMigrationLock takes the lock that stops two Runners applying the same migration at once, reporting whether
+// it was taken. It succeeds if nobody holds the lock, if its holder let it expire, or if owner already holds it,
+// in which case it is extended. The lock lasts until the given time unless it is acquired again or released first.
+// ReleaseMigrationLock gives the lock up, but only if owner holds it.
+type Store interface {
+	AppliedMigrations(ctx context.Context) (migrations []models.AppliedMigration, err error)
+	RecordMigration(ctx context.Context, migration models.AppliedMigration) (err error)
+	RemoveMigration(ctx context.Context, version int) (err error)
+	AcquireMigrationLock(ctx context.Context, owner string, until time.Time) (acquired bool, err error)
+	ReleaseMigrationLock(ctx context.Context, owner string) (err error)
+}
+
+var (
+	// lockLease is how long the migration lock lasts before it has to be extended, so that a Runner which dies
+	// while holding it only holds up the others until it expires
+	lockLease = time.Minute
+	// lockRetry is how long a Runner waits before trying again for a lock someone else holds
+	lockRetry = time.Second
+)
+
+// Step changes the stored languages through the driver's Client, so that it works the same for every driver
+type Step func(ctx context.Context, client mgo.Client) error
+
+// Migration is one versioned change to the stored languages. Down may be nil if the change can't be undone.
+type Migration struct {
+	Version int
+	Name    string
+	Up      Step
+	Down    Step
+}
+
+// Status reports whether a migration has been applied, and when
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Runner applies and reverts migrations against a Client
+type Runner struct {
+	client     mgo.Client
+	store      Store
+	migrations []Migration
+}
+
+// New returns a Runner for the given migrations, which must be sorted by version
+func New(client mgo.Client, migrations []Migration) (*Runner, error) {
+	store, ok := client.(Store)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	for i, m := range migrations {
+		if m.Version <= 0 || (i > 0 && m.Version <= migrations[i-1].Version) || m.Up == nil {
+			return nil, fmt.Errorf("%w: version %d (%s)", ErrInvalidMigrations, m.Version, m.Name)
+		}
+	}
+
+	return &Runner{client: client, store: store, migrations: migrations}, nil
+}
+
+// Up applies every migration that hasn't been applied yet, oldest first, stopping at the first failure.
+// It holds the migration lock throughout, so that instances starting together apply each migration once.
+func (r *Runner) Up(ctx context.Context) (applied []Migration, err error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	done, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range r.migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+
+		log.Info().Msgf("Applying migration %d (%s)", m.Version, m.Name)
+
+		err = m.Up(ctx, r.client)
+		if err != nil {
+			return applied, fmt.Errorf("applying migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		err = r.store.RecordMigration(ctx, models.AppliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()})
+		if err != nil {
+			return applied, fmt.Errorf("recording migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+// Down reverts up to steps of the most recently applied migrations, newest first, holding the migration lock throughout
+func (r *Runner) Down(ctx context.Context, steps int) (reverted []Migration, err error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	done, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range slices.Backward(r.migrations) {
+		if len(reverted) >= steps {
+			break
+		}
+
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+
+		if m.Down == nil {
+			return reverted, fmt.Errorf("%w: %d (%s)", ErrIrreversible, m.Version, m.Name)
+		}
+
+		log.Info().Msgf("Reverting migration %d (%s)", m.Version, m.Name)
+
+		err = m.Down(ctx, r.client)
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		err = r.store.RemoveMigration(ctx, m.Version)
+		if err != nil {
+			return reverted, fmt.Errorf("removing record of migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		reverted = append(reverted, m)
+	}
+
+	return reverted, nil
+}
+
+// Status lists every known migration in version order along with when it was applied, if it has been
+func (r *Runner) Status(ctx context.Context) (statuses []Status, err error) {
+	done, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range r.migrations {
+		status := Status{Version: m.Version, Name: m.Name}
+		if record, ok := done[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (r *Runner) applied(ctx context.Context) (map[int]models.AppliedMigration, error) {
+	records, err := r.store.AppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]models.AppliedMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+
+	return done, nil
+}
+
+// lock waits until it acquires the migration lock, then keeps extending it until the returned func releases it
+func (r *Runner) lock(ctx context.Context) (unlock func(), err error) {
+	owner := primitive.NewObjectID().Hex()
+
+	for {
+		acquired, err := r.store.AcquireMigrationLock(ctx, owner, time.Now().Add(lockLease))
+		if err != nil {
+			return nil, fmt.Errorf("acquiring migration lock: %w", err)
+		}
+
+		if acquired {
+			break
+		}
+
+		log.Info().Msg("Waiting for another instance to finish migrating")
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("acquiring migration lock: %w", ctx.Err())
+		case <-time.After(lockRetry):
+		}
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(lockLease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				acquired, err := r.store.AcquireMigrationLock(ctx, owner, time.Now().Add(lockLease))
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to extend migration lock")
+				} else if !acquired {
+					log.Error().Msg("Migration lock expired before it could be extended")
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+
+		err := r.store.ReleaseMigrationLock(context.WithoutCancel(ctx), owner)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to release migration lock")
+		}
+	}, nil
+}
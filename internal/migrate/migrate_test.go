@@ -0,0 +1,350 @@
+package migrate
+
+import (
+	"languages-api/internal/mem"
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// plainClient hides every method of the wrapped client that isn't part of mgo.Client
+type plainClient struct {
+	mgo.Client
+}
+
+// duplicatesClient holds languages that share names, as a mongo collection written before the unique name index
+// existed can, recording the names they are renamed to
+type duplicatesClient struct {
+	mgo.Client
+	languages []models.Language
+	renamed   map[string]string
+}
+
+func (c duplicatesClient) Find(_ context.Context, _ interface{}, _ models.FindOptions) (models.Languages, []error) {
+	return models.Languages{Languages: c.languages, Total: int64(len(c.languages))}, nil
+}
+
+func (c duplicatesClient) UpdateOne(_ context.Context, id string, update interface{}, _ int64) (int64, error) {
+	c.renamed[id] = update.(models.Language).Name
+	return 2, nil
+}
+
+func counting(counter *int) Step {
+	return func(_ context.Context, _ mgo.Client) error {
+		*counter++
+		return nil
+	}
+}
+
+func Test_New_ShouldReturnErrUnsupportedIfClientCannotRecordMigrations(t *testing.T) {
+	_, err := New(plainClient{Client: mem.NewMemoryClient()}, Migrations)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("New should return ErrUnsupported, but got %v", err)
+	}
+}
+
+func Test_New_ShouldReturnErrInvalidMigrationsIfOutOfOrder(t *testing.T) {
+	var ran int
+
+	_, err := New(mem.NewMemoryClient(), []Migration{
+		{Version: 2, Name: "second", Up: counting(&ran)},
+		{Version: 1, Name: "first", Up: counting(&ran)},
+	})
+	if !errors.Is(err, ErrInvalidMigrations) {
+		t.Errorf("New should return ErrInvalidMigrations, but got %v", err)
+	}
+}
+
+func Test_Up_ShouldOnlyApplyPendingMigrations(t *testing.T) {
+	client := mem.NewMemoryClient()
+	var first, second int
+
+	runner, err := New(client, []Migration{{Version: 1, Name: "first", Up: counting(&first)}})
+	if err != nil {
+		t.Fatal("Error creating runner:", err)
+	}
+
+	_, err = runner.Up(context.Background())
+	if err != nil {
+		t.Error("Error applying migrations:", err)
+	}
+
+	runner, err = New(client, []Migration{{Version: 1, Name: "first", Up: counting(&first)}, {Version: 2, Name: "second", Up: counting(&second)}})
+	if err != nil {
+		t.Fatal("Error creating runner:", err)
+	}
+
+	applied, err := runner.Up(context.Background())
+	if err != nil {
+		t.Error("Error applying migrations:", err)
+	}
+
+	if first != 1 || second != 1 || len(applied) != 1 || applied[0].Version != 2 {
+		t.Errorf("Up should apply each migration once, but first ran %d times, second ran %d times and %v were applied", first, second, applied)
+	}
+}
+
+func Test_Up_ShouldApplyEachMigrationOnceWhenRunnersStartTogether(t *testing.T) {
+	defer func(retry time.Duration) { lockRetry = retry }(lockRetry)
+	lockRetry = 10 * time.Millisecond
+
+	client := mem.NewMemoryClient()
+	var mu sync.Mutex
+	var ran int
+
+	slow := func(_ context.Context, _ mgo.Client) error {
+		time.Sleep(50 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		ran++
+
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			runner, err := New(client, []Migration{{Version: 1, Name: "slow", Up: slow}})
+			if err != nil {
+				t.Error("Error creating runner:", err)
+				return
+			}
+
+			_, err = runner.Up(context.Background())
+			if err != nil {
+				t.Error("Error applying migrations:", err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if ran != 1 {
+		t.Errorf("The migration should be applied once, but ran %d times", ran)
+	}
+}
+
+func Test_Up_ShouldReturnContextErrorWhileLockIsHeldElsewhere(t *testing.T) {
+	client := mem.NewMemoryClient()
+	var ran int
+
+	_, err := client.AcquireMigrationLock(context.Background(), "elsewhere", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal("Error acquiring migration lock:", err)
+	}
+
+	runner, err := New(client, []Migration{{Version: 1, Name: "first", Up: counting(&ran)}})
+	if err != nil {
+		t.Fatal("Error creating runner:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = runner.Up(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || ran != 0 {
+		t.Errorf("Up should wait for the lock until the context ends, but got %v with %d migrations run", err, ran)
+	}
+}
+
+func Test_Up_ShouldStopAtFailedMigration(t *testing.T) {
+	client := mem.NewMemoryClient()
+	var ran int
+
+	runner, err := New(client, []Migration{
+		{Version: 1, Name: "broken", Up: func(_ context.Context, _ mgo.Client) error { return errors.New("broken") }},
+		{Version: 2, Name: "second", Up: counting(&ran)},
+	})
+	if err != nil {
+		t.Fatal("Error creating runner:", err)
+	}
+
+	_, err = runner.Up(context.Background())
+	if err == nil || ran != 0 {
+		t.Errorf("Up should stop at the broken migration, but got %v with %d later migrations run", err, ran)
+	}
+
+	records, _ := client.AppliedMigrations(context.Background())
+	if len(records) != 0 {
+		t.Errorf("A failed migration should not be recorded, but got %v", records)
+	}
+}
+
+func Test_Down_ShouldRevertNewestMigrationsFirst(t *testing.T) {
+	client := mem.NewMemoryClient()
+	var up, firstDown, secondDown int
+
+	runner, err := New(client, []Migration{
+		{Version: 1, Name: "first", Up: counting(&up), Down: counting(&firstDown)},
+		{Version: 2, Name: "second", Up: counting(&up), Down: counting(&secondDown)},
+	})
+	if err != nil {
+		t.Fatal("Error creating runner:", err)
+	}
+
+	_, err = runner.Up(context.Background())
+	if err != nil {
+		t.Error("Error applying migrations:", err)
+	}
+
+	reverted, err := runner.Down(context.Background(), 1)
+	if err != nil {
+		t.Error("Error reverting migrations:", err)
+	}
+
+	if firstDown != 0 || secondDown != 1 || len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Errorf("Down should only revert the second migration, but got %v", reverted)
+	}
+
+	statuses, _ := runner.Status(context.Background())
+	if len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Only the first migration should still be applied, but got %v", statuses)
+	}
+}
+
+func Test_Down_ShouldReturnErrIrreversibleWithoutDownStep(t *testing.T) {
+	var ran int
+
+	runner, err := New(mem.NewMemoryClient(), []Migration{{Version: 1, Name: "first", Up: counting(&ran)}})
+	if err != nil {
+		t.Fatal("Error creating runner:", err)
+	}
+
+	_, err = runner.Up(context.Background())
+	if err != nil {
+		t.Error("Error applying migrations:", err)
+	}
+
+	_, err = runner.Down(context.Background(), 1)
+	if !errors.Is(err, ErrIrreversible) {
+		t.Errorf("Down should return ErrIrreversible, but got %v", err)
+	}
+}
+
+func Test_backfillYear_ShouldSetMissingYearsFromFirstAppeared(t *testing.T) {
+	client := mem.NewMemoryClient()
+
+	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
+	if err != nil {
+		t.Error("Error parsing timestamp:", err)
+	}
+
+	err = client.Load([]models.Language{
+		{Name: "Golang", FirstAppeared: &firstAppeared},
+		{Name: "C", FirstAppeared: &firstAppeared, Year: 1972},
+		{Name: "Unknown"},
+	})
+	if err != nil {
+		t.Fatal("Error loading languages:", err)
+	}
+
+	err = backfillYear(context.Background(), client)
+	if err != nil {
+		t.Error("Error backfilling years:", err)
+	}
+
+	for _, language := range client.Snapshot() {
+		expected := map[string]int32{"Golang": 2009, "C": 1972, "Unknown": 0}[language.Name]
+		if language.Year != expected {
+			t.Errorf("%s should have year %d, but got %d", language.Name, expected, language.Year)
+		}
+	}
+}
+
+func Test_addHeuristics_ShouldOnlyFillInMissingHeuristicsOfKnownLanguages(t *testing.T) {
+	client := mem.NewMemoryClient()
+
+	edited := &models.Heuristics{Keywords: []string{"package"}}
+	err := client.Load([]models.Language{
+		{Name: "Golang"},
+		{Name: "C", Heuristics: edited},
+		{Name: "Unknown"},
+	})
+	if err != nil {
+		t.Fatal("Error loading languages:", err)
+	}
+
+	err = addHeuristics(context.Background(), client)
+	if err != nil {
+		t.Error("Error adding heuristics:", err)
+	}
+
+	golang := defaultHeuristics["Golang"]
+	expected := map[string]*models.Heuristics{"Golang": &golang, "C": edited, "Unknown": nil}
+
+	for _, language := range client.Snapshot() {
+		if !reflect.DeepEqual(language.Heuristics, expected[language.Name]) {
+			t.Errorf("%s should have heuristics %v, but got %v", language.Name, expected[language.Name], language.Heuristics)
+		}
+	}
+}
+
+func Test_deduplicateNames_ShouldRenameEveryLanguageButTheOldestWithAName(t *testing.T) {
+	var languages []models.Language
+	for _, name := range []string{"Go", "C", "Go", "GÓ", "Rust"} {
+		languages = append(languages, models.Language{Id: primitive.NewObjectID(), Name: name, Revision: 1})
+	}
+	client := duplicatesClient{languages: languages, renamed: make(map[string]string)}
+
+	err := deduplicateNames(context.Background(), client)
+	if err != nil {
+		t.Error("Error deduplicating names:", err)
+	}
+
+	expected := map[string]string{
+		languages[2].Id.Hex(): "Go (" + languages[2].Id.Hex() + ")",
+		languages[3].Id.Hex(): "GÓ (" + languages[3].Id.Hex() + ")",
+	}
+	if !reflect.DeepEqual(client.renamed, expected) {
+		t.Errorf("deduplicateNames should rename %v, but renamed %v", expected, client.renamed)
+	}
+}
+
+func Test_removeNullHeuristics_ShouldLeaveOtherDriversAlone(t *testing.T) {
+	client := mem.NewMemoryClient()
+
+	err := client.Load([]models.Language{{Name: "Golang", Heuristics: &models.Heuristics{Modes: []string{"go"}}}})
+	if err != nil {
+		t.Fatal("Error loading languages:", err)
+	}
+
+	err = removeNullHeuristics(context.Background(), client)
+	if err != nil {
+		t.Error("Error removing null heuristics:", err)
+	}
+
+	if languages := client.Snapshot(); languages[0].Heuristics == nil {
+		t.Error("removeNullHeuristics should keep the heuristics of other drivers")
+	}
+}
+
+func Test_defaultHeuristics_ShouldMatchMockData(t *testing.T) {
+	data, err := os.ReadFile("../../mockData.json")
+	if err != nil {
+		t.Fatal("Error reading mock data:", err)
+	}
+
+	var mock models.Languages
+	err = json.Unmarshal(data, &mock)
+	if err != nil {
+		t.Fatal("Error unmarshalling mock data:", err)
+	}
+
+	for _, language := range mock.Languages {
+		heuristics, ok := defaultHeuristics[language.Name]
+		if !ok || !reflect.DeepEqual(language.Heuristics, &heuristics) {
+			t.Errorf("%s should have the default heuristics %v in mockData.json, but got %v", language.Name, heuristics, language.Heuristics)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package migrate
+
+import (
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Migrations is every migration the application knows about, in the order they are applied.
+// New migrations must be appended with a higher version, and released migrations must never be changed.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "backfill_year_from_first_appeared",
+		Up:      backfillYear,
+		// Languages that were given a year by Up can't be told apart from those that already had one
+		Down: nil,
+	},
+	{
+		Version: 2,
+		Name:    "add_revision",
+		Up:      addRevision,
+		// Conditional writes need every language to have a revision, so there is nothing to revert to
+		Down: nil,
+	},
+	{
+		Version: 3,
+		Name:    "add_search_keys",
+		Up:      addSearchKeys,
+		Down:    removeSearchKeys,
+	},
+	{
+		Version: 4,
+		Name:    "add_detection_heuristics",
+		Up:      addHeuristics,
+		// Heuristics may have been edited since they were added, so they are left in place
+		Down: nil,
+	},
+	{
+		Version: 5,
+		Name:    "remove_null_heuristics",
+		Up:      removeNullHeuristics,
+		// Null and missing heuristics both read as none, so there is nothing to revert
+		Down: nil,
+	},
+	{
+		Version: 6,
+		Name:    "deduplicate_names",
+		Up:      deduplicateNames,
+		// Giving the renamed languages back the names they shared would stop the unique name index being built
+		Down: nil,
+	},
+	{
+		Version: 7,
+		Name:    "add_extension_and_wiki_keys",
+		Up:      addExtensionAndWikiKeys,
+		Down:    removeExtensionAndWikiKeys,
+	},
+}
+
+// backfillYear sets the year of every language that doesn't have one from its firstAppeared date
+func backfillYear(ctx context.Context, client mgo.Client) error {
+	languages, errs := client.Find(ctx, models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, language := range languages.Languages {
+		if language.Year != 0 || language.FirstAppeared == nil {
+			continue
+		}
+
+		_, err := client.UpdateOne(ctx, language.Id.Hex(), models.Language{Year: int32(language.FirstAppeared.UTC().Year())}, language.Revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deduplicateNames renames every language that shares its folded name with an older one, logging each rename, so
+// that the unique name index can be built. Such languages can only have been written before the index was on the
+// folded name, or on mongo before there was one at all. A renamed language is called "<name> (<id>)", which no other
+// language can already be called as ids are unique.
+func deduplicateNames(ctx context.Context, client mgo.Client) error {
+	languages, errs := client.Find(ctx, models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Find returns languages in id order, so the oldest language with a name keeps it
+	named := make(map[string]primitive.ObjectID)
+	for _, language := range languages.Languages {
+		key := query.Fold(language.Name)
+		kept, ok := named[key]
+		if !ok {
+			named[key] = language.Id
+			continue
+		}
+
+		name := language.Name + " (" + language.Id.Hex() + ")"
+		_, err := client.UpdateOne(ctx, language.Id.Hex(), models.Language{Name: name}, language.Revision)
+		if err != nil {
+			return fmt.Errorf("renaming language %s: %w", language.Id.Hex(), err)
+		}
+
+		log.Warn().Msgf("Renamed language %s from %q to %q, as language %s has the same name", language.Id.Hex(), language.Name, name, kept.Hex())
+	}
+
+	return nil
+}
+
+// addRevision starts every mongo document that predates revisions at revision 1. The other drivers
+// already give such languages revision 1 when they load them, so there is nothing for them to do.
+func addRevision(ctx context.Context, client mgo.Client) error {
+	mc, ok := mongoClient(client)
+	if !ok {
+		return nil
+	}
+
+	_, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).UpdateMany(ctx,
+		bson.M{"revision": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revision": 1}})
+
+	return mgo.TimeoutError(err)
+}
+
+// addSearchKeys stores the folded name and creators that filters are matched against on every mongo document
+// written before they were. The other drivers fold values as they match them.
+func addSearchKeys(ctx context.Context, client mgo.Client) error {
+	mc, ok := mongoClient(client)
+	if !ok {
+		return nil
+	}
+
+	collection := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName)
+
+	cursor, err := collection.Find(ctx, bson.M{"nameKey": bson.M{"$exists": false}})
+	if err != nil {
+		return mgo.TimeoutError(err)
+	}
+
+	languages, err := mgo.MongoCursor{Cursor: cursor}.DecodeAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, language := range languages.Languages {
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": language.Id}, bson.M{"$set": mgo.SearchKeys(language)})
+		if err != nil {
+			return mgo.TimeoutError(err)
+		}
+	}
+
+	return nil
+}
+
+// removeSearchKeys drops the unique name index first, as it is built on the folded name
+func removeSearchKeys(ctx context.Context, client mgo.Client) error {
+	mc, ok := mongoClient(client)
+	if !ok {
+		return nil
+	}
+
+	err := mc.DropIndex(ctx, mgo.NameIndex)
+	if err != nil {
+		return err
+	}
+
+	_, err = mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).UpdateMany(ctx,
+		bson.M{},
+		bson.M{"$unset": bson.M{"nameKey": "", "creatorKeys": ""}})
+
+	return mgo.TimeoutError(err)
+}
+
+// addExtensionAndWikiKeys stores the folded extensions and wiki that searches are matched against on every mongo
+// document written before they were. The other drivers fold values as they match them.
+func addExtensionAndWikiKeys(ctx context.Context, client mgo.Client) error {
+	mc, ok := mongoClient(client)
+	if !ok {
+		return nil
+	}
+
+	collection := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName)
+
+	cursor, err := collection.Find(ctx, bson.M{"wikiKey": bson.M{"$exists": false}})
+	if err != nil {
+		return mgo.TimeoutError(err)
+	}
+
+	languages, err := mgo.MongoCursor{Cursor: cursor}.DecodeAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, language := range languages.Languages {
+		keys := mgo.SearchKeys(models.Language{Extensions: language.Extensions, Wiki: language.Wiki})
+		if len(keys) == 0 {
+			continue
+		}
+
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": language.Id}, bson.M{"$set": keys})
+		if err != nil {
+			return mgo.TimeoutError(err)
+		}
+	}
+
+	return nil
+}
+
+// removeExtensionAndWikiKeys unsets the keys addExtensionAndWikiKeys stores
+func removeExtensionAndWikiKeys(ctx context.Context, client mgo.Client) error {
+	mc, ok := mongoClient(client)
+	if !ok {
+		return nil
+	}
+
+	_, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).UpdateMany(ctx,
+		bson.M{},
+		bson.M{"$unset": bson.M{"extensionKeys": "", "wikiKey": ""}})
+
+	return mgo.TimeoutError(err)
+}
+
+// removeNullHeuristics unsets the heuristics that replacing a language used to store as null, as a merge patch can't
+// set heuristics one at a time inside a null. The other drivers never store null heuristics.
+func removeNullHeuristics(ctx context.Context, client mgo.Client) error {
+	mc, ok := mongoClient(client)
+	if !ok {
+		return nil
+	}
+
+	_, err := mc.Client.Database(mc.DatabaseName).Collection(mc.CollectionName).UpdateMany(ctx,
+		bson.M{"heuristics": bson.M{"$type": "null"}},
+		bson.M{"$unset": bson.M{"heuristics": ""}})
+
+	return mgo.TimeoutError(err)
+}
+
+// mongoClient returns the MongoClient behind client, if it is one
+func mongoClient(client mgo.Client) (mgo.MongoClient, bool) {
+	switch c := client.(type) {
+	case *mgo.MongoClient:
+		return *c, true
+	case mgo.MongoClient:
+		return c, true
+	default:
+		return mgo.MongoClient{}, false
+	}
+}
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,10 +16,305 @@ var (
 	ErrInvalidId = errors.New("invalid id provided")
 	// ErrCursorNil indicates that the given cursor is nil, so no functions can be called off it
 	ErrCursorNil = errors.New("cursor is nil")
+	// ErrDuplicateId indicates that a language with the inserted id is already stored
+	ErrDuplicateId = errors.New("a language with that id already exists")
+	// ErrIdMismatch indicates that a replacement document carries an id other than the one being replaced
+	ErrIdMismatch = errors.New("document id does not match the given id")
+	// ErrTimeout indicates that the database did not respond within the configured timeout
+	ErrTimeout = errors.New("database operation timed out")
+	// ErrConflict indicates that a write would give a language the same name as another stored language
+	ErrConflict = errors.New("a language with that name already exists")
+	// ErrPreconditionFailed indicates that a conditional write found a different revision of the language than expected
+	ErrPreconditionFailed = errors.New("language revision does not match")
+	// ErrStreamBefore indicates that languages were to be streamed before a cursor, which would need them all held
+	// to put them back in order
+	ErrStreamBefore = errors.New("languages cannot be streamed before a cursor")
+	// ErrNotApplied indicates that an operation of an atomic batch wasn't applied because another one failed
+	ErrNotApplied = errors.New("not applied as another operation in the batch failed")
+	// ErrTransactionsUnsupported indicates that the database can't apply an atomic batch, as a standalone Mongo
+	// server can't run the transaction it needs
+	ErrTransactionsUnsupported = errors.New("the database does not support transactions")
+	// ErrPatchFailed indicates that an operation of a JSON patch couldn't be applied to the stored language
+	ErrPatchFailed = errors.New("patch operation failed")
+	// ErrDuplicateNames indicates that the unique name index couldn't be created as stored languages share a name
+	ErrDuplicateNames = errors.New("stored languages share a name")
 )
 
+// AnyRevision is the revision a conditional write is given to require only that the language exists, as an If-Match
+// of "*" does. Unlike 0 it never upserts, and the write fails with ErrPreconditionFailed if there is no language.
+const AnyRevision int64 = -1
+
+// ConflictError is returned when a write collides with the name of an existing language, identifying that language
+type ConflictError struct {
+	Id string
+}
+
+func (e ConflictError) Error() string {
+	return ErrConflict.Error() + ": " + e.Id
+}
+
+// Unwrap lets errors.Is(err, ErrConflict) match a ConflictError
+func (e ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// PatchError is returned when an operation of a JSON patch can't be applied to the stored language, identifying the
+// operation by its index in the patch and saying why
+type PatchError struct {
+	Operation int
+	Reason    string
+}
+
+func (e PatchError) Error() string {
+	return ErrPatchFailed.Error() + " " + strconv.Itoa(e.Operation) + ": " + e.Reason
+}
+
+// Unwrap lets errors.Is(err, ErrPatchFailed) match a PatchError
+func (e PatchError) Unwrap() error {
+	return ErrPatchFailed
+}
+
 type Languages struct {
 	Languages []Language `json:"languages" bson:"languages"`
+	// Total is how many languages match the filter, regardless of which page of them Languages holds
+	Total int64 `json:"total" bson:"-"`
+	// Facets counts every matching language by the facets FindOptions asked for, or is nil if it asked for none
+	Facets *Facets `json:"facets,omitempty" bson:"-"`
+}
+
+// Facets count the languages matching a filter by the values of their fields, ordered as in Stats. Only the facets
+// that were asked for are set, and those are empty rather than nil when nothing matches.
+type Facets struct {
+	Year       []YearCount `json:"year,omitzero" bson:"year"`
+	Decade     []YearCount `json:"decade,omitzero" bson:"decade"`
+	Creators   []NameCount `json:"creators,omitzero" bson:"creators"`
+	Extensions []NameCount `json:"extensions,omitzero" bson:"extensions"`
+}
+
+// SearchResults are the languages that matched a search, best match first
+type SearchResults struct {
+	Results []SearchResult `json:"results"`
+	// Total is how many languages matched, regardless of which page of them Results holds
+	Total int64 `json:"total"`
+}
+
+// SearchResult is a language that matched a search, along with how well and where it matched
+type SearchResult struct {
+	Language Language `json:"language"`
+	Score    float64  `json:"score"`
+	// Highlights holds the values of each field that matched, HTML escaped and with the matching text wrapped in <em> tags
+	Highlights map[string][]string `json:"highlights"`
+}
+
+// Stats summarises the stored languages
+type Stats struct {
+	Totals StatsTotals `json:"totals" bson:"totals"`
+	// ByYear counts the languages that first appeared in each year, earliest first
+	ByYear []YearCount `json:"byYear" bson:"byYear"`
+	// ByDecade counts the languages that first appeared in each decade, named by its first year, earliest first
+	ByDecade []YearCount `json:"byDecade" bson:"byDecade"`
+	// TopCreators are the creators of the most languages, most first and then in byte order of their names
+	TopCreators []NameCount `json:"topCreators" bson:"topCreators"`
+	// Extensions counts the languages that use each extension, most used first and then in byte order.
+	// Extensions are case-sensitive, so .C and .c are counted apart.
+	Extensions []NameCount `json:"extensions" bson:"extensions"`
+	// MissingFirstAppeared are the languages without a firstAppeared date, in id order
+	MissingFirstAppeared []LanguageRef `json:"missingFirstAppeared" bson:"missingFirstAppeared"`
+}
+
+// StatsTotals are counts across every stored language
+type StatsTotals struct {
+	Languages int64 `json:"languages" bson:"languages"`
+	// Creators and Extensions count distinct values
+	Creators             int64 `json:"creators" bson:"creators"`
+	Extensions           int64 `json:"extensions" bson:"extensions"`
+	MissingFirstAppeared int64 `json:"missingFirstAppeared" bson:"missingFirstAppeared"`
+	// EarliestYear and LatestYear are 0 if no languages are stored
+	EarliestYear int32 `json:"earliestYear" bson:"earliestYear"`
+	LatestYear   int32 `json:"latestYear" bson:"latestYear"`
+}
+
+// YearCount is how many languages share a year or decade
+type YearCount struct {
+	Year  int32 `json:"year" bson:"year"`
+	Count int64 `json:"count" bson:"count"`
+}
+
+// NameCount is how many languages share a creator or extension
+type NameCount struct {
+	Name  string `json:"name" bson:"name"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// LanguageRef identifies a language without the rest of its fields
+type LanguageRef struct {
+	Id   primitive.ObjectID `json:"_id" bson:"_id"`
+	Name string             `json:"name" bson:"name"`
+}
+
+// Extension is a file extension and the languages that use it
+type Extension struct {
+	Extension string        `json:"extension"`
+	Languages []LanguageRef `json:"languages"`
+}
+
+// Classification is the languages a file may be written in, judged by its extension
+type Classification struct {
+	Filename string `json:"filename"`
+	// Extension is the extension the candidates were picked by, or empty if no language uses any of the file's
+	Extension  string        `json:"extension"`
+	Candidates []LanguageRef `json:"candidates"`
+	// Ambiguous is set if more than one language uses the extension, as C and C++ both use .h
+	Ambiguous bool `json:"ambiguous"`
+	// CaseInsensitive is set if no language uses the extension as it was written, so candidates were matched
+	// ignoring case
+	CaseInsensitive bool `json:"caseInsensitive"`
+}
+
+// FindOptions selects which page of the matching languages Find returns. Languages are ordered by Sort and
+// then by id, so pages stay stable as languages are added and removed.
+type FindOptions struct {
+	// Limit is the most languages to return, or 0 for no limit
+	Limit int64
+	// Offset is how many of the matching languages to skip
+	Offset int64
+	// Sort is the fields to order languages by before their id, most significant first
+	Sort []SortField
+	// After only returns languages that come after this one. Only its id and the fields in Sort are used.
+	After *Language
+	// Before only returns languages that come before this one, taking the last Limit of them rather than the first
+	Before *Language
+	// Fields are the fields to return, named as they are in the JSON and bson documents, or nil for every field.
+	// The id, revision and fields in Sort are always returned.
+	Fields []string
+	// Facets are the facets to count across every matching language rather than just the page, named as
+	// query.Facets lists them, or nil for none
+	Facets []string
+}
+
+// Filter selects the languages Find returns. Conditions left as their zero value aren't applied.
+type Filter struct {
+	// Name and Creators are matched ignoring case and diacritics, in the mode given by NameMatch and CreatorsMatch
+	Name      string
+	NameMatch MatchMode
+	// Creators and Extensions match languages whose arrays hold the given values as CreatorsSet and ExtensionsSet ask
+	Creators      []string
+	CreatorsMatch MatchMode
+	CreatorsSet   SetMode
+	Extensions    []string
+	ExtensionsSet SetMode
+	FirstAppeared Range[time.Time]
+	Year          Range[int32]
+	Wiki          string
+	// Expr further narrows the languages to those that match a parsed filter expression, unless it is nil
+	Expr Expr
+	// Terms narrows the languages to those holding each of the folded search terms somewhere in their name,
+	// extensions, creators or wiki, so that a search only ranks the languages that may match it
+	Terms []string
+}
+
+// MatchMode is how a text filter is compared with a stored value
+type MatchMode int
+
+const (
+	// MatchExact matches values equal to the filter
+	MatchExact MatchMode = iota
+	// MatchPrefix matches values that start with the filter
+	MatchPrefix
+	// MatchContains matches values that contain the filter anywhere
+	MatchContains
+)
+
+// SetMode is how the values of an array filter are matched with the values of an array field
+type SetMode int
+
+const (
+	// SetAll matches arrays that hold every one of the filter's values
+	SetAll SetMode = iota
+	// SetAny matches arrays that hold at least one of the filter's values
+	SetAny
+	// SetNone matches arrays that hold none of the filter's values, including missing and empty arrays
+	SetNone
+	// SetExact matches arrays that hold every one of the filter's values and nothing else, in any order
+	SetExact
+)
+
+// Range bounds a value. Nil bounds aren't applied, and a language without the value isn't in any range that has a bound.
+type Range[T any] struct {
+	Eq  *T
+	Gt  *T
+	Gte *T
+	Lt  *T
+	Lte *T
+}
+
+// IsZero reports whether none of the bounds are set
+func (r Range[T]) IsZero() bool {
+	return r.Eq == nil && r.Gt == nil && r.Gte == nil && r.Lt == nil && r.Lte == nil
+}
+
+// Expr is a node of a filter expression. Fields are named as they are in the JSON and bson documents.
+type Expr interface {
+	expr()
+}
+
+// And matches languages that match every one of its expressions
+type And []Expr
+
+// Or matches languages that match at least one of its expressions
+type Or []Expr
+
+// Not matches languages that don't match Expr
+type Not struct {
+	Expr Expr
+}
+
+// Operator is how a Comparison compares a field with its value
+type Operator int
+
+const (
+	OpEq Operator = iota
+	OpNe
+	OpLt
+	OpLte
+	OpGt
+	OpGte
+)
+
+// Comparison compares a scalar field with Value, which is a string for name and wiki, an int32 for year and a
+// time.Time or nil for firstAppeared. Names are compared ignoring case and diacritics. Like Mongo, a language
+// without a firstAppeared is only equal to nil and is not equal to, but never less or greater than, any date.
+type Comparison struct {
+	Field    string
+	Operator Operator
+	Value    interface{}
+}
+
+// In matches languages whose scalar Field is equal to one of Values, compared the same way as in a Comparison
+type In struct {
+	Field  string
+	Values []interface{}
+}
+
+// Has matches languages whose array Field holds Values in the way Set asks for.
+// Creators are compared ignoring case and diacritics.
+type Has struct {
+	Field  string
+	Values []string
+	Set    SetMode
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+func (In) expr()         {}
+func (Has) expr()        {}
+
+// SortField is one of the fields languages are ordered by, named as it is in the JSON and bson documents
+type SortField struct {
+	Field      string
+	Descending bool
 }
 
 type Language struct {
@@ -28,4 +325,128 @@ type Language struct {
 	FirstAppeared *time.Time         `json:"firstAppeared" bson:"firstAppeared"`
 	Year          int32              `json:"year" bson:"year"`
 	Wiki          string             `json:"wiki" bson:"wiki"`
+	// Heuristics are left out of documents for languages without any
+	Heuristics *Heuristics `json:"heuristics,omitempty" bson:"heuristics,omitempty"`
+	// Revision starts at 1 and is incremented by every write. It is managed by the storage driver, so it is ignored in documents being written.
+	Revision int64 `json:"revision" bson:"revision"`
+}
+
+// Patch is a JSON merge patch (RFC 7396) of a language. Fields the patch leaves out are left as they are, and fields
+// it sets to null are removed. Heuristics are merged rather than replaced, so the patch can set only some of them.
+// The id and revision can't be patched, so they are ignored.
+type Patch struct {
+	Name          Field[string]          `json:"name"`
+	Creators      Field[[]string]        `json:"creators"`
+	Extensions    Field[[]string]        `json:"extensions"`
+	FirstAppeared Field[time.Time]       `json:"firstAppeared"`
+	Year          Field[int32]           `json:"year"`
+	Wiki          Field[string]          `json:"wiki"`
+	Heuristics    Field[HeuristicsPatch] `json:"heuristics"`
+}
+
+// HeuristicsPatch is the part of a Patch that is merged into the heuristics of a language
+type HeuristicsPatch struct {
+	Interpreters Field[[]string] `json:"interpreters"`
+	Modes        Field[[]string] `json:"modes"`
+	Keywords     Field[[]string] `json:"keywords"`
+}
+
+// Field is a member of a merge patch, which tells apart a member that was left out from one that is null
+type Field[T any] struct {
+	// Set is whether the patch has the member at all
+	Set bool
+	// Value is what the member is set to, or nil if it is null and so the field is to be removed
+	Value *T
+}
+
+// UnmarshalJSON is only called for members the patch has, including those that are null
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if string(data) == "null" {
+		f.Value = nil
+		return nil
+	}
+
+	f.Value = new(T)
+	return json.Unmarshal(data, f.Value)
+}
+
+// JSONPatch is a JSON patch (RFC 6902) of a language, whose operations are applied in order to the stored language
+// as a whole: either all of them are applied or none are
+type JSONPatch []PatchOperation
+
+// PatchOperation is one operation of a JSON patch. Value is kept as it was sent until the path tells what type of
+// member it is for. From is the path move and copy take the value from.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Heuristics are the clues language detection looks for in the content of a file written in a language
+type Heuristics struct {
+	// Interpreters are the programs a shebang line runs the language with, such as python or bash. Version
+	// suffixes are ignored, so python also matches #!/usr/bin/python3.12.
+	Interpreters []string `json:"interpreters" bson:"interpreters"`
+	// Modes are the names vim and emacs modelines give the language, such as cpp in "vim: ft=cpp"
+	Modes []string `json:"modes" bson:"modes"`
+	// Keywords are text that suggests the language wherever it appears in the content, such as "namespace"
+	Keywords []string `json:"keywords" bson:"keywords"`
+}
+
+// Detection is the languages some content may be written in, most likely first
+type Detection struct {
+	Guesses []Guess `json:"guesses"`
+}
+
+// Guess is a language some content may be written in, with how strongly the clues found point to it
+type Guess struct {
+	Language LanguageRef `json:"language"`
+	Score    float64     `json:"score"`
+	// Reasons describe each clue that was found, such as "shebang python3" or "keyword namespace"
+	Reasons []string `json:"reasons"`
+}
+
+// BatchKind is which write a BatchOperation makes
+type BatchKind int
+
+const (
+	// BatchCreate inserts the operation's language, generating an id if it doesn't have one
+	BatchCreate BatchKind = iota
+	// BatchReplace replaces the language with the operation's id, upserting it if revision is 0
+	BatchReplace
+	// BatchPatch sets the fields of the language with the operation's id that are set in the operation's language
+	BatchPatch
+	// BatchDelete deletes the language with the operation's id
+	BatchDelete
+)
+
+// BatchOperation is one of the writes of a batch, which is made as the write of the same kind on its own would be
+type BatchOperation struct {
+	Kind BatchKind
+	// Id is the language to write, and is ignored by BatchCreate
+	Id string
+	// Language is the language to create or replace with, or the fields to patch
+	Language Language
+	// Revision is the revision the language must be at to be written, AnyRevision for any as long as it exists,
+	// or 0 for any
+	Revision int64
+}
+
+// BatchResult is the outcome of one operation of a batch
+type BatchResult struct {
+	// Id is the language that was written, including the id generated for a created language
+	Id string
+	// Upserted is set if a BatchReplace created the language
+	Upserted bool
+	// Err is why the operation wasn't applied, or nil if it was
+	Err error
+}
+
+// AppliedMigration records that a migration has been applied to the stored languages
+type AppliedMigration struct {
+	Version   int       `json:"version" bson:"_id"`
+	Name      string    `json:"name" bson:"name"`
+	AppliedAt time.Time `json:"appliedAt" bson:"appliedAt"`
 }
@@ -0,0 +1,190 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"cmp"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scores of each kind of clue. A shebang or modeline names the language outright, an extension narrows it down
+// to a few, and a keyword only hints at it.
+const (
+	shebangScore   = 10.0
+	modelineScore  = 10.0
+	extensionScore = 5.0
+	keywordScore   = 1.0
+)
+
+// modelineLines is how many lines at each end of the content are searched for modelines, as vim does by default
+const modelineLines = 5
+
+var (
+	// vimModeline matches modelines such as "vim: set ft=cpp:" and "vi: syntax=python"
+	vimModeline = regexp.MustCompile(`\b(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax)=([\w+#.-]+)`)
+	// emacsModeline matches modelines such as "-*- mode: c++ -*-" and "-*- python -*-"
+	emacsModeline = regexp.MustCompile(`-\*-(.*?)-\*-`)
+	emacsMode     = regexp.MustCompile(`(?i)\bmode:\s*([\w+#.-]+)`)
+)
+
+// Detect ranks the languages content may be written in by the clues their heuristics describe: the interpreter
+// a shebang line runs, the mode a vim or emacs modeline sets, and keywords found in the content. The extension
+// of filename, if one is given, counts towards the languages Classify picks for it. Guesses are ordered by score,
+// with ties in name order, and languages without any clues are left out.
+func Detect(languages []models.Language, content string, filename string) []models.Guess {
+	guesses := map[int]*models.Guess{}
+	add := func(i int, score float64, reason string) {
+		if guesses[i] == nil {
+			guesses[i] = &models.Guess{Language: models.LanguageRef{Id: languages[i].Id, Name: languages[i].Name}, Reasons: []string{}}
+		}
+
+		guesses[i].Score += score
+		guesses[i].Reasons = append(guesses[i].Reasons, reason)
+	}
+
+	interpreter := shebang(content)
+	modes := modelines(content)
+
+	var extension models.Classification
+	if filename != "" {
+		extension = Classify(languages, filename)
+	}
+
+	for i, l := range languages {
+		if slices.ContainsFunc(extension.Candidates, func(c models.LanguageRef) bool { return c.Id == l.Id }) {
+			add(i, extensionScore, "extension "+extension.Extension)
+		}
+
+		if l.Heuristics == nil {
+			continue
+		}
+
+		if interpreter != "" && slices.ContainsFunc(l.Heuristics.Interpreters, func(name string) bool { return runs(interpreter, name) }) {
+			add(i, shebangScore, "shebang "+interpreter)
+		}
+
+		for _, mode := range modes {
+			if slices.ContainsFunc(l.Heuristics.Modes, func(name string) bool { return strings.EqualFold(name, mode) }) {
+				add(i, modelineScore, "modeline "+mode)
+				break
+			}
+		}
+
+		for _, keyword := range l.Heuristics.Keywords {
+			if containsWord(content, keyword) {
+				add(i, keywordScore, "keyword "+keyword)
+			}
+		}
+	}
+
+	ranked := make([]models.Guess, 0, len(guesses))
+	for _, guess := range guesses {
+		ranked = append(ranked, *guess)
+	}
+
+	slices.SortFunc(ranked, func(a, b models.Guess) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+
+		return CompareStrings(a.Language.Name, b.Language.Name)
+	})
+
+	return ranked
+}
+
+// shebang returns the name of the interpreter the first line of content runs, looking past env and its options,
+// or an empty string if it isn't a shebang line
+func shebang(content string) string {
+	line, _, _ := strings.Cut(content, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	args := strings.Fields(line[2:])
+	if len(args) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(args[0])
+	if interpreter != "env" {
+		return interpreter
+	}
+
+	for _, arg := range args[1:] {
+		if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+			return path.Base(arg)
+		}
+	}
+
+	return ""
+}
+
+// runs reports whether interpreter is name, or name followed by a version such as python3.12
+func runs(interpreter string, name string) bool {
+	return interpreter == name || strings.TrimRight(interpreter, "0123456789.") == name
+}
+
+// modelines returns the modes set by the vim and emacs modelines in the first and last lines of content
+func modelines(content string) (modes []string) {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 2*modelineLines {
+		lines = append(lines[:modelineLines], lines[len(lines)-modelineLines:]...)
+	}
+
+	for _, line := range lines {
+		if m := vimModeline.FindStringSubmatch(line); m != nil {
+			modes = append(modes, m[1])
+		}
+
+		if m := emacsModeline.FindStringSubmatch(line); m != nil {
+			if mode := emacsMode.FindStringSubmatch(m[1]); mode != nil {
+				modes = append(modes, mode[1])
+			} else if !strings.Contains(m[1], ":") && strings.TrimSpace(m[1]) != "" {
+				modes = append(modes, strings.TrimSpace(m[1]))
+			}
+		}
+	}
+
+	return modes
+}
+
+// containsWord reports whether keyword appears in content without being part of a longer word, so that "class"
+// isn't found in "subclass". Keywords that start or end with punctuation, such as "#include" or "::", are only
+// bounded on the sides that are part of a word.
+func containsWord(content string, keyword string) bool {
+	if keyword == "" {
+		return false
+	}
+
+	first, _ := utf8.DecodeRuneInString(keyword)
+	last, _ := utf8.DecodeLastRuneInString(keyword)
+
+	for offset := 0; offset < len(content); {
+		i := strings.Index(content[offset:], keyword)
+		if i < 0 {
+			return false
+		}
+
+		start, end := offset+i, offset+i+len(keyword)
+		before, _ := utf8.DecodeLastRuneInString(content[:start])
+		after, _ := utf8.DecodeRuneInString(content[end:])
+
+		if (start == 0 || !isWord(first) || !isWord(before)) && (end == len(content) || !isWord(last) || !isWord(after)) {
+			return true
+		}
+
+		offset = start + 1
+	}
+
+	return false
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,119 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var detectLanguages = []models.Language{
+	{Id: primitive.NewObjectID(), Name: "C", Extensions: []string{".c", ".h"}, Heuristics: &models.Heuristics{
+		Modes:    []string{"c"},
+		Keywords: []string{"#include <stdio.h>", "printf", "typedef struct"},
+	}},
+	{Id: primitive.NewObjectID(), Name: "C++", Extensions: []string{".cpp", ".h"}, Heuristics: &models.Heuristics{
+		Modes:    []string{"cpp", "c++"},
+		Keywords: []string{"#include <iostream>", "std::", "namespace", "class"},
+	}},
+	{Id: primitive.NewObjectID(), Name: "Python", Extensions: []string{".py"}, Heuristics: &models.Heuristics{
+		Interpreters: []string{"python"},
+		Modes:        []string{"python"},
+		Keywords:     []string{"def", "import", "self"},
+	}},
+	{Id: primitive.NewObjectID(), Name: "Bash", Extensions: []string{".sh"}, Heuristics: &models.Heuristics{
+		Interpreters: []string{"bash", "sh"},
+		Keywords:     []string{"fi", "$("},
+	}},
+	{Id: primitive.NewObjectID(), Name: "Text", Extensions: []string{".txt"}},
+}
+
+func Test_Detect_ShouldRankLanguagesByTheirClues(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		content  string
+		filename string
+		expected []string
+	}{
+		{"C++ header", "#include <iostream>\nnamespace util {\nclass Buffer;\n}\n", "util.h", []string{"C++", "C"}},
+		{"C header", "typedef struct buffer buffer;\nint printf(const char *format, ...);\n", "buffer.h", []string{"C", "C++"}},
+		{"shebang without extension", "#!/usr/bin/env python3\nprint('hi')\n", "build", []string{"Python"}},
+		{"shebang with env options", "#!/usr/bin/env -S bash -e\nls\n", "", []string{"Bash"}},
+		{"vim modeline", "print 'hi'\n# vim: set ft=python ts=4:\n", "", []string{"Python"}},
+		{"emacs modeline", "// -*- mode: C++; indent-tabs-mode: nil -*-\nint x;\n", "", []string{"C++"}},
+		{"short emacs modeline", "/* -*- c -*- */\n", "", []string{"C"}},
+		{"extension only", "", "notes.txt", []string{"Text"}},
+		{"keywords inside words", "subclass definitely imported\n", "", nil},
+	} {
+		var names []string
+		for _, guess := range Detect(detectLanguages, test.content, test.filename) {
+			names = append(names, guess.Language.Name)
+		}
+
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Detect should guess %v for the %s, but got %v", test.expected, test.name, names)
+		}
+	}
+}
+
+func Test_Detect_ShouldExplainEachClue(t *testing.T) {
+	guesses := Detect(detectLanguages, "#!/usr/bin/python3.12\nimport os\n", "tool.py")
+
+	expected := models.Guess{
+		Language: models.LanguageRef{Id: detectLanguages[2].Id, Name: "Python"},
+		Score:    extensionScore + shebangScore + keywordScore,
+		Reasons:  []string{"extension .py", "shebang python3.12", "keyword import"},
+	}
+	if len(guesses) != 1 || !reflect.DeepEqual(guesses[0], expected) {
+		t.Errorf("Detect should return %+v, but got %+v", expected, guesses)
+	}
+}
+
+func Test_shebang_ShouldFindInterpreter(t *testing.T) {
+	for content, expected := range map[string]string{
+		"#!/bin/sh\n":                   "sh",
+		"#! /usr/bin/python3 -u\n":      "python3",
+		"#!/usr/bin/env node":           "node",
+		"#!/usr/bin/env -S LANG=C perl": "perl",
+		"#!/usr/bin/env\n":              "",
+		"#!\n":                          "",
+		"echo hi\n":                     "",
+		"\n#!/bin/sh\n":                 "",
+	} {
+		if interpreter := shebang(content); interpreter != expected {
+			t.Errorf("shebang(%q) should be %q, but got %q", content, expected, interpreter)
+		}
+	}
+}
+
+func Test_modelines_ShouldOnlySearchTheFirstAndLastLines(t *testing.T) {
+	content := "# vim: ft=python\n" + "\n\n\n\n\n# vim: ft=ruby\n\n\n\n\n\n" + "# -*- mode: perl -*-\n"
+
+	expected := []string{"python", "perl"}
+	if modes := modelines(content); !reflect.DeepEqual(modes, expected) {
+		t.Errorf("modelines should find %v, but got %v", expected, modes)
+	}
+}
+
+func Test_containsWord_ShouldOnlyMatchWholeWords(t *testing.T) {
+	for _, test := range []struct {
+		content  string
+		keyword  string
+		expected bool
+	}{
+		{"class Foo", "class", true},
+		{"subclass Foo", "class", false},
+		{"subclass class", "class", true},
+		{"classes", "class", false},
+		{"std::vector", "std::", true},
+		{"x := 1", ":=", true},
+		{"#include <stdio.h>", "#include <stdio.h>", true},
+		{"anything", "", false},
+	} {
+		if found := containsWord(test.content, test.keyword); found != test.expected {
+			t.Errorf("containsWord(%q, %q) should be %v, but got %v", test.content, test.keyword, test.expected, found)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Eval reports whether l matches e, in the same way every driver matches the expression
+func Eval(e models.Expr, l models.Language) bool {
+	switch e := e.(type) {
+	case models.And:
+		return !slices.ContainsFunc(e, func(e models.Expr) bool { return !Eval(e, l) })
+	case models.Or:
+		return slices.ContainsFunc(e, func(e models.Expr) bool { return Eval(e, l) })
+	case models.Not:
+		return !Eval(e.Expr, l)
+	case models.Comparison:
+		return compareField(l, e.Field, e.Operator, e.Value)
+	case models.In:
+		return slices.ContainsFunc(e.Values, func(value interface{}) bool { return compareField(l, e.Field, models.OpEq, value) })
+	case models.Has:
+		values, match := l.Extensions, func(value string, pattern string) bool { return value == pattern }
+		if e.Field == FieldCreators {
+			values, match = l.Creators, func(value string, pattern string) bool { return Match(value, pattern, models.MatchExact) }
+		}
+
+		return MatchSet(values, e.Values, e.Set, match)
+	default:
+		panic("query: unknown expression")
+	}
+}
+
+// compareField compares a scalar field of l with value
+func compareField(l models.Language, field string, operator models.Operator, value interface{}) bool {
+	var c int
+
+	switch field {
+	case FieldName:
+		c = cmp.Compare(Fold(l.Name), Fold(value.(string)))
+	case FieldWiki:
+		c = cmp.Compare(l.Wiki, value.(string))
+	case FieldYear:
+		c = cmp.Compare(l.Year, value.(int32))
+	case FieldFirstAppeared:
+		if l.FirstAppeared == nil || value == nil {
+			// Missing dates are only equal to each other, and never less or greater than anything
+			equal := l.FirstAppeared == nil && value == nil
+			return operator == models.OpEq && equal || operator == models.OpNe && !equal
+		}
+
+		c = l.FirstAppeared.Compare(value.(time.Time))
+	}
+
+	switch operator {
+	case models.OpNe:
+		return c != 0
+	case models.OpLt:
+		return c < 0
+	case models.OpLte:
+		return c <= 0
+	case models.OpGt:
+		return c > 0
+	case models.OpGte:
+		return c >= 0
+	default:
+		return c == 0
+	}
+}
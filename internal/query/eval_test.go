@@ -0,0 +1,42 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"testing"
+	"time"
+)
+
+func Test_Eval_ShouldMatchExpressions(t *testing.T) {
+	firstAppeared := time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)
+	elixir := models.Language{Name: "Elixir", Creators: []string{"José Valim"}, Extensions: []string{".ex", ".exs"}, FirstAppeared: &firstAppeared, Year: 2012}
+	c := models.Language{Name: "C", Creators: []string{"Dennis Ritchie"}, Extensions: []string{".c", ".h"}, Year: 1972}
+
+	for _, test := range []struct {
+		expr     string
+		language models.Language
+		expected bool
+	}{
+		{`name = "ELIXIR" and creators has "jose valim"`, elixir, true},
+		{`year > 2000 or extensions has ".h"`, c, true},
+		{`not (year > 2000 or extensions has ".h")`, c, false},
+		{`name in ["Go", "c"]`, c, true},
+		{`extensions has only [".exs", ".ex"]`, elixir, true},
+		{`extensions has any [".c", ".go"] and extensions has none [".hpp"]`, c, true},
+		{`firstAppeared = null`, c, true},
+		{`firstAppeared != null`, elixir, true},
+		{`firstAppeared >= "2012-01-01"`, elixir, true},
+		{`firstAppeared < "2012-01-01"`, c, false},
+		{`not firstAppeared < "2012-01-01"`, c, true},
+		{`firstAppeared != "2012-01-01"`, c, true},
+	} {
+		e, err := ParseExpr(test.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %v", test.expr, err)
+		}
+
+		if Eval(e, test.language) != test.expected {
+			t.Errorf("Eval of %s on %s should be %t", test.expr, test.language.Name, test.expected)
+		}
+	}
+}
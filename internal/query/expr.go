@@ -0,0 +1,472 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidExpr indicates that a filter expression can't be parsed. Every SyntaxError wraps it.
+var ErrInvalidExpr = errors.New("invalid filter expression")
+
+// DateLayouts are the forms a firstAppeared can be given in, a full timestamp or just a date
+var DateLayouts = []string{time.RFC3339, time.DateOnly}
+
+// ParseDate reads a time in any of DateLayouts
+func ParseDate(value string) (t time.Time, err error) {
+	for _, layout := range DateLayouts {
+		t, err = time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// SyntaxError points at the token of a filter expression that couldn't be parsed
+type SyntaxError struct {
+	// Column is where the token starts, counting characters from 1
+	Column int
+	// Token is the text of the token, or empty at the end of the expression
+	Token string
+	// Reason says what was wrong with the token or what was expected in its place
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("column %d: %s, got the end of the expression", e.Column, e.Reason)
+	}
+
+	return fmt.Sprintf("column %d: %s, got %q", e.Column, e.Reason, e.Token)
+}
+
+// Unwrap lets errors.Is(err, ErrInvalidExpr) match a SyntaxError
+func (e *SyntaxError) Unwrap() error {
+	return ErrInvalidExpr
+}
+
+// exprFields are the fields a filter expression can compare, and whether each is an array
+var exprFields = map[string]bool{
+	FieldName:          false,
+	FieldCreators:      true,
+	FieldExtensions:    true,
+	FieldFirstAppeared: false,
+	FieldYear:          false,
+	FieldWiki:          false,
+}
+
+var exprOperators = map[string]models.Operator{
+	"=":  models.OpEq,
+	"!=": models.OpNe,
+	"<":  models.OpLt,
+	"<=": models.OpLte,
+	">":  models.OpGt,
+	">=": models.OpGte,
+}
+
+var exprSetModes = map[string]models.SetMode{"all": models.SetAll, "any": models.SetAny, "none": models.SetNone, "only": models.SetExact}
+
+// ParseExpr parses a filter expression such as
+//
+//	year >= 1990 and (creators has "Anders Hejlsberg" or extensions has any [".ts", ".tsx"])
+//
+// Comparisons are joined with not, and and or, from the tightest binding to the loosest, and can be grouped
+// with parentheses. Each compares a field with a value:
+//
+//   - name, wiki and year with =, != or in [values]
+//   - year and firstAppeared with =, !=, <, <=, > and >=
+//   - firstAppeared with = null or != null, for languages without or with a date
+//   - creators and extensions with has value, or has all, any, none or only [values], as in their
+//     query string filters
+//
+// Strings are double quoted, with \" and \\ as escapes, and dates are strings in any of DateLayouts.
+// Field names and keywords are matched case-insensitively.
+func ParseExpr(s string) (models.Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, t.errorf("expected and, or or the end of the expression")
+	}
+
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as it was written
+	text string
+	// value is the unquoted contents of a string
+	value  string
+	column int
+}
+
+func (t token) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Column: t.column, Token: t.text, Reason: fmt.Sprintf(format, args...)}
+}
+
+// is reports whether t is the given keyword or punctuation
+func (t token) is(text string) bool {
+	return (t.kind == tokenWord || t.kind == tokenPunct || t.kind == tokenOperator) && strings.EqualFold(t.text, text)
+}
+
+func lex(s string) (tokens []token, err error) {
+	column := 1
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		start, startColumn := i, column
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			column++
+			continue
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				i += size
+				column++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[start:i], column: startColumn})
+		case r == '-' || unicode.IsDigit(r):
+			i++
+			column++
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+				column++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[start:i], column: startColumn})
+		case r == '"':
+			var value strings.Builder
+			closed := false
+
+			for i, column = i+1, column+1; i < len(s) && !closed; {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				i += size
+				column++
+
+				switch {
+				case r == '"':
+					closed = true
+				case r == '\\' && i < len(s) && (s[i] == '"' || s[i] == '\\'):
+					value.WriteByte(s[i])
+					i++
+					column++
+				default:
+					value.WriteRune(r)
+				}
+			}
+
+			if !closed {
+				return nil, &SyntaxError{Column: startColumn, Token: s[start:], Reason: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s[start:i], value: value.String(), column: startColumn})
+		case strings.ContainsRune("=!<>", r):
+			i++
+			if i < len(s) && s[i] == '=' {
+				i++
+			}
+			column += i - start
+
+			if _, ok := exprOperators[s[start:i]]; !ok {
+				return nil, &SyntaxError{Column: startColumn, Token: s[start:i], Reason: "unknown operator"}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: s[start:i], column: startColumn})
+		case strings.ContainsRune("()[],", r):
+			i++
+			column++
+			tokens = append(tokens, token{kind: tokenPunct, text: s[start:i], column: startColumn})
+		default:
+			return nil, &SyntaxError{Column: startColumn, Token: string(r), Reason: "unexpected character"}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, column: column}), nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEnd {
+		p.i++
+	}
+
+	return t
+}
+
+func (p *parser) expect(text string) error {
+	if t := p.next(); !t.is(text) {
+		return t.errorf("expected %q", text)
+	}
+
+	return nil
+}
+
+func (p *parser) or() (models.Expr, error) {
+	var operands models.Or
+
+	for {
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, e)
+
+		if !p.peek().is("or") {
+			break
+		}
+		p.next()
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+
+	return operands, nil
+}
+
+func (p *parser) and() (models.Expr, error) {
+	var operands models.And
+
+	for {
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, e)
+
+		if !p.peek().is("and") {
+			break
+		}
+		p.next()
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+
+	return operands, nil
+}
+
+func (p *parser) not() (models.Expr, error) {
+	if p.peek().is("not") {
+		p.next()
+
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		return models.Not{Expr: e}, nil
+	}
+
+	if p.peek().is("(") {
+		p.next()
+
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		return e, p.expect(")")
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (models.Expr, error) {
+	t := p.next()
+	if t.kind != tokenWord {
+		return nil, t.errorf("expected a field or (")
+	}
+
+	field := ""
+	for f := range exprFields {
+		if strings.EqualFold(t.text, f) {
+			field = f
+		}
+	}
+
+	if field == "" {
+		return nil, t.errorf("unknown field, expected one of: %s", strings.Join(exprFieldNames(), ", "))
+	}
+
+	operator := p.next()
+
+	if exprFields[field] {
+		if !operator.is("has") {
+			return nil, operator.errorf("%s can only be compared with has", field)
+		}
+
+		if mode, ok := exprSetModes[strings.ToLower(p.peek().text)]; ok && p.peek().kind == tokenWord {
+			p.next()
+
+			values, err := p.list(field)
+			if err != nil {
+				return nil, err
+			}
+
+			return models.Has{Field: field, Values: stringValues(values), Set: mode}, nil
+		}
+
+		value, err := p.value(field, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return models.Has{Field: field, Values: []string{value.(string)}, Set: models.SetAll}, nil
+	}
+
+	if operator.is("in") {
+		if field == FieldFirstAppeared {
+			return nil, operator.errorf("in can't be used with %s", field)
+		}
+
+		values, err := p.list(field)
+		if err != nil {
+			return nil, err
+		}
+
+		return models.In{Field: field, Values: values}, nil
+	}
+
+	op, ok := exprOperators[operator.text]
+	if operator.kind != tokenOperator || !ok {
+		return nil, operator.errorf("expected a comparison operator for %s", field)
+	}
+
+	if op != models.OpEq && op != models.OpNe && field != FieldYear && field != FieldFirstAppeared {
+		return nil, operator.errorf("%s can only be compared with = and !=", field)
+	}
+
+	value, err := p.value(field, op == models.OpEq || op == models.OpNe)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.Comparison{Field: field, Operator: op, Value: value}, nil
+}
+
+// list reads a bracketed, comma separated list of values of field
+func (p *parser) list(field string) (values []interface{}, err error) {
+	err = p.expect("[")
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		value, err := p.value(field, false)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if !p.peek().is(",") {
+			break
+		}
+		p.next()
+	}
+
+	return values, p.expect("]")
+}
+
+// value reads a value of field: a string, an int32 for year, or a time.Time for firstAppeared, which can
+// also be null if nullable is set
+func (p *parser) value(field string, nullable bool) (interface{}, error) {
+	t := p.next()
+
+	switch field {
+	case FieldYear:
+		if t.kind != tokenNumber {
+			return nil, t.errorf("expected a year")
+		}
+
+		year, err := strconv.ParseInt(t.text, 10, 32)
+		if err != nil {
+			return nil, t.errorf("invalid year")
+		}
+
+		return int32(year), nil
+	case FieldFirstAppeared:
+		if nullable && t.is("null") {
+			return nil, nil
+		}
+
+		if t.kind != tokenString {
+			return nil, t.errorf("expected a quoted date")
+		}
+
+		date, err := ParseDate(t.value)
+		if err != nil {
+			return nil, t.errorf("invalid date, expected one of the forms %s", strings.Join(DateLayouts, " or "))
+		}
+
+		return date, nil
+	default:
+		if t.kind != tokenString {
+			return nil, t.errorf("expected a quoted string")
+		}
+
+		return t.value, nil
+	}
+}
+
+// exprFieldNames lists the fields an expression can compare in the order models.Language declares them
+func exprFieldNames() (names []string) {
+	for _, field := range Fields {
+		if _, ok := exprFields[field]; ok {
+			names = append(names, field)
+		}
+	}
+
+	return names
+}
+
+func stringValues(values []interface{}) []string {
+	s := make([]string, len(values))
+	for i, value := range values {
+		s[i] = value.(string)
+	}
+
+	return s
+}
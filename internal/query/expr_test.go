@@ -0,0 +1,113 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ParseExpr_ShouldParseNestedExpressions(t *testing.T) {
+	expected := models.And{
+		models.Comparison{Field: FieldYear, Operator: models.OpGte, Value: int32(1990)},
+		models.Or{
+			models.Has{Field: FieldCreators, Values: []string{"Anders Hejlsberg"}, Set: models.SetAll},
+			models.Has{Field: FieldExtensions, Values: []string{".ts"}, Set: models.SetAll},
+		},
+	}
+
+	e, err := ParseExpr(`year>=1990 and (creators has "Anders Hejlsberg" or extensions has ".ts")`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing expression: %v", err)
+	}
+
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("ParseExpr should return %#v, but got %#v", expected, e)
+	}
+}
+
+func Test_ParseExpr_ShouldBindNotThenAndThenOr(t *testing.T) {
+	expected := models.Or{
+		models.And{
+			models.Not{Expr: models.Comparison{Field: FieldName, Operator: models.OpEq, Value: "C"}},
+			models.Comparison{Field: FieldWiki, Operator: models.OpNe, Value: `say "hi"`},
+		},
+		models.In{Field: FieldYear, Values: []interface{}{int32(1972), int32(-1)}},
+	}
+
+	e, err := ParseExpr(`NOT Name = "C" AND wiki != "say \"hi\"" OR year in [1972, -1]`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing expression: %v", err)
+	}
+
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("ParseExpr should return %#v, but got %#v", expected, e)
+	}
+}
+
+func Test_ParseExpr_ShouldParseDatesAndNull(t *testing.T) {
+	expected := models.Or{
+		models.Comparison{Field: FieldFirstAppeared, Operator: models.OpLt, Value: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)},
+		models.Comparison{Field: FieldFirstAppeared, Operator: models.OpEq, Value: nil},
+	}
+
+	e, err := ParseExpr(`firstAppeared < "1990-01-01" or firstAppeared = null`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing expression: %v", err)
+	}
+
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("ParseExpr should return %#v, but got %#v", expected, e)
+	}
+}
+
+func Test_ParseExpr_ShouldParseSetModes(t *testing.T) {
+	expected := models.Has{Field: FieldExtensions, Values: []string{".h", ".hpp"}, Set: models.SetNone}
+
+	e, err := ParseExpr(`extensions has none [".h", ".hpp"]`)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing expression: %v", err)
+	}
+
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("ParseExpr should return %#v, but got %#v", expected, e)
+	}
+}
+
+func Test_ParseExpr_ShouldPointAtOffendingToken(t *testing.T) {
+	for _, test := range []struct {
+		expr   string
+		column int
+		token  string
+	}{
+		{`popularity > 3`, 1, "popularity"},
+		{`year >= "1990"`, 9, `"1990"`},
+		{`name > "C"`, 6, ">"},
+		{`year >= 1990 and`, 17, ""},
+		{`year >= 1990 year`, 14, "year"},
+		{`(year = 1990`, 13, ""},
+		{`creators = "Rob Pike"`, 10, "="},
+		{`extensions has any ".h"`, 20, `".h"`},
+		{`firstAppeared in ["2009-11-10"]`, 15, "in"},
+		{`firstAppeared > "yesterday"`, 17, `"yesterday"`},
+		{`firstAppeared > null`, 17, "null"},
+		{`name = "José" and year => 2012`, 25, ">"},
+		{`name = "C`, 8, `"C`},
+		{`year = 1990 & name = "C"`, 13, "&"},
+		{`year = 99999999999`, 8, "99999999999"},
+	} {
+		_, err := ParseExpr(test.expr)
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrInvalidExpr) {
+			t.Errorf("ParseExpr(%s) should return a SyntaxError, but got %v", test.expr, err)
+			continue
+		}
+
+		if syntaxErr.Column != test.column || syntaxErr.Token != test.token {
+			t.Errorf("ParseExpr(%s) should point at %q in column %d, but got %v", test.expr, test.token, test.column, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"slices"
+	"strings"
+)
+
+// Extensions groups languages by the extensions they use, in byte order, each listing its languages in the
+// order they are given. Extensions are case-sensitive, so .C and .c are listed apart.
+func Extensions(languages []models.Language) []models.Extension {
+	byExtension := map[string][]models.LanguageRef{}
+
+	for _, l := range languages {
+		for _, extension := range l.Extensions {
+			refs := byExtension[extension]
+			if len(refs) == 0 || refs[len(refs)-1].Id != l.Id {
+				byExtension[extension] = append(refs, models.LanguageRef{Id: l.Id, Name: l.Name})
+			}
+		}
+	}
+
+	extensions := make([]models.Extension, 0, len(byExtension))
+	for extension, refs := range byExtension {
+		extensions = append(extensions, models.Extension{Extension: extension, Languages: refs})
+	}
+
+	slices.SortFunc(extensions, func(a, b models.Extension) int { return strings.Compare(a.Extension, b.Extension) })
+	return extensions
+}
+
+// NormalizeExtension adds the leading dot extensions are stored with if it is missing, so "go" and ".go" are
+// the same extension
+func NormalizeExtension(extension string) string {
+	if strings.HasPrefix(extension, ".") {
+		return extension
+	}
+
+	return "." + extension
+}
+
+// Classify picks the languages filename may be written in from its extension. Every suffix of the name that
+// starts with a dot is tried, longest first, so a language using .d.ts would be picked over one using .ts.
+// Extensions are matched exactly first, so foo.C is C++ and foo.c is C, and only ignoring case if no language
+// uses any of them as written, so FOO.PY is still Python.
+func Classify(languages []models.Language, filename string) models.Classification {
+	classification := models.Classification{Filename: filename, Candidates: []models.LanguageRef{}}
+	suffixes := suffixes(filename)
+
+	for _, caseInsensitive := range []bool{false, true} {
+		for _, suffix := range suffixes {
+			for _, l := range languages {
+				if slices.ContainsFunc(l.Extensions, func(extension string) bool {
+					return extension == suffix || caseInsensitive && strings.EqualFold(extension, suffix)
+				}) {
+					classification.Candidates = append(classification.Candidates, models.LanguageRef{Id: l.Id, Name: l.Name})
+				}
+			}
+
+			if len(classification.Candidates) > 0 {
+				classification.Extension = suffix
+				classification.Ambiguous = len(classification.Candidates) > 1
+				classification.CaseInsensitive = caseInsensitive
+				return classification
+			}
+		}
+	}
+
+	return classification
+}
+
+// suffixes returns every suffix of the base name of filename that starts with a dot, longest first. A leading
+// dot, as in .bashrc, marks a hidden file rather than an extension, so it isn't a suffix.
+func suffixes(filename string) (suffixes []string) {
+	base := filename[strings.LastIndexAny(filename, `/\`)+1:]
+
+	for i := 1; i < len(base); i++ {
+		if base[i] == '.' && i < len(base)-1 {
+			suffixes = append(suffixes, base[i:])
+		}
+	}
+
+	return suffixes
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var extensionLanguages = []models.Language{
+	{Id: primitive.NewObjectID(), Name: "C", Extensions: []string{".c", ".h"}},
+	{Id: primitive.NewObjectID(), Name: "C++", Extensions: []string{".C", ".cpp", ".h", ".H"}},
+	{Id: primitive.NewObjectID(), Name: "TypeScript", Extensions: []string{".ts", ".d.ts"}},
+	{Id: primitive.NewObjectID(), Name: "Qt", Extensions: []string{".ts"}},
+	{Id: primitive.NewObjectID(), Name: "Python", Extensions: []string{".py", ".py"}},
+}
+
+func Test_Extensions_ShouldGroupLanguagesByCaseSensitiveExtension(t *testing.T) {
+	extensions := Extensions(extensionLanguages)
+
+	var names []string
+	for _, e := range extensions {
+		names = append(names, e.Extension)
+	}
+
+	expected := []string{".C", ".H", ".c", ".cpp", ".d.ts", ".h", ".py", ".ts"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Extensions should list %v, but got %v", expected, names)
+	}
+
+	if h := extensions[5].Languages; len(h) != 2 || h[0].Name != "C" || h[1].Name != "C++" {
+		t.Errorf("Extensions should list C and C++ for .h, but got %v", h)
+	}
+
+	if py := extensions[6].Languages; len(py) != 1 {
+		t.Errorf("Extensions should list Python once for .py, but got %v", py)
+	}
+}
+
+func Test_NormalizeExtension_ShouldAddMissingDot(t *testing.T) {
+	for extension, expected := range map[string]string{"go": ".go", ".go": ".go", "d.ts": ".d.ts"} {
+		if normalized := NormalizeExtension(extension); normalized != expected {
+			t.Errorf("NormalizeExtension(%q) should be %q, but got %q", extension, expected, normalized)
+		}
+	}
+}
+
+func Test_Classify_ShouldPickCandidatesByExtension(t *testing.T) {
+	for _, test := range []struct {
+		filename        string
+		extension       string
+		candidates      []string
+		ambiguous       bool
+		caseInsensitive bool
+	}{
+		{"main.c", ".c", []string{"C"}, false, false},
+		{"main.C", ".C", []string{"C++"}, false, false},
+		{"include/util.h", ".h", []string{"C", "C++"}, true, false},
+		{`src\types.d.ts`, ".d.ts", []string{"TypeScript"}, false, false},
+		{"app.ts", ".ts", []string{"TypeScript", "Qt"}, true, false},
+		{"MAIN.PY", ".PY", []string{"Python"}, false, true},
+		{"main.CPP", ".CPP", []string{"C++"}, false, true},
+		{"Makefile", "", nil, false, false},
+		{".py", "", nil, false, false},
+		{"archive.tar.", "", nil, false, false},
+	} {
+		classification := Classify(extensionLanguages, test.filename)
+
+		var candidates []string
+		for _, l := range classification.Candidates {
+			candidates = append(candidates, l.Name)
+		}
+
+		if classification.Filename != test.filename || classification.Extension != test.extension || !reflect.DeepEqual(candidates, test.candidates) ||
+			classification.Ambiguous != test.ambiguous || classification.CaseInsensitive != test.caseInsensitive {
+			t.Errorf("Classify(%q) returned an unexpected classification %+v", test.filename, classification)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The facets languages can be counted by
+const (
+	FacetYear       = "year"
+	FacetDecade     = "decade"
+	FacetCreators   = "creators"
+	FacetExtensions = "extensions"
+)
+
+var (
+	// ErrUnknownFacet indicates that a list of facets names one languages can't be counted by
+	ErrUnknownFacet = errors.New("unknown facet")
+	// ErrDuplicateFacet indicates that a list of facets names the same facet more than once
+	ErrDuplicateFacet = errors.New("duplicate facet")
+)
+
+// Facets lists every facet in the order models.Facets declares them
+var Facets = []string{FacetYear, FacetDecade, FacetCreators, FacetExtensions}
+
+// ParseFacets reads a comma separated list of facets, such as "year,creators". An empty string means no facets.
+func ParseFacets(s string) (facets []string, err error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	for _, facet := range strings.Split(s, ",") {
+		if !slices.Contains(Facets, facet) {
+			return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownFacet, facet, strings.Join(Facets, ", "))
+		}
+
+		if slices.Contains(facets, facet) {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateFacet, facet)
+		}
+
+		facets = append(facets, facet)
+	}
+
+	return facets, nil
+}
+
+// CountFacets counts languages by each of facets the same way every driver does, or returns nil if there are none
+func CountFacets(languages []models.Language, facets []string) *models.Facets {
+	if len(facets) == 0 {
+		return nil
+	}
+
+	years, decades := map[int32]int64{}, map[int32]int64{}
+	creators, extensions := map[string]int64{}, map[string]int64{}
+
+	for _, l := range languages {
+		years[l.Year]++
+		decades[Decade(l.Year)]++
+
+		for _, creator := range l.Creators {
+			creators[creator]++
+		}
+		for _, extension := range l.Extensions {
+			extensions[extension]++
+		}
+	}
+
+	return CompleteFacets(models.Facets{
+		Year:       yearCounts(years),
+		Decade:     yearCounts(decades),
+		Creators:   nameCounts(creators),
+		Extensions: nameCounts(extensions),
+	}, facets)
+}
+
+// CompleteFacets keeps only the counts of counted that facets asked for, leaving those empty rather than nil so
+// that they are encoded as empty arrays. The counts are put in the order Stats gives them, as a driver may have
+// compared names with a collation while counting. It returns nil if facets is empty.
+func CompleteFacets(counted models.Facets, facets []string) *models.Facets {
+	if len(facets) == 0 {
+		return nil
+	}
+
+	sortYearCounts(counted.Year)
+	sortYearCounts(counted.Decade)
+	sortNameCounts(counted.Creators)
+	sortNameCounts(counted.Extensions)
+
+	completed := &models.Facets{}
+	for _, facet := range facets {
+		switch facet {
+		case FacetYear:
+			completed.Year = nonNil(counted.Year)
+		case FacetDecade:
+			completed.Decade = nonNil(counted.Decade)
+		case FacetCreators:
+			completed.Creators = nonNil(counted.Creators)
+		case FacetExtensions:
+			completed.Extensions = nonNil(counted.Extensions)
+		}
+	}
+
+	return completed
+}
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_ParseFacets_ShouldReadFacetsInOrder(t *testing.T) {
+	expected := []string{"creators", "year"}
+
+	facets, err := ParseFacets("creators,year")
+	if err != nil {
+		t.Errorf("Unexpected error parsing facets: %v", err)
+	}
+
+	if !reflect.DeepEqual(facets, expected) {
+		t.Errorf("ParseFacets should return %v, but got %v", expected, facets)
+	}
+}
+
+func Test_ParseFacets_ShouldRejectUnknownAndDuplicateFacets(t *testing.T) {
+	_, err := ParseFacets("year,name")
+	if !errors.Is(err, ErrUnknownFacet) {
+		t.Errorf("Expected ErrUnknownFacet but got %v", err)
+	}
+
+	_, err = ParseFacets("year,decade,year")
+	if !errors.Is(err, ErrDuplicateFacet) {
+		t.Errorf("Expected ErrDuplicateFacet but got %v", err)
+	}
+}
+
+func Test_CountFacets_ShouldOnlyCountGivenFacets(t *testing.T) {
+	languages := []models.Language{
+		{Name: "C", Creators: []string{"Dennis Ritchie"}, Extensions: []string{".c", ".h"}, Year: 1972},
+		{Name: "C++", Creators: []string{"Bjarne Stroustrup"}, Extensions: []string{".cpp", ".h"}, Year: 1985},
+		{Name: "Go", Creators: []string{"Rob Pike", "Ken Thompson"}, Extensions: []string{".go"}, Year: 2009},
+		{Name: "B", Creators: []string{"Ken Thompson", "Dennis Ritchie"}, Extensions: []string{".b"}, Year: 1969},
+	}
+
+	expected := &models.Facets{
+		Decade: []models.YearCount{{Year: 1960, Count: 1}, {Year: 1970, Count: 1}, {Year: 1980, Count: 1}, {Year: 2000, Count: 1}},
+		Creators: []models.NameCount{
+			{Name: "Dennis Ritchie", Count: 2},
+			{Name: "Ken Thompson", Count: 2},
+			{Name: "Bjarne Stroustrup", Count: 1},
+			{Name: "Rob Pike", Count: 1},
+		},
+	}
+
+	if facets := CountFacets(languages, []string{FacetCreators, FacetDecade}); !reflect.DeepEqual(facets, expected) {
+		t.Errorf("CountFacets should return %+v, but got %+v", expected, facets)
+	}
+
+	if facets := CountFacets(languages, nil); facets != nil {
+		t.Errorf("CountFacets should return nil without facets, but got %+v", facets)
+	}
+}
+
+func Test_CountFacets_ShouldReturnEmptyCountsWhenNothingMatches(t *testing.T) {
+	expected := &models.Facets{Year: []models.YearCount{}, Extensions: []models.NameCount{}}
+
+	if facets := CountFacets(nil, []string{FacetYear, FacetExtensions}); !reflect.DeepEqual(facets, expected) {
+		t.Errorf("CountFacets should return %+v, but got %+v", expected, facets)
+	}
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The fields of a language that aren't sortable, named as they are in the JSON and bson documents
+const (
+	FieldId         = "_id"
+	FieldCreators   = "creators"
+	FieldExtensions = "extensions"
+	FieldWiki       = "wiki"
+	FieldHeuristics = "heuristics"
+	FieldRevision   = "revision"
+)
+
+var (
+	// ErrUnknownField indicates that a list of fields names a field languages don't have
+	ErrUnknownField = errors.New("unknown field")
+	// ErrDuplicateField indicates that a list of fields names the same field more than once
+	ErrDuplicateField = errors.New("duplicate field")
+)
+
+// Fields lists every field of a language in the order models.Language declares them
+var Fields = []string{FieldId, FieldName, FieldCreators, FieldExtensions, FieldFirstAppeared, FieldYear, FieldWiki, FieldHeuristics, FieldRevision}
+
+// ParseFields reads a comma separated list of fields, such as "name,year". An empty string means every field.
+func ParseFields(s string) (fields []string, err error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	for _, field := range strings.Split(s, ",") {
+		if !slices.Contains(Fields, field) {
+			return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownField, field, strings.Join(Fields, ", "))
+		}
+
+		if slices.Contains(fields, field) {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateField, field)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// Selected is the fields a driver reads to return fields of languages sorted by sort. The id and revision are always
+// read, as are the sort fields, so that callers can still build ETags and cursors. Nil fields selects every field.
+func Selected(fields []string, sort []models.SortField) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	selected := []string{FieldId, FieldRevision}
+	for _, f := range sort {
+		selected = append(selected, f.Field)
+	}
+
+	for _, field := range fields {
+		if !slices.Contains(selected, field) {
+			selected = append(selected, field)
+		}
+	}
+
+	return selected
+}
+
+// Project returns l with the fields that aren't in fields left as their zero value. Nil fields keeps every field.
+func Project(l models.Language, fields []string) models.Language {
+	if len(fields) == 0 {
+		return l
+	}
+
+	projected := models.Language{}
+	for _, field := range fields {
+		switch field {
+		case FieldId:
+			projected.Id = l.Id
+		case FieldName:
+			projected.Name = l.Name
+		case FieldCreators:
+			projected.Creators = l.Creators
+		case FieldExtensions:
+			projected.Extensions = l.Extensions
+		case FieldFirstAppeared:
+			projected.FirstAppeared = l.FirstAppeared
+		case FieldYear:
+			projected.Year = l.Year
+		case FieldWiki:
+			projected.Wiki = l.Wiki
+		case FieldHeuristics:
+			projected.Heuristics = l.Heuristics
+		case FieldRevision:
+			projected.Revision = l.Revision
+		}
+	}
+
+	return projected
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_ParseFields_ShouldReadFieldsInOrder(t *testing.T) {
+	expected := []string{"year", "name"}
+
+	fields, err := ParseFields("year,name")
+	if err != nil {
+		t.Errorf("Unexpected error parsing fields: %v", err)
+	}
+
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("ParseFields should return %v, but got %v", expected, fields)
+	}
+}
+
+func Test_ParseFields_ShouldReturnNilForEmptyString(t *testing.T) {
+	fields, err := ParseFields("")
+	if err != nil || fields != nil {
+		t.Errorf("ParseFields should return nil, nil but got %v, %v", fields, err)
+	}
+}
+
+func Test_ParseFields_ShouldRejectUnknownAndDuplicateFields(t *testing.T) {
+	_, err := ParseFields("name,nameKey")
+	if !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField but got %v", err)
+	}
+
+	_, err = ParseFields("name,year,name")
+	if !errors.Is(err, ErrDuplicateField) {
+		t.Errorf("Expected ErrDuplicateField but got %v", err)
+	}
+}
+
+func Test_Selected_ShouldAddIdRevisionAndSortFields(t *testing.T) {
+	expected := []string{"_id", "revision", "firstAppeared", "name"}
+
+	selected := Selected([]string{"name", "revision"}, []models.SortField{{Field: FieldFirstAppeared, Descending: true}})
+	if !reflect.DeepEqual(selected, expected) {
+		t.Errorf("Selected should return %v, but got %v", expected, selected)
+	}
+
+	if selected = Selected(nil, []models.SortField{{Field: FieldName}}); selected != nil {
+		t.Errorf("Selected should return nil for every field, but got %v", selected)
+	}
+}
+
+func Test_Project_ShouldKeepOnlyGivenFields(t *testing.T) {
+	firstAppeared := time.Date(2009, 11, 10, 0, 0, 0, 0, time.UTC)
+	language := models.Language{
+		Id:            primitive.NewObjectID(),
+		Name:          "Golang",
+		Creators:      []string{"Rob Pike"},
+		Extensions:    []string{".go"},
+		FirstAppeared: &firstAppeared,
+		Year:          2009,
+		Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
+		Revision:      3,
+	}
+	expected := models.Language{Name: "Golang", Year: 2009}
+
+	if projected := Project(language, []string{"name", "year"}); !reflect.DeepEqual(projected, expected) {
+		t.Errorf("Project should return %+v, but got %+v", expected, projected)
+	}
+
+	if projected := Project(language, nil); !reflect.DeepEqual(projected, language) {
+		t.Errorf("Project should return %+v, but got %+v", language, projected)
+	}
+}
@@ -0,0 +1,66 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold returns s without case or diacritics, which is the form names and creators are matched in,
+// so that "jose valim" matches "José Valim"
+func Fold(s string) string {
+	// Transformers keep state between calls, so each call needs its own
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), cases.Fold(), norm.NFC)
+
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+
+	return folded
+}
+
+// Match reports whether value matches pattern in the given mode, ignoring case and diacritics
+func Match(value string, pattern string, mode models.MatchMode) bool {
+	value, pattern = Fold(value), Fold(pattern)
+
+	switch mode {
+	case models.MatchPrefix:
+		return strings.HasPrefix(value, pattern)
+	case models.MatchContains:
+		return strings.Contains(value, pattern)
+	default:
+		return value == pattern
+	}
+}
+
+// MatchSet reports whether values holds patterns in the way mode asks for, using match to compare a value with a pattern
+func MatchSet(values []string, patterns []string, mode models.SetMode, match func(value string, pattern string) bool) bool {
+	held := func(pattern string) bool {
+		return slices.ContainsFunc(values, func(value string) bool { return match(value, pattern) })
+	}
+
+	switch mode {
+	case models.SetAny:
+		return slices.ContainsFunc(patterns, held)
+	case models.SetNone:
+		return !slices.ContainsFunc(patterns, held)
+	}
+
+	allHeld := !slices.ContainsFunc(patterns, func(pattern string) bool { return !held(pattern) })
+	if mode != models.SetExact {
+		return allHeld
+	}
+
+	// An exact set also holds nothing that isn't one of the patterns
+	return allHeld && !slices.ContainsFunc(values, func(value string) bool {
+		return !slices.ContainsFunc(patterns, func(pattern string) bool { return match(value, pattern) })
+	})
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"testing"
+)
+
+func Test_Fold_ShouldRemoveCaseAndDiacritics(t *testing.T) {
+	for value, expected := range map[string]string{"José Valim": "jose valim", "François Yergeau": "francois yergeau", "GOLANG": "golang", "C++": "c++"} {
+		if folded := Fold(value); folded != expected {
+			t.Errorf("Fold(%q) should return %q, but got %q", value, expected, folded)
+		}
+	}
+}
+
+func Test_Match_ShouldCompareInEachMode(t *testing.T) {
+	for _, test := range []struct {
+		pattern  string
+		mode     models.MatchMode
+		expected bool
+	}{
+		{"jose valim", models.MatchExact, true},
+		{"jose", models.MatchExact, false},
+		{"JOSE", models.MatchPrefix, true},
+		{"valim", models.MatchPrefix, false},
+		{"sé va", models.MatchContains, true},
+		{"vali m", models.MatchContains, false},
+	} {
+		if Match("José Valim", test.pattern, test.mode) != test.expected {
+			t.Errorf("Match of %q in mode %d should be %t", test.pattern, test.mode, test.expected)
+		}
+	}
+}
+
+func Test_MatchSet_ShouldMatchInEachMode(t *testing.T) {
+	values := []string{".c", ".h"}
+	equal := func(value string, pattern string) bool { return value == pattern }
+
+	for _, test := range []struct {
+		patterns []string
+		mode     models.SetMode
+		expected bool
+	}{
+		{[]string{".h", ".c"}, models.SetAll, true},
+		{[]string{".h", ".hpp"}, models.SetAll, false},
+		{[]string{".h", ".hpp"}, models.SetAny, true},
+		{[]string{".cpp", ".hpp"}, models.SetAny, false},
+		{[]string{".cpp", ".hpp"}, models.SetNone, true},
+		{[]string{".h", ".hpp"}, models.SetNone, false},
+		{[]string{".h", ".c"}, models.SetExact, true},
+		{[]string{".c"}, models.SetExact, false},
+		{[]string{".c", ".h", ".hpp"}, models.SetExact, false},
+	} {
+		if MatchSet(values, test.patterns, test.mode, equal) != test.expected {
+			t.Errorf("MatchSet of %v in mode %d should be %t", test.patterns, test.mode, test.expected)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The operations of a JSON patch (RFC 6902)
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// PatchOps lists the operations a JSON patch of a language may hold
+var PatchOps = []string{OpAdd, OpRemove, OpReplace, OpMove, OpCopy, OpTest}
+
+// heuristicFields are the members of the heuristics of a language, each of which is an array of strings
+var heuristicFields = []string{"interpreters", "modes", "keywords"}
+
+// ErrInvalidOperation indicates that an operation of a JSON patch isn't one that can apply to a language
+var ErrInvalidOperation = errors.New("invalid operation")
+
+var (
+	// errPathMissing fails an operation whose path doesn't exist in the language
+	errPathMissing = errors.New("path does not exist")
+	// errTestFailed fails a test operation whose value isn't the one at its path
+	errTestFailed = errors.New("test failed")
+)
+
+// arrayIndex matches the tokens of a JSON pointer (RFC 6901) that index an array, which have no leading zeros
+var arrayIndex = regexp.MustCompile(`^(0|[1-9][0-9]*)$`)
+
+// pointerEscapes undoes the escaping of "~" and "/" in the tokens of a JSON pointer
+var pointerEscapes = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ValidatePatch checks each operation of a JSON patch against the shape of a language, before it is applied to any:
+// the path must name a member a language has, and the value must be one that member can hold. The id and revision
+// are managed by the storage drivers, so they can only be tested, and a language always keeps a name.
+func ValidatePatch(patch models.JSONPatch) error {
+	for i, operation := range patch {
+		if !slices.Contains(PatchOps, operation.Op) {
+			return fmt.Errorf("%w %d: unknown op %q, expected one of: %s", ErrInvalidOperation, i, operation.Op, strings.Join(PatchOps, ", "))
+		}
+
+		if operation.Path == "" {
+			return fmt.Errorf("%w %d: the whole language can't be patched, replace it instead", ErrInvalidOperation, i)
+		}
+
+		tokens, ok := pointer(operation.Path)
+		target, index := patchTarget(tokens)
+		if !ok || target == nil {
+			return fmt.Errorf("%w %d: %q is not a member of a language", ErrInvalidOperation, i, operation.Path)
+		}
+
+		last := tokens[len(tokens)-1]
+		adds := operation.Op == OpAdd || operation.Op == OpMove || operation.Op == OpCopy
+		if index && !arrayIndex.MatchString(last) && (last != "-" || !adds) {
+			return fmt.Errorf("%w %d: %q does not index an array, which takes a number or - when adding", ErrInvalidOperation, i, operation.Path)
+		}
+
+		readOnly := tokens[0] == FieldId || tokens[0] == FieldRevision
+		if readOnly && operation.Op != OpTest {
+			return fmt.Errorf("%w %d: %s is managed by the database, so it can only be tested", ErrInvalidOperation, i, tokens[0])
+		}
+
+		if operation.Path == "/"+FieldName && operation.Op == OpRemove {
+			return fmt.Errorf("%w %d: a language's name cannot be removed", ErrInvalidOperation, i)
+		}
+
+		if operation.Op == OpMove || operation.Op == OpCopy {
+			if err := validateFrom(operation, target); err != nil {
+				return fmt.Errorf("%w %d: %w", ErrInvalidOperation, i, err)
+			}
+			continue
+		}
+
+		if operation.Op == OpRemove {
+			continue
+		}
+
+		if len(operation.Value) == 0 {
+			return fmt.Errorf("%w %d: %s needs a value", ErrInvalidOperation, i, operation.Op)
+		}
+
+		if index && string(bytes.TrimSpace(operation.Value)) == "null" {
+			return fmt.Errorf("%w %d: the value of %q cannot be null", ErrInvalidOperation, i, operation.Path)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(operation.Value))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(target); err != nil {
+			return fmt.Errorf("%w %d: the value does not fit %q: %w", ErrInvalidOperation, i, operation.Path, err)
+		}
+
+		if name, ok := target.(*string); ok && operation.Path == "/"+FieldName && *name == "" {
+			return fmt.Errorf("%w %d: a language's name cannot be empty", ErrInvalidOperation, i)
+		}
+	}
+
+	return nil
+}
+
+// ApplyPatch applies a JSON patch to language as RFC 6902 does to a document, for drivers to apply to the stored
+// language as a whole. Either every operation is applied or, if any fails, the language is returned unchanged with
+// a models.PatchError. An array that is null is taken to be empty, so elements can be added to it.
+func ApplyPatch(language models.Language, patch models.JSONPatch) (models.Language, error) {
+	if err := ValidatePatch(patch); err != nil {
+		return language, err
+	}
+
+	data, err := json.Marshal(language)
+	if err != nil {
+		return language, err
+	}
+
+	var document interface{}
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		return language, err
+	}
+
+	for i, operation := range patch {
+		var value interface{}
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return language, err
+			}
+		}
+
+		if operation.Op == OpMove || operation.Op == OpCopy {
+			from, _ := pointer(operation.From)
+			moved, ok := valueAt(document, from)
+			if !ok {
+				return language, models.PatchError{Operation: i, Reason: operation.From + " does not exist"}
+			}
+
+			if operation.Op == OpMove {
+				document, _ = patchAt(document, from, patchOperation(OpRemove, nil))
+			}
+
+			value, operation.Op = copyValue(moved), OpAdd
+		}
+
+		tokens, _ := pointer(operation.Path)
+		document, err = patchAt(document, tokens, patchOperation(operation.Op, value))
+		if errors.Is(err, errTestFailed) {
+			return language, models.PatchError{Operation: i, Reason: "the value at " + operation.Path + " is not the one tested"}
+		}
+
+		if err != nil {
+			return language, models.PatchError{Operation: i, Reason: operation.Path + " does not exist"}
+		}
+	}
+
+	data, err = json.Marshal(document)
+	if err != nil {
+		return language, err
+	}
+
+	var patched models.Language
+	err = json.Unmarshal(data, &patched)
+	if err != nil {
+		return language, err
+	}
+	patched.Id, patched.Revision = language.Id, language.Revision
+
+	if patched.Name == "" {
+		return language, models.PatchError{Operation: len(patch) - 1, Reason: "it leaves the language without a name"}
+	}
+
+	return patched, nil
+}
+
+// validateFrom checks that a move or copy takes its value from a member of a language that holds the same type as
+// the one it is put in, and that a move leaves the language with what it can't do without
+func validateFrom(operation models.PatchOperation, target interface{}) error {
+	tokens, ok := pointer(operation.From)
+	from, index := patchTarget(tokens)
+	if !ok || from == nil {
+		return fmt.Errorf("from %q is not a member of a language", operation.From)
+	}
+
+	if index && !arrayIndex.MatchString(tokens[len(tokens)-1]) {
+		return fmt.Errorf("from %q does not index an array, which takes a number", operation.From)
+	}
+
+	if reflect.TypeOf(from) != reflect.TypeOf(target) {
+		return fmt.Errorf("%q cannot hold the value of %q", operation.Path, operation.From)
+	}
+
+	if operation.Op != OpMove {
+		return nil
+	}
+
+	if tokens[0] == FieldId || tokens[0] == FieldRevision {
+		return fmt.Errorf("%s is managed by the database, so it can't be moved", tokens[0])
+	}
+
+	if operation.From == "/"+FieldName && operation.Path != operation.From {
+		return errors.New("a language's name cannot be removed")
+	}
+
+	if strings.HasPrefix(operation.Path, operation.From+"/") {
+		return fmt.Errorf("%q cannot be moved into itself", operation.From)
+	}
+
+	return nil
+}
+
+// pointer splits a JSON pointer into its tokens, or reports that path isn't one
+func pointer(path string) (tokens []string, ok bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+
+	tokens = strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerEscapes.Replace(token)
+	}
+
+	return tokens, true
+}
+
+// patchTarget is a new value of the type held by the member of a language the tokens point to, for the value of an
+// operation to be decoded into, or nil if languages have no such member. index reports whether the last token
+// indexes an array.
+func patchTarget(tokens []string) (target interface{}, index bool) {
+	switch {
+	case len(tokens) == 1:
+		switch tokens[0] {
+		case FieldId:
+			return new(primitive.ObjectID), false
+		case FieldName, FieldWiki:
+			return new(string), false
+		case FieldCreators, FieldExtensions:
+			return new([]string), false
+		case FieldFirstAppeared:
+			return new(*time.Time), false
+		case FieldYear:
+			return new(int32), false
+		case FieldHeuristics:
+			return new(*models.Heuristics), false
+		case FieldRevision:
+			return new(int64), false
+		}
+	case len(tokens) == 2 && (tokens[0] == FieldCreators || tokens[0] == FieldExtensions):
+		return new(string), true
+	case len(tokens) == 2 && tokens[0] == FieldHeuristics && slices.Contains(heuristicFields, tokens[1]):
+		return new([]string), false
+	case len(tokens) == 3 && tokens[0] == FieldHeuristics && slices.Contains(heuristicFields, tokens[1]):
+		return new(string), true
+	}
+
+	return nil, false
+}
+
+// patchAt applies operation to the object or array holding the member the tokens point to. Arrays are copied when
+// they grow or shrink, so each object or array on the way is set to the one its member was patched into.
+func patchAt(node interface{}, tokens []string, operation func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return operation(node, tokens[0])
+	}
+
+	child, ok := member(node, tokens[0])
+	if !ok {
+		return nil, errPathMissing
+	}
+
+	child, err := patchAt(child, tokens[1:], operation)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		container[tokens[0]] = child
+	case []interface{}:
+		i, _ := strconv.Atoi(tokens[0])
+		container[i] = child
+	}
+
+	return node, nil
+}
+
+// valueAt is the value the tokens point to in document, if there is one
+func valueAt(document interface{}, tokens []string) (interface{}, bool) {
+	for _, token := range tokens {
+		var ok bool
+		document, ok = member(document, token)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return document, true
+}
+
+// copyValue copies a value of a document deeply, so that a copy can be patched apart from the original
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, member := range value {
+			copied[key] = copyValue(member)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, element := range value {
+			copied[i] = copyValue(element)
+		}
+		return copied
+	}
+
+	return value
+}
+
+// member is the member of an object or the element of an array the token names, if there is one
+func member(container interface{}, token string) (interface{}, bool) {
+	switch container := container.(type) {
+	case map[string]interface{}:
+		value, ok := container[token]
+		return value, ok
+	case []interface{}:
+		i, err := strconv.Atoi(token)
+		if err != nil || i >= len(container) {
+			return nil, false
+		}
+		return container[i], true
+	}
+
+	return nil, false
+}
+
+// patchOperation makes the operation op with value on the member of an object or array the token names, returning
+// the object or array it leaves
+func patchOperation(op string, value interface{}) func(container interface{}, token string) (interface{}, error) {
+	return func(container interface{}, token string) (interface{}, error) {
+		if container == nil {
+			container = []interface{}{}
+		}
+
+		if op == OpAdd {
+			switch container := container.(type) {
+			case map[string]interface{}:
+				container[token] = value
+				return container, nil
+			case []interface{}:
+				if token == "-" {
+					return append(container, value), nil
+				}
+
+				i, _ := strconv.Atoi(token)
+				if i > len(container) {
+					return nil, errPathMissing
+				}
+				return slices.Insert(container, i, value), nil
+			}
+		}
+
+		current, ok := member(container, token)
+		if !ok {
+			return nil, errPathMissing
+		}
+
+		switch op {
+		case OpTest:
+			if !reflect.DeepEqual(current, value) {
+				return nil, errTestFailed
+			}
+		case OpReplace:
+			switch container := container.(type) {
+			case map[string]interface{}:
+				container[token] = value
+			case []interface{}:
+				i, _ := strconv.Atoi(token)
+				container[i] = value
+			}
+		case OpRemove:
+			switch c := container.(type) {
+			case map[string]interface{}:
+				delete(c, token)
+			case []interface{}:
+				i, _ := strconv.Atoi(token)
+				container = slices.Delete(c, i, i+1)
+			}
+		}
+
+		return container, nil
+	}
+}
@@ -0,0 +1,208 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newJSONPatch(t *testing.T, data string) models.JSONPatch {
+	var patch models.JSONPatch
+
+	err := json.Unmarshal([]byte(data), &patch)
+	if err != nil {
+		t.Fatal("Error unmarshalling patch:", err)
+	}
+
+	return patch
+}
+
+func newPatchedLanguage(t *testing.T) models.Language {
+	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
+	if err != nil {
+		t.Error("Error parsing timestamp:", err)
+	}
+
+	return models.Language{
+		Id:            primitive.NewObjectID(),
+		Name:          "Golang",
+		Creators:      []string{"Robert Griesemer", "Rob Pike", "Ken Thompson"},
+		Extensions:    []string{".go"},
+		FirstAppeared: &firstAppeared,
+		Year:          2009,
+		Wiki:          "https://go.dev",
+		Heuristics:    &models.Heuristics{Keywords: []string{"package"}},
+		Revision:      3,
+	}
+}
+
+func Test_ValidatePatch_ShouldRejectOperationsThatDoNotFitALanguage(t *testing.T) {
+	patches := map[string]string{
+		"unknown op":         `[{"op": "merge", "path": "/name"}]`,
+		"missing from":       `[{"op": "copy", "path": "/wiki"}]`,
+		"mismatched from":    `[{"op": "copy", "from": "/year", "path": "/wiki"}]`,
+		"appending from":     `[{"op": "copy", "from": "/creators/-", "path": "/extensions/-"}]`,
+		"moved name":         `[{"op": "move", "from": "/name", "path": "/wiki"}]`,
+		"moved revision":     `[{"op": "move", "from": "/revision", "path": "/revision"}]`,
+		"moved into itself":  `[{"op": "move", "from": "/heuristics/modes", "path": "/heuristics/modes/0"}]`,
+		"whole language":     `[{"op": "replace", "path": "", "value": {}}]`,
+		"unknown member":     `[{"op": "add", "path": "/color", "value": "blue"}]`,
+		"too deep":           `[{"op": "add", "path": "/creators/0/name", "value": "Rob Pike"}]`,
+		"unknown heuristic":  `[{"op": "add", "path": "/heuristics/shebangs", "value": []}]`,
+		"leading zero":       `[{"op": "remove", "path": "/creators/01"}]`,
+		"append not adding":  `[{"op": "replace", "path": "/creators/-", "value": "Rob Pike"}]`,
+		"read only id":       `[{"op": "replace", "path": "/_id", "value": "5f0c6a1e8f1b2c3d4e5f6a7b"}]`,
+		"read only revision": `[{"op": "remove", "path": "/revision"}]`,
+		"removed name":       `[{"op": "remove", "path": "/name"}]`,
+		"empty name":         `[{"op": "replace", "path": "/name", "value": ""}]`,
+		"missing value":      `[{"op": "add", "path": "/wiki"}]`,
+		"null element":       `[{"op": "add", "path": "/creators/-", "value": null}]`,
+		"wrong type":         `[{"op": "replace", "path": "/year", "value": "2009"}]`,
+		"unknown field":      `[{"op": "add", "path": "/heuristics", "value": {"shebangs": []}}]`,
+	}
+
+	for name, data := range patches {
+		err := ValidatePatch(newJSONPatch(t, data))
+		if !errors.Is(err, ErrInvalidOperation) {
+			t.Errorf("%s: ValidatePatch should return ErrInvalidOperation, but got %v", name, err)
+		}
+	}
+}
+
+func Test_ValidatePatch_ShouldAcceptOperationsThatFitALanguage(t *testing.T) {
+	err := ValidatePatch(newJSONPatch(t, `[
+		{"op": "test", "path": "/revision", "value": 3},
+		{"op": "add", "path": "/creators/-", "value": "Russ Cox"},
+		{"op": "remove", "path": "/extensions/0"},
+		{"op": "replace", "path": "/firstAppeared", "value": null},
+		{"op": "add", "path": "/heuristics/modes/0", "value": "go"},
+		{"op": "replace", "path": "/heuristics", "value": {"keywords": ["func"]}},
+		{"op": "move", "from": "/creators/0", "path": "/extensions/-"},
+		{"op": "copy", "from": "/heuristics/keywords", "path": "/heuristics/modes"}
+	]`))
+	if err != nil {
+		t.Errorf("ValidatePatch should accept the patch, but got %v", err)
+	}
+}
+
+func Test_ApplyPatch_ShouldApplyEachOperationInOrder(t *testing.T) {
+	language := newPatchedLanguage(t)
+
+	patched, err := ApplyPatch(language, newJSONPatch(t, `[
+		{"op": "test", "path": "/name", "value": "Golang"},
+		{"op": "replace", "path": "/name", "value": "Go"},
+		{"op": "add", "path": "/creators/-", "value": "Russ Cox"},
+		{"op": "remove", "path": "/creators/0"},
+		{"op": "replace", "path": "/creators/0", "value": "Robert Pike"},
+		{"op": "add", "path": "/extensions/0", "value": ".mod"},
+		{"op": "remove", "path": "/wiki"},
+		{"op": "replace", "path": "/firstAppeared", "value": null},
+		{"op": "add", "path": "/heuristics/modes/-", "value": "go"}
+	]`))
+	if err != nil {
+		t.Fatal("Unexpected error in ApplyPatch:", err)
+	}
+
+	expected := models.Language{
+		Id:         language.Id,
+		Name:       "Go",
+		Creators:   []string{"Robert Pike", "Ken Thompson", "Russ Cox"},
+		Extensions: []string{".mod", ".go"},
+		Year:       2009,
+		Heuristics: &models.Heuristics{Modes: []string{"go"}, Keywords: []string{"package"}},
+		Revision:   3,
+	}
+	if !reflect.DeepEqual(patched, expected) {
+		t.Errorf("ApplyPatch should return %+v, but got %+v", expected, patched)
+	}
+
+	if language.Name != "Golang" || !reflect.DeepEqual(language.Creators, []string{"Robert Griesemer", "Rob Pike", "Ken Thompson"}) || language.Wiki == "" {
+		t.Error("ApplyPatch should not modify the language it is given")
+	}
+}
+
+func Test_ApplyPatch_ShouldApplyNothingIfAnOperationFails(t *testing.T) {
+	language := newPatchedLanguage(t)
+
+	patches := map[string]string{
+		"failed test":       `[{"op": "replace", "path": "/year", "value": 2012}, {"op": "test", "path": "/wiki", "value": "https://golang.org"}]`,
+		"missing element":   `[{"op": "replace", "path": "/year", "value": 2012}, {"op": "remove", "path": "/creators/3"}]`,
+		"past the end":      `[{"op": "replace", "path": "/year", "value": 2012}, {"op": "add", "path": "/extensions/2", "value": ".mod"}]`,
+		"missing parent":    `[{"op": "remove", "path": "/heuristics"}, {"op": "add", "path": "/heuristics/modes", "value": ["go"]}]`,
+		"missing heuristic": `[{"op": "replace", "path": "/year", "value": 2012}, {"op": "remove", "path": "/heuristics/modes/0"}]`,
+	}
+
+	for name, data := range patches {
+		patched, err := ApplyPatch(language, newJSONPatch(t, data))
+
+		var patchError models.PatchError
+		if !errors.As(err, &patchError) || patchError.Operation != 1 {
+			t.Errorf("%s: ApplyPatch should fail the second operation, but got %v", name, err)
+		}
+
+		if !reflect.DeepEqual(patched, language) {
+			t.Errorf("%s: ApplyPatch should return the language unchanged, but got %+v", name, patched)
+		}
+	}
+}
+
+func Test_ApplyPatch_ShouldMoveAndCopyValues(t *testing.T) {
+	language := newPatchedLanguage(t)
+
+	patched, err := ApplyPatch(language, newJSONPatch(t, `[
+		{"op": "move", "from": "/creators/2", "path": "/creators/0"},
+		{"op": "copy", "from": "/heuristics/keywords", "path": "/heuristics/modes"},
+		{"op": "add", "path": "/heuristics/modes/-", "value": "go"},
+		{"op": "move", "from": "/wiki", "path": "/creators/-"}
+	]`))
+	if err != nil {
+		t.Fatal("Unexpected error in ApplyPatch:", err)
+	}
+
+	if !reflect.DeepEqual(patched.Creators, []string{"Ken Thompson", "Robert Griesemer", "Rob Pike", "https://go.dev"}) || patched.Wiki != "" {
+		t.Errorf("ApplyPatch should move the creator and the wiki, but got %v and %q", patched.Creators, patched.Wiki)
+	}
+
+	if !reflect.DeepEqual(patched.Heuristics, &models.Heuristics{Modes: []string{"package", "go"}, Keywords: []string{"package"}}) {
+		t.Errorf("ApplyPatch should copy the keywords apart from the modes, but got %+v", patched.Heuristics)
+	}
+
+	_, err = ApplyPatch(language, newJSONPatch(t, `[{"op": "copy", "from": "/extensions/1", "path": "/creators/-"}]`))
+	var patchError models.PatchError
+	if !errors.As(err, &patchError) || patchError.Reason != "/extensions/1 does not exist" {
+		t.Errorf("ApplyPatch should fail to copy a missing value, but got %v", err)
+	}
+
+	_, err = ApplyPatch(language, newJSONPatch(t, `[{"op": "copy", "from": "/wiki", "path": "/name"}, {"op": "replace", "path": "/wiki", "value": ""}, {"op": "copy", "from": "/wiki", "path": "/name"}]`))
+	if !errors.As(err, &patchError) {
+		t.Errorf("ApplyPatch should fail a patch that leaves the language without a name, but got %v", err)
+	}
+}
+
+func Test_ApplyPatch_ShouldAddToNullArrays(t *testing.T) {
+	patched, err := ApplyPatch(models.Language{Name: "Go"}, newJSONPatch(t, `[{"op": "add", "path": "/creators/-", "value": "Rob Pike"}]`))
+	if err != nil {
+		t.Fatal("Unexpected error in ApplyPatch:", err)
+	}
+
+	if !reflect.DeepEqual(patched.Creators, []string{"Rob Pike"}) {
+		t.Errorf("ApplyPatch should add the creator, but got %v", patched.Creators)
+	}
+}
+
+func Test_pointer_ShouldUnescapeTokens(t *testing.T) {
+	tokens, ok := pointer("/a~1b/c~0d/~01")
+	if !ok || !reflect.DeepEqual(tokens, []string{"a/b", "c~d", "~1"}) {
+		t.Errorf("pointer should unescape each token, but got %v", tokens)
+	}
+
+	if _, ok := pointer("name"); ok {
+		t.Error("pointer should reject paths that don't start with /")
+	}
+}
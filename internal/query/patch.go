@@ -0,0 +1,81 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"slices"
+	"time"
+)
+
+// MergePatch applies a JSON merge patch to language as RFC 7396 does to a document, for drivers that can't apply
+// the patch in the database. Removed fields are left as their zero value. The result shares nothing with patch.
+func MergePatch(language models.Language, patch models.Patch) models.Language {
+	merge(&language.Name, patch.Name, "")
+	merge(&language.Creators, patch.Creators, nil)
+	merge(&language.Extensions, patch.Extensions, nil)
+	mergeTime(&language.FirstAppeared, patch.FirstAppeared)
+	merge(&language.Year, patch.Year, 0)
+	merge(&language.Wiki, patch.Wiki, "")
+
+	language.Creators = slices.Clone(language.Creators)
+	language.Extensions = slices.Clone(language.Extensions)
+
+	if patch.Heuristics.Set {
+		language.Heuristics = mergeHeuristics(language.Heuristics, patch.Heuristics.Value)
+	}
+
+	return language
+}
+
+// mergeHeuristics merges a patch of heuristics into heuristics, removing them if the patch is nil. Heuristics that
+// don't exist yet are merged into empty ones, as RFC 7396 merges into an empty object.
+func mergeHeuristics(heuristics *models.Heuristics, patch *models.HeuristicsPatch) *models.Heuristics {
+	if patch == nil {
+		return nil
+	}
+
+	merged := models.Heuristics{}
+	if heuristics != nil {
+		merged = *heuristics
+	}
+
+	merge(&merged.Interpreters, patch.Interpreters, nil)
+	merge(&merged.Modes, patch.Modes, nil)
+	merge(&merged.Keywords, patch.Keywords, nil)
+
+	merged.Interpreters = slices.Clone(merged.Interpreters)
+	merged.Modes = slices.Clone(merged.Modes)
+	merged.Keywords = slices.Clone(merged.Keywords)
+
+	return &merged
+}
+
+// merge sets value to the value of field if the patch has it, or to removed if it is null
+func merge[T any](value *T, field models.Field[T], removed T) {
+	if !field.Set {
+		return
+	}
+
+	if field.Value == nil {
+		*value = removed
+		return
+	}
+
+	*value = *field.Value
+}
+
+// mergeTime sets value to a copy of the time field holds if the patch has it, or to nil if it is null. firstAppeared
+// is a pointer in models.Language, but a value in the patch.
+func mergeTime(value **time.Time, field models.Field[time.Time]) {
+	if !field.Set {
+		return
+	}
+
+	if field.Value == nil {
+		*value = nil
+		return
+	}
+
+	t := *field.Value
+	*value = &t
+}
@@ -0,0 +1,87 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newPatch(t *testing.T, data string) models.Patch {
+	var patch models.Patch
+
+	err := json.Unmarshal([]byte(data), &patch)
+	if err != nil {
+		t.Fatal("Error unmarshalling patch:", err)
+	}
+
+	return patch
+}
+
+func Test_Patch_ShouldTellAbsentMembersFromNullOnes(t *testing.T) {
+	patch := newPatch(t, `{"wiki": null, "year": 2012}`)
+
+	if !patch.Wiki.Set || patch.Wiki.Value != nil {
+		t.Errorf("wiki should be set to null, but got %+v", patch.Wiki)
+	}
+
+	if !patch.Year.Set || patch.Year.Value == nil || *patch.Year.Value != 2012 {
+		t.Errorf("year should be set to 2012, but got %+v", patch.Year)
+	}
+
+	if patch.Name.Set || patch.Heuristics.Set {
+		t.Errorf("name and heuristics should be absent, but got %+v and %+v", patch.Name, patch.Heuristics)
+	}
+}
+
+func Test_MergePatch_ShouldSetAndRemoveOnlyTheFieldsThePatchHas(t *testing.T) {
+	firstAppeared := time.Date(2009, 11, 10, 0, 0, 0, 0, time.UTC)
+	language := models.Language{
+		Name:          "Golang",
+		Creators:      []string{"Rob Pike"},
+		Extensions:    []string{".go"},
+		FirstAppeared: &firstAppeared,
+		Year:          2009,
+		Wiki:          "https://go.dev",
+		Heuristics:    &models.Heuristics{Interpreters: []string{"go"}, Keywords: []string{"package"}},
+		Revision:      3,
+	}
+
+	merged := MergePatch(language, newPatch(t, `{
+		"name": "Go",
+		"creators": [],
+		"firstAppeared": null,
+		"wiki": null,
+		"heuristics": {"keywords": ["func"], "interpreters": null}
+	}`))
+
+	expected := models.Language{
+		Name:       "Go",
+		Creators:   []string{},
+		Extensions: []string{".go"},
+		Year:       2009,
+		Heuristics: &models.Heuristics{Keywords: []string{"func"}},
+		Revision:   3,
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("MergePatch should return %+v, but got %+v", expected, merged)
+	}
+
+	if language.Heuristics.Interpreters == nil || language.Wiki == "" {
+		t.Error("MergePatch should not modify the language it is given")
+	}
+}
+
+func Test_MergePatch_ShouldRemoveHeuristicsOrMergeThemIntoEmptyOnes(t *testing.T) {
+	removed := MergePatch(models.Language{Heuristics: &models.Heuristics{Modes: []string{"go"}}}, newPatch(t, `{"heuristics": null}`))
+	if removed.Heuristics != nil {
+		t.Errorf("MergePatch should remove the heuristics, but got %+v", removed.Heuristics)
+	}
+
+	added := MergePatch(models.Language{}, newPatch(t, `{"heuristics": {"modes": ["go"]}}`))
+	if !reflect.DeepEqual(added.Heuristics, &models.Heuristics{Modes: []string{"go"}}) {
+		t.Errorf("MergePatch should add the heuristics, but got %+v", added.Heuristics)
+	}
+}
@@ -0,0 +1,209 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"cmp"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Match qualities, from a term that is the whole value down to one found anywhere in it
+const (
+	exactMatch    = 1.0
+	wordMatch     = 0.5
+	containsMatch = 0.25
+)
+
+// searchFields are the fields Search looks in, with how much a match in each counts towards the score
+var searchFields = []struct {
+	name   string
+	weight float64
+	values func(l models.Language) []string
+}{
+	{"name", 8, func(l models.Language) []string { return []string{l.Name} }},
+	{"extensions", 4, func(l models.Language) []string { return l.Extensions }},
+	{"creators", 2, func(l models.Language) []string { return l.Creators }},
+	{"wiki", 1, func(l models.Language) []string { return []string{l.Wiki} }},
+}
+
+// Terms splits a search query into the folded terms it is matched with, dropping repeats
+func Terms(q string) (terms []string) {
+	for _, term := range strings.Fields(Fold(q)) {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+// Search returns the languages that match every term of q in at least one of their name, extensions, creators
+// or wiki, ignoring case and diacritics. Results are ranked by score, with ties in name order. Each term scores
+// the weight of every field it matches, scaled by whether it matched a whole value, the start of a word or
+// anywhere else, so that a search for "java" ranks Java above JavaScript.
+func Search(languages []models.Language, q string) []models.SearchResult {
+	terms := Terms(q)
+	results := []models.SearchResult{}
+
+	if len(terms) == 0 {
+		return results
+	}
+
+	for _, language := range languages {
+		if result, ok := search(language, terms); ok {
+			results = append(results, result)
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b models.SearchResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+
+		return Compare(a.Language, b.Language, []models.SortField{{Field: FieldName}})
+	})
+
+	return results
+}
+
+// MatchTerms reports whether Search would return the language for the given folded terms
+func MatchTerms(language models.Language, terms []string) bool {
+	_, ok := search(language, terms)
+	return ok
+}
+
+func search(language models.Language, terms []string) (result models.SearchResult, ok bool) {
+	result = models.SearchResult{Language: language, Highlights: map[string][]string{}}
+	matched := make([]bool, len(terms))
+
+	for _, field := range searchFields {
+		best := make([]float64, len(terms))
+
+		for _, value := range field.values(language) {
+			folded := foldValue(value)
+
+			var spans []span
+			for i, term := range terms {
+				quality, termSpans := matchTerm(folded, term, field.name == "extensions")
+				if quality == 0 {
+					continue
+				}
+
+				matched[i] = true
+				best[i] = max(best[i], quality)
+				spans = append(spans, termSpans...)
+			}
+
+			if len(spans) > 0 {
+				result.Highlights[field.name] = append(result.Highlights[field.name], highlight(value, folded, spans))
+			}
+		}
+
+		for _, quality := range best {
+			result.Score += field.weight * quality
+		}
+	}
+
+	return result, !slices.Contains(matched, false)
+}
+
+// foldedValue is a value folded rune by rune, so that text found in the folded form can be traced back
+// to the original
+type foldedValue struct {
+	text string
+	// start and end hold, for each byte of text, where the rune it was folded from starts and ends in the original
+	start []int
+	end   []int
+}
+
+func foldValue(value string) (f foldedValue) {
+	var text strings.Builder
+
+	for i, r := range value {
+		end := i + utf8.RuneLen(r)
+		folded := Fold(string(r))
+
+		// Runes that fold away, such as combining accents, belong with the rune before them
+		if folded == "" && len(f.end) > 0 {
+			for j := len(f.end) - 1; j >= 0 && f.end[j] == i; j-- {
+				f.end[j] = end
+			}
+			continue
+		}
+
+		text.WriteString(folded)
+		for range len(folded) {
+			f.start = append(f.start, i)
+			f.end = append(f.end, end)
+		}
+	}
+
+	f.text = text.String()
+	return f
+}
+
+// span is a range of bytes in a folded value
+type span struct {
+	start int
+	end   int
+}
+
+// matchTerm finds every occurrence of term in value and how good the best of them is. Extensions are
+// compared without their leading dot, so that "go" is an exact match for ".go".
+func matchTerm(value foldedValue, term string, extension bool) (quality float64, spans []span) {
+	if extension && strings.TrimPrefix(value.text, ".") == strings.TrimPrefix(term, ".") {
+		return exactMatch, []span{{0, len(value.text)}}
+	}
+
+	if value.text == term {
+		return exactMatch, []span{{0, len(value.text)}}
+	}
+
+	for offset := 0; offset < len(value.text); {
+		i := strings.Index(value.text[offset:], term)
+		if i < 0 {
+			break
+		}
+
+		start := offset + i
+		spans = append(spans, span{start, start + len(term)})
+
+		q := containsMatch
+		if before, _ := utf8.DecodeLastRuneInString(value.text[:start]); start == 0 || !unicode.IsLetter(before) && !unicode.IsDigit(before) {
+			q = wordMatch
+		}
+		quality = max(quality, q)
+
+		offset = start + len(term)
+	}
+
+	return quality, spans
+}
+
+// highlight HTML escapes the original value and wraps the text each span was folded from in <em> tags
+func highlight(original string, value foldedValue, spans []span) string {
+	slices.SortFunc(spans, func(a, b span) int { return cmp.Compare(a.start, b.start) })
+
+	var b strings.Builder
+	written := 0
+
+	for i := 0; i < len(spans); {
+		start, end := value.start[spans[i].start], value.end[spans[i].end-1]
+
+		// Merge spans that overlap or touch once traced back to the original
+		for i++; i < len(spans) && value.start[spans[i].start] <= end; i++ {
+			end = max(end, value.end[spans[i].end-1])
+		}
+
+		b.WriteString(html.EscapeString(original[written:start]))
+		b.WriteString("<em>" + html.EscapeString(original[start:end]) + "</em>")
+		written = end
+	}
+
+	b.WriteString(html.EscapeString(original[written:]))
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"reflect"
+	"testing"
+)
+
+var searchLanguages = []models.Language{
+	{Name: "JavaScript", Creators: []string{"Brendan Eich"}, Extensions: []string{".js"}, Wiki: "https://en.wikipedia.org/wiki/JavaScript"},
+	{Name: "Java", Creators: []string{"James Gosling"}, Extensions: []string{".java"}, Wiki: "https://en.wikipedia.org/wiki/Java_(programming_language)"},
+	{Name: "Elixir", Creators: []string{"José Valim"}, Extensions: []string{".ex", ".exs"}},
+	{Name: "Go", Creators: []string{"Rob Pike"}, Extensions: []string{".go"}},
+}
+
+func Test_Terms_ShouldFoldAndDropRepeats(t *testing.T) {
+	expected := []string{"jose", "valim"}
+
+	if terms := Terms("  José JOSE valim "); !reflect.DeepEqual(terms, expected) {
+		t.Errorf("Terms should return %v, but got %v", expected, terms)
+	}
+}
+
+func Test_Search_ShouldRankWholeMatchesFirst(t *testing.T) {
+	results := Search(searchLanguages, "java")
+
+	if len(results) != 2 || results[0].Language.Name != "Java" || results[1].Language.Name != "JavaScript" {
+		t.Fatalf("Expected Java then JavaScript, but got %+v", results)
+	}
+
+	if results[0].Score <= results[1].Score {
+		t.Errorf("Expected Java to score higher than JavaScript, but got %v and %v", results[0].Score, results[1].Score)
+	}
+}
+
+func Test_Search_ShouldRequireEveryTerm(t *testing.T) {
+	results := Search(searchLanguages, "java gosling")
+
+	if len(results) != 1 || results[0].Language.Name != "Java" {
+		t.Errorf("Expected only Java, but got %+v", results)
+	}
+}
+
+func Test_Search_ShouldHighlightOriginalText(t *testing.T) {
+	results := Search(searchLanguages, "jose")
+
+	if len(results) != 1 {
+		t.Fatalf("Expected only Elixir, but got %+v", results)
+	}
+
+	expected := map[string][]string{"creators": {"<em>José</em> Valim"}}
+	if !reflect.DeepEqual(results[0].Highlights, expected) {
+		t.Errorf("Expected highlights of %v, but got %v", expected, results[0].Highlights)
+	}
+}
+
+func Test_Search_ShouldEscapeHighlights(t *testing.T) {
+	results := Search([]models.Language{{Name: "<b>C&C</b>"}}, "c&c")
+
+	expected := "&lt;b&gt;<em>C&amp;C</em>&lt;/b&gt;"
+	if len(results) != 1 || results[0].Highlights["name"][0] != expected {
+		t.Errorf("Expected a highlight of %s, but got %+v", expected, results)
+	}
+}
+
+func Test_Search_ShouldMatchExtensionsWithoutDot(t *testing.T) {
+	results := Search(searchLanguages, "go")
+
+	if len(results) == 0 || results[0].Language.Name != "Go" || results[0].Highlights["extensions"][0] != "<em>.go</em>" {
+		t.Errorf("Expected Go with .go highlighted, but got %+v", results)
+	}
+}
+
+func Test_Search_ShouldReturnNothingForEmptyQuery(t *testing.T) {
+	if results := Search(searchLanguages, " "); len(results) != 0 {
+		t.Errorf("Expected no results, but got %+v", results)
+	}
+}
@@ -0,0 +1,146 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Locale is the collation names are sorted with, so that every driver puts names like "C", "C#" and "C++"
+// in the same order as Mongo does
+const Locale = "en"
+
+// The fields languages can be sorted by
+const (
+	FieldName          = "name"
+	FieldYear          = "year"
+	FieldFirstAppeared = "firstAppeared"
+)
+
+var (
+	// ErrUnknownSortField indicates that a sort names a field languages can't be sorted by
+	ErrUnknownSortField = errors.New("unknown sort field")
+	// ErrDuplicateSortField indicates that a sort names the same field more than once
+	ErrDuplicateSortField = errors.New("duplicate sort field")
+)
+
+// SortableFields lists the fields languages can be sorted by
+var SortableFields = []string{FieldName, FieldYear, FieldFirstAppeared}
+
+// A Collator keeps buffers between calls, so it can only be used by one goroutine at a time
+var (
+	collatorMu sync.Mutex
+	collator   = collate.New(language.Make(Locale))
+)
+
+// ParseSort reads a comma separated list of fields, each of which may be prefixed with - to sort it in
+// descending order, such as "-year,name". An empty string means the default order.
+func ParseSort(s string) (sort []models.SortField, err error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	for _, field := range strings.Split(s, ",") {
+		descending := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+
+		if !slices.Contains(SortableFields, field) {
+			return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownSortField, field, strings.Join(SortableFields, ", "))
+		}
+
+		if slices.ContainsFunc(sort, func(f models.SortField) bool { return f.Field == field }) {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateSortField, field)
+		}
+
+		sort = append(sort, models.SortField{Field: field, Descending: descending})
+	}
+
+	return sort, nil
+}
+
+// FormatSort is the inverse of ParseSort
+func FormatSort(sort []models.SortField) string {
+	fields := make([]string, len(sort))
+	for i, f := range sort {
+		fields[i] = f.Field
+		if f.Descending {
+			fields[i] = "-" + f.Field
+		}
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// CompareStrings orders strings by the Locale collation
+func CompareStrings(a string, b string) int {
+	collatorMu.Lock()
+	defer collatorMu.Unlock()
+
+	return collator.CompareString(a, b)
+}
+
+// Value returns the value of a sortable field of l, which is nil for a missing firstAppeared
+func Value(l models.Language, field string) interface{} {
+	switch field {
+	case FieldName:
+		return l.Name
+	case FieldYear:
+		return l.Year
+	case FieldFirstAppeared:
+		if l.FirstAppeared == nil {
+			return nil
+		}
+		return *l.FirstAppeared
+	default:
+		panic("query: unsortable field " + field)
+	}
+}
+
+// Compare orders a and b by sort and then by id, the same way every driver orders Find results.
+// Like Mongo, a missing firstAppeared comes before every date.
+func Compare(a models.Language, b models.Language, sort []models.SortField) int {
+	for _, f := range sort {
+		var c int
+
+		switch f.Field {
+		case FieldName:
+			c = CompareStrings(a.Name, b.Name)
+		case FieldYear:
+			c = cmp.Compare(a.Year, b.Year)
+		case FieldFirstAppeared:
+			switch {
+			case a.FirstAppeared == nil || b.FirstAppeared == nil:
+				c = cmp.Compare(boolInt(a.FirstAppeared != nil), boolInt(b.FirstAppeared != nil))
+			default:
+				c = a.FirstAppeared.Compare(*b.FirstAppeared)
+			}
+		}
+
+		if f.Descending {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return bytes.Compare(a.Id[:], b.Id[:])
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,73 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_ParseSort_ShouldReadDirectionOfEachField(t *testing.T) {
+	expected := []models.SortField{{Field: "year", Descending: true}, {Field: "name"}}
+
+	sort, err := ParseSort("-year,name")
+	if err != nil {
+		t.Errorf("Unexpected error parsing sort: %v", err)
+	}
+
+	if !reflect.DeepEqual(sort, expected) {
+		t.Errorf("ParseSort should return %v, but got %v", expected, sort)
+	}
+
+	if FormatSort(sort) != "-year,name" {
+		t.Errorf("FormatSort should return -year,name, but got %s", FormatSort(sort))
+	}
+}
+
+func Test_ParseSort_ShouldReturnErrUnknownSortFieldForUnsortableField(t *testing.T) {
+	for _, s := range []string{"creators", "name,", "-"} {
+		_, err := ParseSort(s)
+		if !errors.Is(err, ErrUnknownSortField) {
+			t.Errorf("ParseSort(%q) should return ErrUnknownSortField, but got %v", s, err)
+		}
+	}
+}
+
+func Test_ParseSort_ShouldReturnErrDuplicateSortFieldForRepeatedField(t *testing.T) {
+	_, err := ParseSort("name,-name")
+	if !errors.Is(err, ErrDuplicateSortField) {
+		t.Errorf("ParseSort should return ErrDuplicateSortField, but got %v", err)
+	}
+}
+
+func Test_Compare_ShouldCollateNames(t *testing.T) {
+	names := []string{"C++", "cobol", "C#", "bash", "C", "COBOL"}
+
+	slices.SortFunc(names, CompareStrings)
+
+	expected := []string{"bash", "C", "C#", "C++", "cobol", "COBOL"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Names should sort as %v, but got %v", expected, names)
+	}
+}
+
+func Test_Compare_ShouldPutMissingDatesFirstThenBreakTiesById(t *testing.T) {
+	firstAppeared := time.Date(2009, time.November, 10, 0, 0, 0, 0, time.UTC)
+	a := models.Language{Id: primitive.NewObjectID()}
+	b := models.Language{Id: primitive.NewObjectID()}
+	c := models.Language{Id: primitive.NewObjectID(), FirstAppeared: &firstAppeared}
+
+	languages := []models.Language{c, b, a}
+	slices.SortFunc(languages, func(x, y models.Language) int {
+		return Compare(x, y, []models.SortField{{Field: FieldFirstAppeared}})
+	})
+
+	if languages[0].Id != a.Id || languages[1].Id != b.Id || languages[2].Id != c.Id {
+		t.Errorf("Languages without a date should come first in id order, but got %v", languages)
+	}
+}
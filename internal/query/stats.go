@@ -0,0 +1,117 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"bytes"
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Stats summarises languages the same way every driver does, keeping the topCreators most prolific creators,
+// or all of them if topCreators is 0
+func Stats(languages []models.Language, topCreators int64) models.Stats {
+	stats := models.Stats{}
+	years, decades := map[int32]int64{}, map[int32]int64{}
+	creators, extensions := map[string]int64{}, map[string]int64{}
+
+	for i, l := range languages {
+		stats.Totals.Languages++
+		if i == 0 || l.Year < stats.Totals.EarliestYear {
+			stats.Totals.EarliestYear = l.Year
+		}
+		if i == 0 || l.Year > stats.Totals.LatestYear {
+			stats.Totals.LatestYear = l.Year
+		}
+
+		years[l.Year]++
+		decades[Decade(l.Year)]++
+
+		for _, creator := range l.Creators {
+			creators[creator]++
+		}
+		for _, extension := range l.Extensions {
+			extensions[extension]++
+		}
+
+		if l.FirstAppeared == nil {
+			stats.MissingFirstAppeared = append(stats.MissingFirstAppeared, models.LanguageRef{Id: l.Id, Name: l.Name})
+		}
+	}
+
+	stats.ByYear, stats.ByDecade = yearCounts(years), yearCounts(decades)
+	stats.TopCreators, stats.Extensions = nameCounts(creators), nameCounts(extensions)
+	slices.SortFunc(stats.MissingFirstAppeared, func(a, b models.LanguageRef) int { return bytes.Compare(a.Id[:], b.Id[:]) })
+
+	return CompleteStats(stats, topCreators)
+}
+
+// CompleteStats fills in the totals a driver can count from the other parts of stats, given every creator in
+// TopCreators, then keeps only the topCreators most prolific of them, or all of them if topCreators is 0.
+// Missing parts are left empty rather than nil, so that they are encoded as empty arrays.
+func CompleteStats(stats models.Stats, topCreators int64) models.Stats {
+	stats.ByYear = nonNil(stats.ByYear)
+	stats.ByDecade = nonNil(stats.ByDecade)
+	stats.TopCreators = nonNil(stats.TopCreators)
+	stats.Extensions = nonNil(stats.Extensions)
+	stats.MissingFirstAppeared = nonNil(stats.MissingFirstAppeared)
+
+	stats.Totals.Creators = int64(len(stats.TopCreators))
+	stats.Totals.Extensions = int64(len(stats.Extensions))
+	stats.Totals.MissingFirstAppeared = int64(len(stats.MissingFirstAppeared))
+
+	if topCreators > 0 && int64(len(stats.TopCreators)) > topCreators {
+		stats.TopCreators = stats.TopCreators[:topCreators]
+	}
+
+	return stats
+}
+
+// Decade names the decade year falls in by its first year, so 1995 is in the 1990 decade
+func Decade(year int32) int32 {
+	return year - year%10
+}
+
+func yearCounts(counts map[int32]int64) []models.YearCount {
+	years := make([]models.YearCount, 0, len(counts))
+	for year, count := range counts {
+		years = append(years, models.YearCount{Year: year, Count: count})
+	}
+
+	sortYearCounts(years)
+	return years
+}
+
+// nameCounts orders names by count, most first, and then in byte order
+func nameCounts(counts map[string]int64) []models.NameCount {
+	names := make([]models.NameCount, 0, len(counts))
+	for name, count := range counts {
+		names = append(names, models.NameCount{Name: name, Count: count})
+	}
+
+	sortNameCounts(names)
+	return names
+}
+
+func sortYearCounts(years []models.YearCount) {
+	slices.SortFunc(years, func(a, b models.YearCount) int { return cmp.Compare(a.Year, b.Year) })
+}
+
+func sortNameCounts(names []models.NameCount) {
+	slices.SortFunc(names, func(a, b models.NameCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+
+	return s
+}
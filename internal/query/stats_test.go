@@ -0,0 +1,68 @@
+package query
+
+import (
+	"languages-api/internal/models"
+
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_Stats_ShouldCountYearsCreatorsAndExtensions(t *testing.T) {
+	firstAppeared := time.Date(2009, 11, 10, 0, 0, 0, 0, time.UTC)
+	languages := []models.Language{
+		{Id: primitive.NewObjectID(), Name: "Golang", Creators: []string{"Rob Pike", "Ken Thompson"}, Extensions: []string{".go"}, FirstAppeared: &firstAppeared, Year: 2009},
+		{Id: primitive.NewObjectID(), Name: "C", Creators: []string{"Dennis Ritchie"}, Extensions: []string{".c", ".h"}, Year: 1972},
+		{Id: primitive.NewObjectID(), Name: "C++", Creators: []string{"Bjarne Stroustrup"}, Extensions: []string{".C", ".h"}, Year: 1985},
+		{Id: primitive.NewObjectID(), Name: "B", Creators: []string{"Ken Thompson", "Dennis Ritchie"}, Year: 1969},
+	}
+
+	stats := Stats(languages, 2)
+
+	expectedTotals := models.StatsTotals{Languages: 4, Creators: 4, Extensions: 4, MissingFirstAppeared: 3, EarliestYear: 1969, LatestYear: 2009}
+	if stats.Totals != expectedTotals {
+		t.Errorf("Stats should total %+v, but got %+v", expectedTotals, stats.Totals)
+	}
+
+	expectedDecades := []models.YearCount{{Year: 1960, Count: 1}, {Year: 1970, Count: 1}, {Year: 1980, Count: 1}, {Year: 2000, Count: 1}}
+	if !reflect.DeepEqual(stats.ByDecade, expectedDecades) {
+		t.Errorf("Stats should count decades %v, but got %v", expectedDecades, stats.ByDecade)
+	}
+
+	expectedCreators := []models.NameCount{{Name: "Dennis Ritchie", Count: 2}, {Name: "Ken Thompson", Count: 2}}
+	if !reflect.DeepEqual(stats.TopCreators, expectedCreators) {
+		t.Errorf("Stats should keep the top creators %v, but got %v", expectedCreators, stats.TopCreators)
+	}
+
+	expectedExtensions := []models.NameCount{{Name: ".h", Count: 2}, {Name: ".C", Count: 1}, {Name: ".c", Count: 1}, {Name: ".go", Count: 1}}
+	if !reflect.DeepEqual(stats.Extensions, expectedExtensions) {
+		t.Errorf("Stats should count extensions %v, but got %v", expectedExtensions, stats.Extensions)
+	}
+
+	expectedMissing := []models.LanguageRef{{Id: languages[1].Id, Name: "C"}, {Id: languages[2].Id, Name: "C++"}, {Id: languages[3].Id, Name: "B"}}
+	if !reflect.DeepEqual(stats.MissingFirstAppeared, expectedMissing) {
+		t.Errorf("Stats should list %v as missing firstAppeared, but got %v", expectedMissing, stats.MissingFirstAppeared)
+	}
+}
+
+func Test_CompleteStats_ShouldKeepEveryCreatorIfTopCreatorsIsZero(t *testing.T) {
+	stats := CompleteStats(models.Stats{TopCreators: []models.NameCount{{Name: "Rob Pike", Count: 1}, {Name: "Ken Thompson", Count: 1}}}, 0)
+
+	if len(stats.TopCreators) != 2 || stats.Totals.Creators != 2 {
+		t.Errorf("CompleteStats should keep and total both creators, but got %+v", stats)
+	}
+
+	if stats.ByYear == nil || stats.ByDecade == nil || stats.Extensions == nil || stats.MissingFirstAppeared == nil {
+		t.Errorf("CompleteStats should leave missing parts empty rather than nil, but got %+v", stats)
+	}
+}
+
+func Test_Decade_ShouldNameDecadesByTheirFirstYear(t *testing.T) {
+	for year, expected := range map[int32]int32{1990: 1990, 1995: 1990, 1999: 1990, 2000: 2000} {
+		if decade := Decade(year); decade != expected {
+			t.Errorf("Decade(%d) should be %d, but got %d", year, expected, decade)
+		}
+	}
+}
@@ -3,27 +3,66 @@ package repo
 import (
 	"languages-api/internal/config"
 	"languages-api/internal/mgo"
+	"languages-api/internal/migrate"
 	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"context"
+	"errors"
 
 	"github.com/rs/zerolog/log"
 )
 
 type Repository interface {
 	Close() error
-	Ping() error
-	GetLanguages(language models.Language) (languages models.Languages, errors []error)
-	GetLanguage(id string) (language models.Language, err error)
-	PostLanguage(language models.Language) (insertedId string, err error)
-	PutLanguage(id string, language models.Language) (isUpserted bool, err error)
-	PatchLanguage(id string, update models.Language) (err error)
-	DeleteLanguage(id string) (err error)
+	Ping(ctx context.Context) error
+	GetLanguages(ctx context.Context, filter models.Filter, opts models.FindOptions) (languages models.Languages, errors []error)
+	StreamLanguages(ctx context.Context, filter models.Filter, opts models.FindOptions, send func(models.Language) error) (err error)
+	SearchLanguages(ctx context.Context, q string, filter models.Filter, opts models.FindOptions) (results models.SearchResults, errors []error)
+	GetLanguage(ctx context.Context, id string, fields []string) (language models.Language, err error)
+	PostLanguage(ctx context.Context, language models.Language) (insertedId string, err error)
+	PutLanguage(ctx context.Context, id string, language models.Language, revision int64) (written int64, isUpserted bool, err error)
+	PatchLanguage(ctx context.Context, id string, update models.Language, revision int64) (written int64, err error)
+	MergePatchLanguage(ctx context.Context, id string, patch models.Patch, revision int64) (written int64, err error)
+	JSONPatchLanguage(ctx context.Context, id string, patch models.JSONPatch, revision int64) (written int64, err error)
+	DeleteLanguage(ctx context.Context, id string, revision int64) (err error)
+	BatchLanguages(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error)
+	GetStats(ctx context.Context, topCreators int64) (stats models.Stats, err error)
+	GetExtensions(ctx context.Context) (extensions []models.Extension, errors []error)
+	GetExtension(ctx context.Context, extension string) (languages models.Extension, errors []error)
+	Classify(ctx context.Context, filename string) (classification models.Classification, errors []error)
+	Detect(ctx context.Context, content string, filename string) (detection models.Detection, errors []error)
 }
 
 type Repo struct {
 	client mgo.Client
 }
 
+// New connects to the database, applies any pending migrations if the config says to, and then creates the indexes.
+// Migrations run first so that they can fix up languages that would stop an index being built, such as those
+// that share a name.
 func New(cfg config.Config, c mgo.Connector) (r *Repo, err error) {
+	r, err = Open(cfg, c)
+	if err != nil {
+		return
+	}
+
+	if cfg.Migrations.RunOnStartup {
+		err = r.Migrate(context.Background())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to apply migrations")
+			return
+		}
+	}
+
+	err = r.EnsureIndexes(context.Background())
+
+	return
+}
+
+// Open connects to the database without migrating it or creating indexes, for the migrate subcommand to run
+// migrations that must be applied before the indexes can be built
+func Open(cfg config.Config, c mgo.Connector) (r *Repo, err error) {
 	r = &Repo{}
 	r.client, err = c.Connect(cfg)
 	if err != nil {
@@ -31,7 +70,7 @@ func New(cfg config.Config, c mgo.Connector) (r *Repo, err error) {
 		return
 	}
 
-	err = r.client.Ping()
+	err = r.client.Ping(context.Background())
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to ping database")
 		return
@@ -40,34 +79,186 @@ func New(cfg config.Config, c mgo.Connector) (r *Repo, err error) {
 	return
 }
 
+// EnsureIndexes creates any indexes that don't exist yet
+func (r *Repo) EnsureIndexes(ctx context.Context) error {
+	err := r.client.EnsureIndexes(ctx)
+	if errors.Is(err, models.ErrDuplicateNames) {
+		log.Error().Err(err).Msg("Failed to create the unique name index, as languages share a name. Apply the deduplicate_names migration with \"migrate up\" to rename them.")
+		return err
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create database indexes")
+	}
+
+	return err
+}
+
+// Migrate applies any pending migrations
+func (r *Repo) Migrate(ctx context.Context) error {
+	runner, err := r.Migrator()
+	if err != nil {
+		return err
+	}
+
+	applied, err := runner.Up(ctx)
+	if len(applied) > 0 {
+		log.Info().Msgf("Applied %d migration(s)", len(applied))
+	}
+
+	return err
+}
+
+// Migrator returns a migration runner for the known migrations, backed by the repo's database
+func (r *Repo) Migrator() (*migrate.Runner, error) {
+	return migrate.New(r.client, migrate.Migrations)
+}
+
+// Close disconnects from the database. It is only called on shutdown, so it isn't tied to a request context.
 func (r *Repo) Close() error {
-	return r.client.Disconnect()
+	return r.client.Disconnect(context.Background())
+}
+
+func (r *Repo) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx)
+}
+
+func (r *Repo) GetLanguages(ctx context.Context, filter models.Filter, opts models.FindOptions) (languages models.Languages, errors []error) {
+	return r.client.Find(ctx, filter, opts)
+}
+
+// StreamLanguages calls send with each language GetLanguages would return, as the driver reads them, without
+// totalling them or counting facets. It stops at the first error send returns.
+func (r *Repo) StreamLanguages(ctx context.Context, filter models.Filter, opts models.FindOptions, send func(models.Language) error) (err error) {
+	return r.client.Stream(ctx, filter, opts, send)
 }
 
-func (r *Repo) Ping() error {
-	return r.client.Ping()
+// SearchLanguages ranks the languages that match filter by how well they match q, returning the page of the
+// results that the Limit and Offset of opts select. The driver only reads the languages holding every term of q,
+// which are then ranked as they are stored, so results are the same whichever driver stores them.
+func (r *Repo) SearchLanguages(ctx context.Context, q string, filter models.Filter, opts models.FindOptions) (results models.SearchResults, errors []error) {
+	filter.Terms = query.Terms(q)
+
+	languages, errors := r.client.Find(ctx, filter, models.FindOptions{})
+	if len(errors) > 0 {
+		return models.SearchResults{Results: []models.SearchResult{}}, errors
+	}
+
+	ranked := query.Search(languages.Languages, q)
+	results.Total = int64(len(ranked))
+
+	ranked = ranked[min(opts.Offset, int64(len(ranked))):]
+	if opts.Limit > 0 && opts.Limit < int64(len(ranked)) {
+		ranked = ranked[:opts.Limit]
+	}
+	results.Results = ranked
+
+	return results, nil
+}
+
+func (r *Repo) GetLanguage(ctx context.Context, id string, fields []string) (language models.Language, err error) {
+	return r.client.FindOne(ctx, id, fields)
 }
 
-func (r *Repo) GetLanguages(language models.Language) (languages models.Languages, errors []error) {
-	return r.client.Find(language)
+func (r *Repo) PostLanguage(ctx context.Context, language models.Language) (insertedId string, err error) {
+	return r.client.InsertOne(ctx, language)
 }
 
-func (r *Repo) GetLanguage(id string) (language models.Language, err error) {
-	return r.client.FindOne(id)
+// PutLanguage replaces the language if it is at the given revision, or unconditionally if revision is 0, returning
+// the revision it was written at
+func (r *Repo) PutLanguage(ctx context.Context, id string, language models.Language, revision int64) (written int64, isUpserted bool, err error) {
+	return r.client.ReplaceOne(ctx, id, language, revision)
 }
 
-func (r *Repo) PostLanguage(language models.Language) (insertedId string, err error) {
-	return r.client.InsertOne(language)
+// PatchLanguage updates the language if it is at the given revision, or unconditionally if revision is 0, returning
+// the revision it was written at
+func (r *Repo) PatchLanguage(ctx context.Context, id string, update models.Language, revision int64) (written int64, err error) {
+	return r.client.UpdateOne(ctx, id, update, revision)
 }
 
-func (r *Repo) PutLanguage(id string, language models.Language) (isUpserted bool, err error) {
-	return r.client.ReplaceOne(id, language)
+// MergePatchLanguage applies a JSON merge patch to the language if it is at the given revision, or unconditionally
+// if revision is 0. Unlike PatchLanguage, it can remove fields and set them to zero values.
+func (r *Repo) MergePatchLanguage(ctx context.Context, id string, patch models.Patch, revision int64) (written int64, err error) {
+	return r.client.UpdateOne(ctx, id, patch, revision)
 }
 
-func (r *Repo) PatchLanguage(id string, update models.Language) (err error) {
-	return r.client.UpdateOne(id, update)
+// JSONPatchLanguage applies a JSON patch to the language if it is at the given revision, or unconditionally if
+// revision is 0. The operations are applied to the stored language as a whole, so if any fails none are.
+func (r *Repo) JSONPatchLanguage(ctx context.Context, id string, patch models.JSONPatch, revision int64) (written int64, err error) {
+	return r.client.UpdateOne(ctx, id, patch, revision)
 }
 
-func (r *Repo) DeleteLanguage(id string) (err error) {
-	return r.client.DeleteOne(id)
+// DeleteLanguage deletes the language if it is at the given revision, or unconditionally if revision is 0
+func (r *Repo) DeleteLanguage(ctx context.Context, id string, revision int64) (err error) {
+	return r.client.DeleteOne(ctx, id, revision)
+}
+
+// BatchLanguages makes each of the operations in order, as the write of the same kind on its own would, returning
+// the result of each. If atomic is set, either every operation is made or none are.
+func (r *Repo) BatchLanguages(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error) {
+	return r.client.BulkWrite(ctx, operations, atomic)
+}
+
+// GetStats summarises every stored language, keeping the topCreators most prolific creators, or all of them if
+// topCreators is 0
+func (r *Repo) GetStats(ctx context.Context, topCreators int64) (stats models.Stats, err error) {
+	return r.client.Stats(ctx, topCreators)
+}
+
+// extensionOptions reads only what extension lookups need, in name order so that each extension lists its
+// languages alphabetically
+var extensionOptions = models.FindOptions{
+	Sort:   []models.SortField{{Field: query.FieldName}},
+	Fields: []string{query.FieldName, query.FieldExtensions},
+}
+
+// GetExtensions lists every extension a stored language uses, with the languages that use it
+func (r *Repo) GetExtensions(ctx context.Context) (extensions []models.Extension, errors []error) {
+	languages, errors := r.client.Find(ctx, models.Filter{}, extensionOptions)
+	if len(errors) > 0 {
+		return []models.Extension{}, errors
+	}
+
+	return query.Extensions(languages.Languages), nil
+}
+
+// GetExtension returns the languages that use extension, which is matched case-sensitively. Its Languages are
+// empty if no language uses it.
+func (r *Repo) GetExtension(ctx context.Context, extension string) (languages models.Extension, errors []error) {
+	extension = query.NormalizeExtension(extension)
+	languages = models.Extension{Extension: extension, Languages: []models.LanguageRef{}}
+
+	found, errors := r.client.Find(ctx, models.Filter{Extensions: []string{extension}}, extensionOptions)
+	if len(errors) > 0 {
+		return languages, errors
+	}
+
+	for _, l := range found.Languages {
+		languages.Languages = append(languages.Languages, models.LanguageRef{Id: l.Id, Name: l.Name})
+	}
+
+	return languages, nil
+}
+
+// Classify picks the languages filename may be written in from its extension, as query.Classify does
+func (r *Repo) Classify(ctx context.Context, filename string) (classification models.Classification, errors []error) {
+	languages, errors := r.client.Find(ctx, models.Filter{}, extensionOptions)
+	if len(errors) > 0 {
+		return models.Classification{Filename: filename, Candidates: []models.LanguageRef{}}, errors
+	}
+
+	return query.Classify(languages.Languages, filename), nil
+}
+
+// Detect ranks the languages content may be written in by their heuristics and, if filename isn't empty, its
+// extension, as query.Detect does
+func (r *Repo) Detect(ctx context.Context, content string, filename string) (detection models.Detection, errors []error) {
+	languages, errors := r.client.Find(ctx, models.Filter{}, models.FindOptions{
+		Fields: []string{query.FieldName, query.FieldExtensions, query.FieldHeuristics},
+	})
+	if len(errors) > 0 {
+		return models.Detection{Guesses: []models.Guess{}}, errors
+	}
+
+	return models.Detection{Guesses: query.Detect(languages.Languages, content, filename)}, nil
 }
@@ -2,44 +2,101 @@ package repo
 
 import (
 	"languages-api/internal/models"
+
+	"context"
 )
 
 type MockRepo struct {
 	languages  models.Languages
+	results    models.SearchResults
+	stats      models.Stats
+	extensions []models.Extension
+	extension  models.Extension
+	class      models.Classification
+	detection  models.Detection
 	language   models.Language
 	id         string
 	isUpserted bool
+	revision   int64
+	batch      []models.BatchResult
 	Err        error
 }
 
-func (m *MockRepo) Ping() error {
+func (m *MockRepo) Ping(_ context.Context) error {
 	return m.Err
 }
 
-func (m *MockRepo) GetLanguages(_ models.Language) (languages models.Languages, err error) {
+func (m *MockRepo) GetLanguages(_ context.Context, _ models.Filter, _ models.FindOptions) (languages models.Languages, err error) {
 	return m.languages, m.Err
 }
 
-func (m *MockRepo) GetLanguage(_ string) (language models.Language, err error) {
+func (m *MockRepo) StreamLanguages(_ context.Context, _ models.Filter, _ models.FindOptions, send func(models.Language) error) (err error) {
+	for _, language := range m.languages.Languages {
+		err = send(language)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.Err
+}
+
+func (m *MockRepo) SearchLanguages(_ context.Context, _ string, _ models.Filter, _ models.FindOptions) (results models.SearchResults, err error) {
+	return m.results, m.Err
+}
+
+func (m *MockRepo) GetLanguage(_ context.Context, _ string, _ []string) (language models.Language, err error) {
 	return m.language, m.Err
 }
 
-func (m *MockRepo) PostLanguage(_ models.Language) (string, error) {
+func (m *MockRepo) PostLanguage(_ context.Context, _ models.Language) (string, error) {
 	return m.id, m.Err
 }
 
-func (m *MockRepo) PutLanguage(_ string, _ models.Language) (bool, error) {
-	return m.isUpserted, m.Err
+func (m *MockRepo) PutLanguage(_ context.Context, _ string, _ models.Language, _ int64) (int64, bool, error) {
+	return m.revision, m.isUpserted, m.Err
 }
 
-func (m *MockRepo) PatchLanguage(_ string, _ models.Language) (err error) {
-	return m.Err
+func (m *MockRepo) PatchLanguage(_ context.Context, _ string, _ models.Language, _ int64) (written int64, err error) {
+	return m.revision, m.Err
+}
+
+func (m *MockRepo) MergePatchLanguage(_ context.Context, _ string, _ models.Patch, _ int64) (written int64, err error) {
+	return m.revision, m.Err
+}
+
+func (m *MockRepo) JSONPatchLanguage(_ context.Context, _ string, _ models.JSONPatch, _ int64) (written int64, err error) {
+	return m.revision, m.Err
 }
 
-func (m *MockRepo) DeleteLanguage(_ string) (err error) {
+func (m *MockRepo) DeleteLanguage(_ context.Context, _ string, _ int64) (err error) {
 	return m.Err
 }
 
+func (m *MockRepo) BatchLanguages(_ context.Context, _ []models.BatchOperation, _ bool) (results []models.BatchResult, err error) {
+	return m.batch, m.Err
+}
+
+func (m *MockRepo) GetStats(_ context.Context, _ int64) (stats models.Stats, err error) {
+	return m.stats, m.Err
+}
+
+func (m *MockRepo) GetExtensions(_ context.Context) (extensions []models.Extension, err error) {
+	return m.extensions, m.Err
+}
+
+func (m *MockRepo) GetExtension(_ context.Context, _ string) (languages models.Extension, err error) {
+	return m.extension, m.Err
+}
+
+func (m *MockRepo) Classify(_ context.Context, _ string) (classification models.Classification, err error) {
+	return m.class, m.Err
+}
+
+func (m *MockRepo) Detect(_ context.Context, _ string, _ string) (detection models.Detection, err error) {
+	return m.detection, m.Err
+}
+
 func (m *MockRepo) Close() error {
 	return m.Err
 }
@@ -3,6 +3,7 @@ package repo
 import (
 	"languages-api/internal/models"
 
+	"context"
 	"errors"
 	"reflect"
 	"testing"
@@ -14,7 +15,7 @@ import (
 func Test_Ping_ShouldReturnRepoError(t *testing.T) {
 	expected := errors.New("ping error")
 
-	err := (&MockRepo{Err: expected}).Ping()
+	err := (&MockRepo{Err: expected}).Ping(context.Background())
 	if !errors.Is(err, expected) {
 		t.Errorf("expected %v, got %v", expected, err)
 	}
@@ -46,7 +47,7 @@ func Test_GetLanguages_ShouldReturnRepoLanguages(t *testing.T) {
 		},
 	}
 
-	result, err := (&MockRepo{languages: expected}).GetLanguages(models.Language{})
+	result, err := (&MockRepo{languages: expected}).GetLanguages(context.Background(), models.Filter{}, models.FindOptions{})
 	if err != nil {
 		t.Error("Error getting languages:", err)
 	}
@@ -59,12 +60,30 @@ func Test_GetLanguages_ShouldReturnRepoLanguages(t *testing.T) {
 func Test_GetLanguages_ShouldReturnRepoError(t *testing.T) {
 	expected := errors.New("getLanguages error")
 
-	_, err := (&MockRepo{Err: expected}).GetLanguages(models.Language{})
+	_, err := (&MockRepo{Err: expected}).GetLanguages(context.Background(), models.Filter{}, models.FindOptions{})
 	if !errors.Is(err, expected) {
 		t.Errorf("expected %v, got %v", expected, err)
 	}
 }
 
+func Test_StreamLanguages_ShouldSendRepoLanguagesThenError(t *testing.T) {
+	expected := errors.New("streamLanguages error")
+	languages := models.Languages{Languages: []models.Language{{Name: "Golang"}, {Name: "Rust"}}}
+
+	var sent []models.Language
+	err := (&MockRepo{languages: languages, Err: expected}).StreamLanguages(context.Background(), models.Filter{}, models.FindOptions{}, func(language models.Language) error {
+		sent = append(sent, language)
+		return nil
+	})
+	if !errors.Is(err, expected) {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+
+	if !reflect.DeepEqual(sent, languages.Languages) {
+		t.Errorf("expected %v, got %v", languages.Languages, sent)
+	}
+}
+
 func Test_GetLanguage_ShouldReturnRepoLanguage(t *testing.T) {
 	firstAppeared, err := time.Parse(time.RFC3339, "2009-11-10T00:00:00Z")
 	if err != nil {
@@ -87,7 +106,7 @@ func Test_GetLanguage_ShouldReturnRepoLanguage(t *testing.T) {
 		Wiki:          "https://en.wikipedia.org/wiki/Go_(programming_language)",
 	}
 
-	result, err := (&MockRepo{language: expected}).GetLanguage("")
+	result, err := (&MockRepo{language: expected}).GetLanguage(context.Background(), "", nil)
 	if err != nil {
 		t.Error("Error getting language:", err)
 	}
@@ -100,7 +119,7 @@ func Test_GetLanguage_ShouldReturnRepoLanguage(t *testing.T) {
 func Test_GetLanguage_ShouldReturnRepoError(t *testing.T) {
 	expected := errors.New("getLanguage error")
 
-	_, err := (&MockRepo{Err: expected}).GetLanguage("")
+	_, err := (&MockRepo{Err: expected}).GetLanguage(context.Background(), "", nil)
 	if !errors.Is(err, expected) {
 		t.Errorf("expected %v, got %v", expected, err)
 	}
@@ -109,7 +128,7 @@ func Test_GetLanguage_ShouldReturnRepoError(t *testing.T) {
 func Test_PostLanguage_ShouldReturnRepoId(t *testing.T) {
 	expected := "id"
 
-	result, err := (&MockRepo{id: expected}).PostLanguage(models.Language{})
+	result, err := (&MockRepo{id: expected}).PostLanguage(context.Background(), models.Language{})
 	if err != nil {
 		t.Error("Error posting language id:", err)
 	}
@@ -122,14 +141,14 @@ func Test_PostLanguage_ShouldReturnRepoId(t *testing.T) {
 func Test_PostLanguage_ShouldReturnRepoError(t *testing.T) {
 	expected := errors.New("postLanguage error")
 
-	_, err := (&MockRepo{Err: expected}).PostLanguage(models.Language{})
+	_, err := (&MockRepo{Err: expected}).PostLanguage(context.Background(), models.Language{})
 	if !errors.Is(err, expected) {
 		t.Errorf("expected %v, got %v", expected, err)
 	}
 }
 
 func Test_PutLanguage_ShouldReturnRepoIsUpserted(t *testing.T) {
-	result, err := (&MockRepo{isUpserted: true}).PutLanguage("", models.Language{})
+	_, result, err := (&MockRepo{isUpserted: true}).PutLanguage(context.Background(), "", models.Language{}, 0)
 	if err != nil {
 		t.Error("Error posting language id:", err)
 	}
@@ -142,7 +161,7 @@ func Test_PutLanguage_ShouldReturnRepoIsUpserted(t *testing.T) {
 func Test_PutLanguage_ShouldReturnRepoError(t *testing.T) {
 	expected := errors.New("putLanguage error")
 
-	_, err := (&MockRepo{Err: expected}).PutLanguage("", models.Language{})
+	_, _, err := (&MockRepo{Err: expected}).PutLanguage(context.Background(), "", models.Language{}, 0)
 	if !errors.Is(err, expected) {
 		t.Errorf("expected %v, got %v", expected, err)
 	}
@@ -151,7 +170,25 @@ func Test_PutLanguage_ShouldReturnRepoError(t *testing.T) {
 func Test_PatchLanguage_ShouldReturnRepoError(t *testing.T) {
 	expected := errors.New("patchLanguage error")
 
-	err := (&MockRepo{Err: expected}).PatchLanguage("", models.Language{})
+	_, err := (&MockRepo{Err: expected}).PatchLanguage(context.Background(), "", models.Language{}, 0)
+	if !errors.Is(err, expected) {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func Test_MergePatchLanguage_ShouldReturnRepoError(t *testing.T) {
+	expected := errors.New("mergePatchLanguage error")
+
+	_, err := (&MockRepo{Err: expected}).MergePatchLanguage(context.Background(), "", models.Patch{}, 0)
+	if !errors.Is(err, expected) {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func Test_JSONPatchLanguage_ShouldReturnRepoError(t *testing.T) {
+	expected := errors.New("jsonPatchLanguage error")
+
+	_, err := (&MockRepo{Err: expected}).JSONPatchLanguage(context.Background(), "", models.JSONPatch{}, 0)
 	if !errors.Is(err, expected) {
 		t.Errorf("expected %v, got %v", expected, err)
 	}
@@ -160,7 +197,42 @@ func Test_PatchLanguage_ShouldReturnRepoError(t *testing.T) {
 func Test_DeleteLanguage_ShouldReturnRepoError(t *testing.T) {
 	expected := errors.New("deleteLanguage error")
 
-	err := (&MockRepo{Err: expected}).DeleteLanguage("")
+	err := (&MockRepo{Err: expected}).DeleteLanguage(context.Background(), "", 0)
+	if !errors.Is(err, expected) {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func Test_BatchLanguages_ShouldReturnRepoResults(t *testing.T) {
+	expected := []models.BatchResult{{Id: "1"}, {Id: "2", Err: models.ErrNotFound}}
+
+	results, err := (&MockRepo{batch: expected}).BatchLanguages(context.Background(), nil, false)
+	if err != nil {
+		t.Error("Error applying batch:", err)
+	}
+
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %v, got %v", expected, results)
+	}
+}
+
+func Test_GetStats_ShouldReturnRepoStats(t *testing.T) {
+	expected := models.Stats{Totals: models.StatsTotals{Languages: 1, EarliestYear: 2009, LatestYear: 2009}}
+
+	result, err := (&MockRepo{stats: expected}).GetStats(context.Background(), 10)
+	if err != nil {
+		t.Error("Error getting stats:", err)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func Test_GetStats_ShouldReturnRepoError(t *testing.T) {
+	expected := errors.New("stats error")
+
+	_, err := (&MockRepo{Err: expected}).GetStats(context.Background(), 10)
 	if !errors.Is(err, expected) {
 		t.Errorf("expected %v, got %v", expected, err)
 	}
@@ -2,18 +2,59 @@ package repo
 
 import (
 	"languages-api/internal/config"
+	"languages-api/internal/mem"
 	"languages-api/internal/mgo"
+	"languages-api/internal/migrate"
 	"languages-api/internal/models"
+	"languages-api/internal/query"
 
+	"context"
 	"errors"
+	"reflect"
 	"testing"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// orderedClient records the order New prepares the database in
+type orderedClient struct {
+	*mem.MemoryClient
+	calls *[]string
+}
+
+func (c orderedClient) EnsureIndexes(_ context.Context) error {
+	*c.calls = append(*c.calls, "EnsureIndexes")
+	return nil
+}
+
+func (c orderedClient) RecordMigration(ctx context.Context, migration models.AppliedMigration) error {
+	*c.calls = append(*c.calls, "RecordMigration")
+	return c.MemoryClient.RecordMigration(ctx, migration)
+}
+
+type orderedConnector struct {
+	client orderedClient
+}
+
+func (oc orderedConnector) Connect(_ config.Config) (mgo.Client, error) {
+	return oc.client, nil
+}
+
+func Test_New_ShouldApplyMigrationsBeforeCreatingIndexes(t *testing.T) {
+	var calls []string
+	_, err := New(config.Config{Migrations: config.MigrationsConfig{RunOnStartup: true}}, orderedConnector{client: orderedClient{MemoryClient: mem.NewMemoryClient(), calls: &calls}})
+	if err != nil {
+		t.Fatal("New() returned an unexpected error:", err)
+	}
+
+	if len(calls) != len(migrate.Migrations)+1 || calls[len(calls)-1] != "EnsureIndexes" {
+		t.Errorf("New() should apply every migration and then create the indexes, but made %v", calls)
+	}
+}
+
 func Test_New_ShouldReturnConnectError(t *testing.T) {
-	_, err := New(config.Config{DBURL: "mongodb://"}, mgo.MongoConnector{})
+	_, err := New(config.Config{Mongo: config.MongoConfig{URL: "mongodb://"}}, mgo.MongoConnector{})
 	if err.Error() != "error parsing uri: must have at least 1 host" {
 		t.Errorf("New() returned an unexpected error")
 	}
@@ -37,7 +78,7 @@ func Test_Ping_ShouldReturnDisconnectError(t *testing.T) {
 		t.Error("Error creating client:", err)
 	}
 
-	err = (&Repo{client: mgo.MongoClient{Client: c}}).Ping()
+	err = (&Repo{client: mgo.MongoClient{Client: c}}).Ping(context.Background())
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("Ping() returned an unexpected error: %v", err)
 	}
@@ -49,19 +90,100 @@ func Test_GetLanguages_ShouldReturnFindError(t *testing.T) {
 		t.Error("Error creating client:", err)
 	}
 
-	_, errs := (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).GetLanguages(models.Language{})
+	_, errs := (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).GetLanguages(context.Background(), models.Filter{}, models.FindOptions{})
 	if !errors.Is(errs[0], mongo.ErrClientDisconnected) {
 		t.Errorf("GetLanguages() returned an unexpected error: %v", errs[0])
 	}
 }
 
+func Test_StreamLanguages_ShouldReturnFindError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).StreamLanguages(context.Background(), models.Filter{}, models.FindOptions{}, func(models.Language) error {
+		return nil
+	})
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("StreamLanguages() returned an unexpected error: %v", err)
+	}
+}
+
+func Test_SearchLanguages_ShouldFindLanguagesWhicheverWayTheyWereWritten(t *testing.T) {
+	r := &Repo{client: mem.NewMemoryClient()}
+
+	for _, name := range []string{"Java", "Javelin"} {
+		if _, err := r.PostLanguage(context.Background(), models.Language{Name: name}); err != nil {
+			t.Fatal("Error creating language:", err)
+		}
+	}
+
+	id, err := r.PostLanguage(context.Background(), models.Language{Name: "Kotlin"})
+	if err != nil {
+		t.Fatal("Error creating language:", err)
+	}
+
+	_, err = r.PatchLanguage(context.Background(), id, models.Language{Name: "JavaScript"}, 0)
+	if err != nil {
+		t.Fatal("Error updating language:", err)
+	}
+
+	results, errs := r.SearchLanguages(context.Background(), "java", models.Filter{}, models.FindOptions{Limit: 1, Offset: 1})
+	if len(errs) > 0 {
+		t.Fatal("SearchLanguages() returned errors:", errs)
+	}
+
+	if results.Total != 2 || len(results.Results) != 1 || results.Results[0].Language.Name != "JavaScript" {
+		t.Errorf("Expected JavaScript as the second of 2 results, but got %+v", results)
+	}
+}
+
+func Test_GetExtension_ShouldMatchCaseSensitivelyWithOrWithoutDot(t *testing.T) {
+	r := &Repo{client: mem.NewMemoryClient()}
+
+	for _, l := range []models.Language{{Name: "C", Extensions: []string{".c", ".h"}}, {Name: "C++", Extensions: []string{".C", ".h"}}} {
+		if _, err := r.PostLanguage(context.Background(), l); err != nil {
+			t.Fatal("Error creating language:", err)
+		}
+	}
+
+	for extension, expected := range map[string][]string{"c": {"C"}, ".C": {"C++"}, "h": {"C", "C++"}, ".hpp": nil} {
+		found, errs := r.GetExtension(context.Background(), extension)
+		if len(errs) > 0 {
+			t.Fatal("GetExtension() returned errors:", errs)
+		}
+
+		var names []string
+		for _, l := range found.Languages {
+			names = append(names, l.Name)
+		}
+
+		if !reflect.DeepEqual(names, expected) || found.Extension != query.NormalizeExtension(extension) {
+			t.Errorf("Expected %s to be used by %v, but got %+v", extension, expected, found)
+		}
+	}
+}
+
+func Test_Classify_ShouldReturnFindError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	_, errs := (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).Classify(context.Background(), "main.go")
+	if !errors.Is(errs[0], mongo.ErrClientDisconnected) {
+		t.Errorf("Classify() returned an unexpected error: %v", errs[0])
+	}
+}
+
 func Test_GetLanguage_ShouldReturnFindOneError(t *testing.T) {
 	c, err := mongo.NewClient()
 	if err != nil {
 		t.Error("Error creating client:", err)
 	}
 
-	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).GetLanguage(primitive.NewObjectID().Hex())
+	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).GetLanguage(context.Background(), primitive.NewObjectID().Hex(), nil)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("GetLanguage() returned an unexpected error: %v", err)
 	}
@@ -73,7 +195,7 @@ func Test_PostLanguage_ShouldReturnInsertOneError(t *testing.T) {
 		t.Error("Error creating client:", err)
 	}
 
-	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).PostLanguage(models.Language{})
+	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).PostLanguage(context.Background(), models.Language{})
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
 		t.Errorf("PostLanguage() returned an unexpected error: %v", err)
 	}
@@ -85,9 +207,9 @@ func Test_PutLanguage_ShouldReturnReplaceOneError(t *testing.T) {
 		t.Error("Error creating client:", err)
 	}
 
-	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).PutLanguage(primitive.NewObjectID().Hex(), models.Language{})
+	_, _, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).PutLanguage(context.Background(), primitive.NewObjectID().Hex(), models.Language{}, 0)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
-		t.Errorf("PutLanguage() returned an unexpected error: %v", err)
+		t.Errorf("PutLanguage(, 0) returned an unexpected error: %v", err)
 	}
 }
 
@@ -97,9 +219,33 @@ func Test_PatchLanguage_ShouldReturnUpdateOneError(t *testing.T) {
 		t.Error("Error creating client:", err)
 	}
 
-	err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).PatchLanguage(primitive.NewObjectID().Hex(), models.Language{})
+	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).PatchLanguage(context.Background(), primitive.NewObjectID().Hex(), models.Language{}, 0)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
-		t.Errorf("PatchLanguage() returned an unexpected error: %v", err)
+		t.Errorf("PatchLanguage(, 0) returned an unexpected error: %v", err)
+	}
+}
+
+func Test_MergePatchLanguage_ShouldReturnUpdateOneError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).MergePatchLanguage(context.Background(), primitive.NewObjectID().Hex(), models.Patch{}, 0)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("MergePatchLanguage(, 0) returned an unexpected error: %v", err)
+	}
+}
+
+func Test_JSONPatchLanguage_ShouldReturnUpdateOneError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).JSONPatchLanguage(context.Background(), primitive.NewObjectID().Hex(), models.JSONPatch{}, 0)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("JSONPatchLanguage(, 0) returned an unexpected error: %v", err)
 	}
 }
 
@@ -109,8 +255,48 @@ func Test_DeleteLanguage_ShouldReturnUpdateOneError(t *testing.T) {
 		t.Error("Error creating client:", err)
 	}
 
-	err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).DeleteLanguage(primitive.NewObjectID().Hex())
+	err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).DeleteLanguage(context.Background(), primitive.NewObjectID().Hex(), 0)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("DeleteLanguage(, 0) returned an unexpected error: %v", err)
+	}
+}
+
+func Test_BatchLanguages_ShouldReturnBulkWriteError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).BatchLanguages(context.Background(), []models.BatchOperation{{Kind: models.BatchDelete, Id: primitive.NewObjectID().Hex()}}, true)
+	if !errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Errorf("BatchLanguages() returned an unexpected error: %v", err)
+	}
+}
+
+func Test_BatchLanguages_ShouldReturnResultOfEachOperation(t *testing.T) {
+	r := &Repo{client: mem.NewMemoryClient()}
+
+	results, err := r.BatchLanguages(context.Background(), []models.BatchOperation{
+		{Kind: models.BatchCreate, Language: models.Language{Name: "Kotlin"}},
+		{Kind: models.BatchDelete, Id: primitive.NewObjectID().Hex()},
+	}, false)
+	if err != nil {
+		t.Fatal("Unexpected error in BatchLanguages:", err)
+	}
+
+	if len(results) != 2 || results[0].Err != nil || results[0].Id == "" || !errors.Is(results[1].Err, models.ErrNotFound) {
+		t.Errorf("BatchLanguages should create the language and fail the delete, but got %+v", results)
+	}
+}
+
+func Test_GetStats_ShouldReturnAggregateError(t *testing.T) {
+	c, err := mongo.NewClient()
+	if err != nil {
+		t.Error("Error creating client:", err)
+	}
+
+	_, err = (&Repo{client: mgo.MongoClient{Client: c, DatabaseName: "test", CollectionName: "test"}}).GetStats(context.Background(), 10)
 	if !errors.Is(err, mongo.ErrClientDisconnected) {
-		t.Errorf("DeleteLanguage() returned an unexpected error: %v", err)
+		t.Errorf("GetStats() returned an unexpected error: %v", err)
 	}
 }
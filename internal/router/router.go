@@ -13,8 +13,16 @@ func CreateHandler(ctrl controller.APIController, repo *repo.Repo) http.Handler
 	r := mux.NewRouter().StrictSlash(true)
 	r.HandleFunc("/health", ctrl.HealthCheckHandler(repo)).Methods(http.MethodGet)
 	r.HandleFunc("/", ctrl.GetLanguagesHandler(repo)).Methods(http.MethodGet)
+	r.HandleFunc("/search", ctrl.SearchHandler(repo)).Methods(http.MethodGet)
+	r.HandleFunc("/stats", ctrl.StatsHandler(repo)).Methods(http.MethodGet)
+	r.HandleFunc("/stats/{section}", ctrl.StatsHandler(repo)).Methods(http.MethodGet)
+	r.HandleFunc("/extensions", ctrl.GetExtensionsHandler(repo)).Methods(http.MethodGet)
+	r.HandleFunc("/extensions/{ext}", ctrl.GetExtensionHandler(repo)).Methods(http.MethodGet)
+	r.HandleFunc("/classify", ctrl.ClassifyHandler(repo)).Methods(http.MethodGet)
 	r.HandleFunc("/{id}", ctrl.GetLanguageHandler(repo)).Methods(http.MethodGet)
 	r.HandleFunc("/", ctrl.CreateLanguageHandler(repo)).Methods(http.MethodPost)
+	r.HandleFunc("/detect", ctrl.DetectHandler(repo)).Methods(http.MethodPost)
+	r.HandleFunc("/batch", ctrl.BatchHandler(repo)).Methods(http.MethodPost)
 	r.HandleFunc("/{id}", ctrl.UpsertLanguageHandler(repo)).Methods(http.MethodPut)
 	r.HandleFunc("/{id}", ctrl.UpdateLanguageHandler(repo)).Methods(http.MethodPatch)
 	r.HandleFunc("/{id}", ctrl.DeleteLanguageHandler(repo)).Methods(http.MethodDelete)
@@ -3,11 +3,34 @@ package router
 import (
 	"languages-api/internal/config"
 	"languages-api/internal/controller"
+	"languages-api/internal/mem"
+	"languages-api/internal/models"
+	"languages-api/internal/repo"
 
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+func newMemoryHandler(t *testing.T) http.Handler {
+	cfg := config.Config{Memory: config.MemoryConfig{SeedFile: "../../mockData.json"}}
+
+	db, err := repo.New(cfg, mem.MemoryConnector{})
+	if err != nil {
+		t.Error("Error creating in-memory repo:", err)
+	}
+
+	return CreateHandler(controller.New(cfg), db)
+}
+
 func Test_CreateHandler_ShouldReturnRouter(t *testing.T) {
 	r := CreateHandler(controller.New(config.Config{}), nil)
 
@@ -15,3 +38,668 @@ func Test_CreateHandler_ShouldReturnRouter(t *testing.T) {
 		t.Errorf("CreateHandler returned %s, expected *mux.Router", reflect.TypeOf(r).String())
 	}
 }
+
+func Test_CreateHandler_ShouldServeSeededLanguagesFromMemory(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?creators=Anders%20Hejlsberg", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody models.Languages
+
+	err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(respBody.Languages) != 2 {
+		t.Errorf("Expected C# and TypeScript, but got %+v", respBody.Languages)
+	}
+}
+
+func Test_CreateHandler_ShouldFilterLanguagesByYearRange(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?year[gte]=1990&year[lt]=2000&firstAppeared[before]=1995-06-01", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody models.Languages
+
+	err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(respBody.Languages) != 2 || respBody.Total != 2 {
+		t.Errorf("Expected Java and Python, but got %+v", respBody.Languages)
+	}
+}
+
+func Test_CreateHandler_ShouldFindCreatorsTypedWithoutAccents(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?creators[contains]=francois", nil))
+
+	var respBody models.Languages
+
+	err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(respBody.Languages) != 1 || respBody.Languages[0].Name != "XML" {
+		t.Errorf("Expected XML, but got %+v", respBody.Languages)
+	}
+}
+
+func Test_CreateHandler_ShouldSearchWithinFilters(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/search?q=java&year[gte]=1995", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody models.SearchResults
+
+	err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if respBody.Total != 2 || respBody.Results[0].Language.Name != "Java" || respBody.Results[1].Language.Name != "JavaScript" {
+		t.Errorf("Expected Java then JavaScript, but got %+v", respBody)
+	}
+}
+
+func Test_CreateHandler_ShouldOmitUnrequestedFields(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?fields=name,year&year=1995&name[prefix]=java&sort=name", nil))
+
+	var respBody struct {
+		Languages []map[string]interface{} `json:"languages"`
+	}
+
+	err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := []map[string]interface{}{{"name": "Java", "year": float64(1995)}, {"name": "JavaScript", "year": float64(1995)}}
+	if !reflect.DeepEqual(respBody.Languages, expected) {
+		t.Errorf("Expected %v, but got %v", expected, respBody.Languages)
+	}
+}
+
+func Test_CreateHandler_ShouldFilterLanguagesByExpression(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	expression := `year>=1990 and (creators has "Anders Hejlsberg" or extensions has ".ts")`
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?sort=name&filter="+url.QueryEscape(expression), nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody models.Languages
+
+	err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(respBody.Languages) != 2 || respBody.Languages[0].Name != "C#" || respBody.Languages[1].Name != "TypeScript" {
+		t.Errorf("Expected C# and TypeScript, but got %+v", respBody.Languages)
+	}
+}
+
+func Test_CreateHandler_ShouldReturnEveryLanguageWithoutALimitByDefault(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v", rr.Code)
+	}
+
+	var respBody models.Languages
+
+	err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if respBody.Total != 22 || len(respBody.Languages) != 22 || strings.Contains(rr.Header().Get("Link"), `rel="next"`) {
+		t.Errorf("Expected all 22 languages on one page, but got %d of %d with links %q", len(respBody.Languages), respBody.Total, rr.Header().Get("Link"))
+	}
+}
+
+func Test_CreateHandler_ShouldWalkEveryPageThroughNextLinks(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	seen := map[string]bool{}
+	var previous models.Language
+	next := "/?limit=5&sort=-year,name"
+
+	for next != "" {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, next, nil))
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for %s but got %v", next, rr.Code)
+		}
+
+		var respBody models.Languages
+
+		err := json.Unmarshal(rr.Body.Bytes(), &respBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if respBody.Total != 22 || len(respBody.Languages) > 5 {
+			t.Errorf("Expected at most 5 of 22 languages, but got %d of %d", len(respBody.Languages), respBody.Total)
+		}
+
+		for _, language := range respBody.Languages {
+			if seen[language.Name] {
+				t.Errorf("%s was returned on more than one page", language.Name)
+			}
+			seen[language.Name] = true
+
+			if language.Year > previous.Year && previous.Name != "" {
+				t.Errorf("%s (%d) should not come after %s (%d)", language.Name, language.Year, previous.Name, previous.Year)
+			}
+			previous = language
+		}
+
+		next = ""
+		for _, link := range strings.Split(rr.Header().Get("Link"), ", ") {
+			if strings.HasSuffix(link, `; rel="next"`) {
+				next = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+			}
+		}
+	}
+
+	if len(seen) != 22 {
+		t.Errorf("Expected to see all 22 languages, but saw %d", len(seen))
+	}
+}
+
+func Test_CreateHandler_ShouldRoundTripLanguageThroughMemory(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	reqBody, err := json.Marshal(models.Language{Name: "C--", Creators: []string{"Simon Peyton Jones"}, Year: 1997})
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(reqBody)))
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Expected 201 but got %v", rr.Code)
+	}
+
+	location := rr.Header().Get("Location")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, location, bytes.NewReader([]byte(`{"year":1998}`))))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, location, nil))
+
+	var respBody models.Language
+
+	err = json.Unmarshal(rr.Body.Bytes(), &respBody)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if respBody.Name != "C--" || respBody.Year != 1998 {
+		t.Errorf("Expected the patched language, but got %+v", respBody)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, location, nil))
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 but got %v", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, location, nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %v", rr.Code)
+	}
+}
+
+func Test_CreateHandler_ShouldReturn409ForExistingName(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	reqBody, err := json.Marshal(models.Language{Name: "C--", Year: 1997})
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(reqBody)))
+
+	location := rr.Header().Get("Location")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(reqBody)))
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 but got %v", rr.Code)
+	}
+
+	if !strings.HasSuffix(rr.Body.String(), strings.TrimPrefix(location, "/")) {
+		t.Errorf("Expected the response to name the existing language %s, but got %s", location, rr.Body.String())
+	}
+}
+
+func Test_CreateHandler_ShouldRejectWritesWithStaleETag(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	reqBody, err := json.Marshal(models.Language{Name: "C--", Year: 1997})
+	if err != nil {
+		t.Error(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(reqBody)))
+
+	location := rr.Header().Get("Location")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, location, nil))
+
+	etag := rr.Header().Get("ETag")
+
+	for _, expected := range []int{http.StatusOK, http.StatusPreconditionFailed} {
+		req := httptest.NewRequest(http.MethodPatch, location, bytes.NewReader([]byte(`{"year":1998}`)))
+		req.Header.Set("If-Match", etag)
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != expected {
+			t.Errorf("Expected %d but got %v", expected, rr.Code)
+		}
+	}
+}
+
+func Test_CreateHandler_ShouldServeStatsAndTheirSections(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats?top=2", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var stats models.Stats
+
+	err := json.Unmarshal(rr.Body.Bytes(), &stats)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if stats.Totals.Languages != 22 || len(stats.TopCreators) != 2 {
+		t.Errorf("Expected 22 languages and 2 top creators, but got %+v", stats)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats/totals", nil))
+
+	var totals models.StatsTotals
+
+	err = json.Unmarshal(rr.Body.Bytes(), &totals)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if totals != stats.Totals {
+		t.Errorf("Expected %+v, but got %+v", stats.Totals, totals)
+	}
+}
+
+func Test_CreateHandler_ShouldLookUpExtensionsAndClassifyFiles(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/extensions/C", nil))
+
+	var extension models.Extension
+
+	err := json.Unmarshal(rr.Body.Bytes(), &extension)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if rr.Code != http.StatusOK || len(extension.Languages) != 1 || extension.Languages[0].Name != "C++" {
+		t.Errorf("Expected .C to be used by C++ only, but got %v %+v", rr.Code, extension)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/classify?filename=include/util.h", nil))
+
+	var classification models.Classification
+
+	err = json.Unmarshal(rr.Body.Bytes(), &classification)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !classification.Ambiguous || len(classification.Candidates) != 2 || classification.Candidates[0].Name != "C" || classification.Candidates[1].Name != "C++" {
+		t.Errorf("Expected .h to be ambiguous between C and C++, but got %+v", classification)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/extensions", nil))
+
+	var extensions []models.Extension
+
+	err = json.Unmarshal(rr.Body.Bytes(), &extensions)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(extensions) != 72 {
+		t.Errorf("Expected 72 extensions, but got %d", len(extensions))
+	}
+}
+
+func Test_CreateHandler_ShouldDetectLanguagesFromContent(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	content := "#include <iostream>\n\nnamespace util {\nclass Buffer {\npublic:\n    std::size_t size;\n};\n}\n"
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/detect?filename=buffer.h", strings.NewReader(content)))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 but got %v", rr.Code)
+	}
+
+	var detection models.Detection
+
+	err := json.Unmarshal(rr.Body.Bytes(), &detection)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(detection.Guesses) < 2 || detection.Guesses[0].Language.Name != "C++" || detection.Guesses[1].Language.Name != "C" {
+		t.Errorf("Expected C++ then C, but got %+v", detection.Guesses)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/detect", strings.NewReader("#!/bin/bash\nif [ -n \"$1\" ]; then echo hi; fi\n")))
+
+	err = json.Unmarshal(rr.Body.Bytes(), &detection)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(detection.Guesses) == 0 || detection.Guesses[0].Language.Name != "Bash" {
+		t.Errorf("Expected Bash first, but got %+v", detection.Guesses)
+	}
+}
+
+func Test_CreateHandler_ShouldCountFacetsForTheWholeFilter(t *testing.T) {
+	handler := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?filter=year>=2000&limit=2&facets=decade,creators", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	var languages models.Languages
+
+	err := json.Unmarshal(rr.Body.Bytes(), &languages)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(languages.Languages) != 2 || languages.Total != 7 {
+		t.Errorf("Expected 2 of 7 languages, but got %d of %d", len(languages.Languages), languages.Total)
+	}
+
+	expectedDecades := []models.YearCount{{Year: 2000, Count: 3}, {Year: 2010, Count: 4}}
+	if languages.Facets == nil || !reflect.DeepEqual(languages.Facets.Decade, expectedDecades) {
+		t.Fatalf("Expected decades %v, but got %+v", expectedDecades, languages.Facets)
+	}
+
+	if len(languages.Facets.Creators) != 12 || languages.Facets.Year != nil {
+		t.Errorf("Expected only 12 creators besides the decades, but got %+v", languages.Facets)
+	}
+}
+
+func Test_CreateHandler_ShouldStreamEveryLanguagePastTheMaxPageSize(t *testing.T) {
+	cfg := config.Config{Memory: config.MemoryConfig{SeedFile: "../../mockData.json"}, HTTP: config.HTTPConfig{DefaultPageSize: 5, MaxPageSize: 5}}
+
+	db, err := repo.New(cfg, mem.MemoryConnector{})
+	if err != nil {
+		t.Fatal("Error creating in-memory repo:", err)
+	}
+
+	server := httptest.NewServer(CreateHandler(controller.New(cfg), db))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/?sort=name&fields=name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Expected a 200 application/x-ndjson response, but got %v %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var language models.Language
+
+		err = json.Unmarshal(scanner.Bytes(), &language)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, language.Name)
+	}
+
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 22 || names[0] != "Assembly" || names[21] != "XML" {
+		t.Errorf("Expected all 22 languages from Assembly to XML, but got %v", names)
+	}
+}
+
+func Test_CreateHandler_ShouldApplyBatchesThroughMemory(t *testing.T) {
+	handler := newMemoryHandler(t)
+	id := primitive.NewObjectID().Hex()
+
+	body := `{"operations": [
+		{"op": "create", "language": {"_id": "` + id + `", "name": "Kotlin", "year": 2011}},
+		{"op": "patch", "id": "` + id + `", "ifMatch": "\"1\"", "language": {"creators": ["JetBrains,Andrey Breslav"]}},
+		{"op": "create", "language": {"name": "Kotlin"}}
+	]}`
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Results []struct {
+			Status int    `json:"status"`
+			Id     string `json:"id"`
+		} `json:"results"`
+	}
+
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Results) != 3 || response.Results[0].Status != http.StatusCreated || response.Results[1].Status != http.StatusOK || response.Results[2].Status != http.StatusConflict {
+		t.Errorf("Expected 201, 200 and 409, but got %+v", response.Results)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{"atomic": true, "operations": [
+		{"op": "delete", "id": "`+id+`"},
+		{"op": "patch", "id": "`+primitive.NewObjectID().Hex()+`", "language": {"year": 2012}}
+	]}`)))
+
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Results) != 2 || response.Results[0].Status != http.StatusFailedDependency || response.Results[1].Status != http.StatusNotFound {
+		t.Errorf("Expected 424 and 404, but got %+v", response.Results)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+id, nil))
+
+	var language models.Language
+
+	err = json.Unmarshal(rr.Body.Bytes(), &language)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(language.Creators, []string{"JetBrains", "Andrey Breslav"}) || language.Revision != 2 {
+		t.Errorf("Expected the patched language to survive the atomic batch, but got %+v", language)
+	}
+}
+
+func Test_CreateHandler_ShouldApplyMergePatchesThroughMemory(t *testing.T) {
+	handler := newMemoryHandler(t)
+	id := primitive.NewObjectID().Hex()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{"operations": [
+		{"op": "create", "language": {"_id": "`+id+`", "name": "Kotlin", "year": 2011, "firstAppeared": "2011-07-22T00:00:00Z", "wiki": "https://kotlinlang.org"}}
+	]}`)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/"+id, strings.NewReader(`{"wiki": null, "firstAppeared": null, "creators": ["JetBrains,Andrey Breslav"]}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+id, nil))
+
+	var language models.Language
+
+	err := json.Unmarshal(rr.Body.Bytes(), &language)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if language.Wiki != "" || language.FirstAppeared != nil || language.Year != 2011 || !reflect.DeepEqual(language.Creators, []string{"JetBrains,Andrey Breslav"}) {
+		t.Errorf("Expected wiki and firstAppeared removed and creators set as given, but got %+v", language)
+	}
+}
+
+func Test_CreateHandler_ShouldApplyJSONPatchesThroughMemory(t *testing.T) {
+	handler := newMemoryHandler(t)
+	id := primitive.NewObjectID().Hex()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{"operations": [
+		{"op": "create", "language": {"_id": "`+id+`", "name": "Kotlin", "creators": ["JetBrains"], "extensions": [".kt"], "year": 2011}}
+	]}`)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json-patch+json")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr = patch(`[
+		{"op": "test", "path": "/revision", "value": 1},
+		{"op": "add", "path": "/creators/-", "value": "Andrey Breslav"},
+		{"op": "add", "path": "/extensions/-", "value": ".kts"}
+	]`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = patch(`[
+		{"op": "remove", "path": "/extensions/1"},
+		{"op": "test", "path": "/revision", "value": 1}
+	]`)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 but got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+id, nil))
+
+	var language models.Language
+
+	err := json.Unmarshal(rr.Body.Bytes(), &language)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(language.Creators, []string{"JetBrains", "Andrey Breslav"}) || !reflect.DeepEqual(language.Extensions, []string{".kt", ".kts"}) || language.Revision != 2 {
+		t.Errorf("Expected only the first patch applied, but got %+v", language)
+	}
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"strings"
+	"time"
+)
+
+// comparisonOperators are the SQL operators each models.Operator compiles to
+var comparisonOperators = map[models.Operator]string{
+	models.OpEq:  "=",
+	models.OpNe:  "<>",
+	models.OpLt:  "<",
+	models.OpLte: "<=",
+	models.OpGt:  ">",
+	models.OpGte: ">=",
+}
+
+// exprCondition compiles a filter expression into a condition on the languages table l and its arguments.
+// Every condition is either true or false, never NULL, so that NOT matches the same languages as Mongo's $nor.
+func exprCondition(e models.Expr) (condition string, args []interface{}) {
+	switch e := e.(type) {
+	case models.And:
+		return joinConditions(e, " AND ")
+	case models.Or:
+		return joinConditions(e, " OR ")
+	case models.Not:
+		condition, args = exprCondition(e.Expr)
+		return "NOT " + condition, args
+	case models.Comparison:
+		return comparisonCondition(e)
+	case models.In:
+		for _, value := range e.Values {
+			args = append(args, exprArg(e.Field, value))
+		}
+		return exprColumn(e.Field) + " IN (" + strings.Repeat("?, ", len(args)-1) + "?)", args
+	case models.Has:
+		var values []interface{}
+		for _, value := range e.Values {
+			values = append(values, exprArg(e.Field, value))
+		}
+
+		var conditions []string
+		if e.Field == query.FieldCreators {
+			conditions, args = setConditions(nil, nil, "SELECT 1 FROM language_creators c WHERE c.language_id = l.id",
+				matchCondition("c.creator", models.MatchExact), values, e.Set)
+		} else {
+			conditions, args = setConditions(nil, nil, "SELECT 1 FROM language_extensions e WHERE e.language_id = l.id",
+				"e.extension = ?", values, e.Set)
+		}
+		return "(" + strings.Join(conditions, " AND ") + ")", args
+	default:
+		panic("sqlite: unknown expression")
+	}
+}
+
+func joinConditions(operands []models.Expr, operator string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	for _, operand := range operands {
+		condition, operandArgs := exprCondition(operand)
+		conditions = append(conditions, condition)
+		args = append(args, operandArgs...)
+	}
+
+	return "(" + strings.Join(conditions, operator) + ")", args
+}
+
+// comparisonCondition compares a column with a value. Like Mongo, a missing first_appeared is only equal to null,
+// and not equal to, but never less or greater than, any date.
+func comparisonCondition(c models.Comparison) (string, []interface{}) {
+	column, operator := exprColumn(c.Field), comparisonOperators[c.Operator]
+
+	if c.Field != query.FieldFirstAppeared {
+		return column + " " + operator + " ?", []interface{}{exprArg(c.Field, c.Value)}
+	}
+
+	switch {
+	case c.Value == nil && c.Operator == models.OpEq:
+		return "l.first_appeared IS NULL", nil
+	case c.Value == nil:
+		return "l.first_appeared IS NOT NULL", nil
+	case c.Operator == models.OpNe:
+		return "(l.first_appeared IS NULL OR " + column + " <> julianday(?))", []interface{}{exprArg(c.Field, c.Value)}
+	default:
+		return "(l.first_appeared IS NOT NULL AND " + column + " " + operator + " julianday(?))", []interface{}{exprArg(c.Field, c.Value)}
+	}
+}
+
+// exprColumn is the column a field of an expression is compared through
+func exprColumn(field string) string {
+	switch field {
+	case query.FieldName:
+		return "fold(l.name)"
+	case query.FieldWiki:
+		return "l.wiki"
+	default:
+		return sortColumns[field]
+	}
+}
+
+// exprArg is value as it is compared with the column of exprColumn, or with a row of an array table
+func exprArg(field string, value interface{}) interface{} {
+	switch field {
+	case query.FieldName, query.FieldCreators:
+		return query.Fold(value.(string))
+	case query.FieldFirstAppeared:
+		t := value.(time.Time)
+		return formatTime(&t)
+	default:
+		return value
+	}
+}
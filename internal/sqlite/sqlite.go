@@ -0,0 +1,1175 @@
+package sqlite
+
+import (
+	"languages-api/internal/config"
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	driver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// schema stores the scalar fields of a language in one row and each array field in its own table,
+// one row per element, so that array filters can be expressed as EXISTS sub-queries
+const schema = `
+CREATE TABLE IF NOT EXISTS languages (
+	id             TEXT PRIMARY KEY,
+	name           TEXT NOT NULL,
+	first_appeared TEXT,
+	year           INTEGER NOT NULL,
+	wiki           TEXT NOT NULL,
+	revision       INTEGER NOT NULL DEFAULT 1,
+	heuristics     TEXT
+);
+
+CREATE TABLE IF NOT EXISTS language_creators (
+	language_id TEXT NOT NULL REFERENCES languages (id) ON DELETE CASCADE,
+	position    INTEGER NOT NULL,
+	creator     TEXT NOT NULL,
+	PRIMARY KEY (language_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS language_extensions (
+	language_id TEXT NOT NULL REFERENCES languages (id) ON DELETE CASCADE,
+	position    INTEGER NOT NULL,
+	extension   TEXT NOT NULL,
+	PRIMARY KEY (language_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS schema_migration_lock (
+	id         INTEGER PRIMARY KEY CHECK (id = 1),
+	owner      TEXT NOT NULL,
+	expires_at INTEGER NOT NULL
+);
+`
+
+// indexes mirrors mgo.Indexes: a unique index on the folded name, and indexes on the other columns Find filters by.
+// The unique index on the name as it was given, which earlier versions created, is dropped once it is replaced.
+const indexes = `
+CREATE UNIQUE INDEX IF NOT EXISTS languages_name_key ON languages (fold(name));
+DROP INDEX IF EXISTS languages_name;
+CREATE INDEX IF NOT EXISTS languages_year ON languages (year);
+CREATE INDEX IF NOT EXISTS language_creators_creator ON language_creators (creator);
+CREATE INDEX IF NOT EXISTS language_extensions_extension ON language_extensions (extension);
+`
+
+// addedColumns are the columns of languages added after it was first created, with the statements that add them
+// to databases created before they existed
+var addedColumns = []struct {
+	name string
+	add  string
+}{
+	{"revision", `ALTER TABLE languages ADD COLUMN revision INTEGER NOT NULL DEFAULT 1`},
+	// heuristics holds the JSON encoded models.Heuristics, or NULL for languages without any
+	{"heuristics", `ALTER TABLE languages ADD COLUMN heuristics TEXT`},
+}
+
+// selectLanguages reads every column of a language, aggregating the array tables into JSON arrays
+const selectLanguages = `
+SELECT l.id, l.name, l.first_appeared, l.year, l.wiki, l.revision, l.heuristics,
+	(SELECT json_group_array(c.creator ORDER BY c.position) FROM language_creators c WHERE c.language_id = l.id),
+	(SELECT json_group_array(e.extension ORDER BY e.position) FROM language_extensions e WHERE e.language_id = l.id)
+FROM languages l`
+
+// DriverName is the Config.Driver value that selects SQLiteConnector
+const DriverName = "sqlite"
+
+// collation is the name names are compared with in SQL, which sorts them like Mongo does with query.Locale
+const collation = "locale"
+
+func init() {
+	mgo.Register(DriverName, SQLiteConnector{})
+	driver.MustRegisterCollationUtf8(collation, query.CompareStrings)
+	driver.MustRegisterDeterministicScalarFunction("fold", 1, func(_ *driver.FunctionContext, args []sqldriver.Value) (sqldriver.Value, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return args[0], nil
+		}
+
+		return query.Fold(s), nil
+	})
+}
+
+// sortColumns are the expressions each sortable field is ordered and compared by. Dates are stored as RFC 3339
+// text, which doesn't sort chronologically once fractional seconds are trimmed, so they're compared as Julian days.
+var sortColumns = map[string]string{
+	query.FieldName:          "l.name COLLATE " + collation,
+	query.FieldYear:          "l.year",
+	query.FieldFirstAppeared: "julianday(l.first_appeared)",
+}
+
+// SQLiteClient implements the mgo.Client interface on top of an embedded SQLite database
+type SQLiteClient struct {
+	DB       *sql.DB
+	Timeouts config.TimeoutConfig
+}
+
+// Ping checks the connection to the database
+func (sc SQLiteClient) Ping(ctx context.Context) error {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Ping)
+	defer cancel()
+
+	return mgo.TimeoutError(sc.DB.PingContext(ctx))
+}
+
+// Disconnect closes the database
+func (sc SQLiteClient) Disconnect(_ context.Context) error {
+	return sc.DB.Close()
+}
+
+// EnsureIndexes creates any of the indexes that don't exist yet, returning models.ErrDuplicateNames if the unique
+// name index can't be built because languages already share a folded name
+func (sc SQLiteClient) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Index)
+	defer cancel()
+
+	_, err := sc.DB.ExecContext(ctx, indexes)
+
+	var se *driver.Error
+	if errors.As(err, &se) && se.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return fmt.Errorf("%w: %w", models.ErrDuplicateNames, err)
+	}
+
+	return mgo.TimeoutError(err)
+}
+
+func (sc SQLiteClient) Find(ctx context.Context, filter interface{}, opts models.FindOptions) (languages models.Languages, errs []error) {
+	where, args, statement, pageArgs := findStatement(filter, opts)
+
+	// Before pages are found by walking backwards from the anchor, then put back in order
+	reverse := opts.Before != nil
+
+	languages.Languages = []models.Language{}
+
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Read)
+	defer cancel()
+
+	err := sc.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM languages l"+where, args...).Scan(&languages.Total)
+	if err != nil {
+		errs = append(errs, mgo.TimeoutError(err))
+		return
+	}
+
+	rows, err := sc.DB.QueryContext(ctx, statement, pageArgs...)
+	if err != nil {
+		errs = append(errs, mgo.TimeoutError(err))
+		return
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to close database rows")
+		}
+	}()
+
+	selected := query.Selected(opts.Fields, opts.Sort)
+
+	for rows.Next() {
+		l, err := scanLanguage(rows)
+		if err != nil {
+			errs = append(errs, mgo.TimeoutError(err))
+			return
+		}
+
+		languages.Languages = append(languages.Languages, query.Project(l, selected))
+	}
+
+	err = rows.Err()
+	if err != nil {
+		errs = append(errs, mgo.TimeoutError(err))
+	}
+
+	if reverse {
+		slices.Reverse(languages.Languages)
+	}
+
+	if len(errs) > 0 || len(opts.Facets) == 0 {
+		return
+	}
+
+	languages.Facets, err = sc.facets(ctx, where, args, opts.Facets)
+	if err != nil {
+		errs = append(errs, mgo.TimeoutError(err))
+	}
+
+	return
+}
+
+func (sc SQLiteClient) Stream(ctx context.Context, filter interface{}, opts models.FindOptions, send func(models.Language) error) (err error) {
+	if opts.Before != nil {
+		return models.ErrStreamBefore
+	}
+
+	_, _, statement, pageArgs := findStatement(filter, opts)
+
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Stream)
+	defer cancel()
+
+	selected := query.Selected(opts.Fields, opts.Sort)
+
+	err = scanRows(ctx, sc.DB, statement, func(rows *sql.Rows) error {
+		l, err := scanLanguage(rows)
+		if err != nil {
+			return mgo.TimeoutError(err)
+		}
+
+		return send(query.Project(l, selected))
+	}, pageArgs...)
+
+	return mgo.TimeoutError(err)
+}
+
+// findStatement builds the WHERE clause, with its args, that matches the languages filter selects, and the
+// statement, with its args, that reads the page of them opts asks for. Languages before a cursor are read in
+// reverse, walking backwards from it.
+func findStatement(filter interface{}, opts models.FindOptions) (where string, args []interface{}, statement string, pageArgs []interface{}) {
+	f := filter.(models.Filter)
+
+	var conditions []string
+
+	if f.Name != "" {
+		conditions = append(conditions, matchCondition("l.name", f.NameMatch))
+		args = append(args, query.Fold(f.Name))
+	}
+
+	var creators []interface{}
+	for _, creator := range f.Creators {
+		creators = append(creators, query.Fold(creator))
+	}
+	conditions, args = setConditions(conditions, args, "SELECT 1 FROM language_creators c WHERE c.language_id = l.id",
+		matchCondition("c.creator", f.CreatorsMatch), creators, f.CreatorsSet)
+
+	var extensions []interface{}
+	for _, extension := range f.Extensions {
+		extensions = append(extensions, extension)
+	}
+	conditions, args = setConditions(conditions, args, "SELECT 1 FROM language_extensions e WHERE e.language_id = l.id",
+		"e.extension = ?", extensions, f.ExtensionsSet)
+
+	conditions, args = rangeConditions(conditions, args, sortColumns[query.FieldFirstAppeared], "julianday(?)", f.FirstAppeared, func(t time.Time) interface{} {
+		return formatTime(&t)
+	})
+
+	conditions, args = rangeConditions(conditions, args, sortColumns[query.FieldYear], "?", f.Year, func(year int32) interface{} {
+		return year
+	})
+
+	if f.Wiki != "" {
+		conditions = append(conditions, "l.wiki = ?")
+		args = append(args, f.Wiki)
+	}
+
+	if f.Expr != nil {
+		condition, exprArgs := exprCondition(f.Expr)
+		conditions = append(conditions, condition)
+		args = append(args, exprArgs...)
+	}
+
+	for _, term := range f.Terms {
+		conditions = append(conditions, termCondition)
+		args = append(args, term, term, term, strings.TrimPrefix(term, "."))
+	}
+
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	pageConditions := slices.Clone(conditions)
+	pageArgs = slices.Clone(args)
+
+	reverse := opts.Before != nil
+
+	if opts.After != nil {
+		condition, conditionArgs := keyset(*opts.After, opts.Sort, false)
+		pageConditions = append(pageConditions, condition)
+		pageArgs = append(pageArgs, conditionArgs...)
+	}
+
+	if opts.Before != nil {
+		condition, conditionArgs := keyset(*opts.Before, opts.Sort, true)
+		pageConditions = append(pageConditions, condition)
+		pageArgs = append(pageArgs, conditionArgs...)
+	}
+
+	var order []string
+	for _, f := range opts.Sort {
+		order = append(order, sortColumns[f.Field]+direction(f.Descending != reverse))
+	}
+	order = append(order, "l.id"+direction(reverse))
+
+	statement = selectLanguages
+	if len(pageConditions) > 0 {
+		statement += " WHERE " + strings.Join(pageConditions, " AND ")
+	}
+	statement += " ORDER BY " + strings.Join(order, ", ")
+
+	// SQLite only accepts an OFFSET after a LIMIT, where -1 means no limit
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	statement += " LIMIT ? OFFSET ?"
+	pageArgs = append(pageArgs, limit, opts.Offset)
+
+	return where, args, statement, pageArgs
+}
+
+func (sc SQLiteClient) FindOne(ctx context.Context, id string, fields []string) (language models.Language, err error) {
+	_, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Language{}, models.ErrInvalidId
+	}
+
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Read)
+	defer cancel()
+
+	language, err = scanLanguage(sc.DB.QueryRowContext(ctx, selectLanguages+" WHERE l.id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = models.ErrNotFound
+	}
+	err = mgo.TimeoutError(err)
+	language = query.Project(language, query.Selected(fields, nil))
+
+	return
+}
+
+func (sc SQLiteClient) InsertOne(ctx context.Context, document interface{}) (insertedId string, err error) {
+	language := document.(models.Language)
+	if language.Id.IsZero() {
+		language.Id = primitive.NewObjectID()
+	}
+
+	err = sc.inTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return insertOne(ctx, tx, language)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return language.Id.Hex(), nil
+}
+
+func (sc SQLiteClient) ReplaceOne(ctx context.Context, id string, document interface{}, revision int64) (written int64, isUpserted bool, err error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, false, models.ErrInvalidId
+	}
+
+	language := document.(models.Language)
+	if !language.Id.IsZero() && language.Id != objectId {
+		return 0, false, models.ErrIdMismatch
+	}
+	language.Id = objectId
+
+	err = sc.inTx(ctx, func(ctx context.Context, tx *sql.Tx) (err error) {
+		written, isUpserted, err = replaceOne(ctx, tx, id, language, revision)
+		return err
+	})
+
+	return
+}
+
+func (sc SQLiteClient) UpdateOne(ctx context.Context, id string, update interface{}, revision int64) (written int64, err error) {
+	_, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return 0, models.ErrInvalidId
+	}
+
+	err = sc.inTx(ctx, func(ctx context.Context, tx *sql.Tx) (err error) {
+		switch update := update.(type) {
+		case models.Patch:
+			written, err = patchOne(ctx, tx, id, revision, func(stored models.Language) (models.Language, error) {
+				return query.MergePatch(stored, update), nil
+			})
+		case models.JSONPatch:
+			written, err = patchOne(ctx, tx, id, revision, func(stored models.Language) (models.Language, error) {
+				return query.ApplyPatch(stored, update)
+			})
+		default:
+			written, err = updateOne(ctx, tx, id, update.(models.Language), revision)
+		}
+
+		return err
+	})
+
+	return
+}
+
+func (sc SQLiteClient) DeleteOne(ctx context.Context, id string, revision int64) (err error) {
+	_, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.ErrInvalidId
+	}
+
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Write)
+	defer cancel()
+
+	return sc.inTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return deleteOne(ctx, tx, id, revision)
+	})
+}
+
+// BulkWrite makes the whole batch in one transaction, with each operation in a savepoint of its own so that one
+// failing only undoes its own changes. An atomic batch is rolled back as a whole as soon as one fails.
+func (sc SQLiteClient) BulkWrite(ctx context.Context, operations []models.BatchOperation, atomic bool) (results []models.BatchResult, err error) {
+	err = sc.inTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var txErr error
+		var rollback bool
+		results, rollback = mgo.ApplyBatch(operations, atomic, func(operation models.BatchOperation) (result models.BatchResult) {
+			if txErr != nil {
+				return models.BatchResult{Id: operation.Id, Err: models.ErrNotApplied}
+			}
+
+			result.Err, txErr = savepoint(ctx, tx, func() (err error) {
+				result, err = writeOne(ctx, tx, operation)
+				return err
+			})
+
+			return result
+		})
+
+		if txErr != nil {
+			return txErr
+		}
+
+		if rollback {
+			return errRollback
+		}
+
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return results, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// AppliedMigrations returns the record of every migration that has been applied, in version order
+func (sc SQLiteClient) AppliedMigrations(ctx context.Context) (migrations []models.AppliedMigration, err error) {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Read)
+	defer cancel()
+
+	rows, err := sc.DB.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, mgo.TimeoutError(err)
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to close database rows")
+		}
+	}()
+
+	for rows.Next() {
+		var migration models.AppliedMigration
+		var appliedAt string
+
+		err = rows.Scan(&migration.Version, &migration.Name, &appliedAt)
+		if err != nil {
+			return nil, mgo.TimeoutError(err)
+		}
+
+		migration.AppliedAt, err = time.Parse(time.RFC3339Nano, appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	return migrations, mgo.TimeoutError(rows.Err())
+}
+
+// RecordMigration marks a migration as applied
+func (sc SQLiteClient) RecordMigration(ctx context.Context, migration models.AppliedMigration) (err error) {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Write)
+	defer cancel()
+
+	_, err = sc.DB.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		migration.Version, migration.Name, formatTime(&migration.AppliedAt))
+
+	return mgo.TimeoutError(err)
+}
+
+// RemoveMigration marks a migration as no longer applied
+func (sc SQLiteClient) RemoveMigration(ctx context.Context, version int) (err error) {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Write)
+	defer cancel()
+
+	_, err = sc.DB.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", version)
+
+	return mgo.TimeoutError(err)
+}
+
+// AcquireMigrationLock takes or extends the migration lock for owner, unless someone else holds an unexpired one.
+// The lock is the only row of schema_migration_lock, and its expiry is kept in Unix nanoseconds so it compares as a number.
+func (sc SQLiteClient) AcquireMigrationLock(ctx context.Context, owner string, until time.Time) (acquired bool, err error) {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Write)
+	defer cancel()
+
+	result, err := sc.DB.ExecContext(ctx, `INSERT INTO schema_migration_lock (id, owner, expires_at) VALUES (1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE owner = excluded.owner OR expires_at < ?`, owner, until.UnixNano(), time.Now().UnixNano())
+	if err != nil {
+		return false, mgo.TimeoutError(err)
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected == 1, err
+}
+
+// ReleaseMigrationLock gives up the migration lock if owner holds it
+func (sc SQLiteClient) ReleaseMigrationLock(ctx context.Context, owner string) (err error) {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Write)
+	defer cancel()
+
+	_, err = sc.DB.ExecContext(ctx, "DELETE FROM schema_migration_lock WHERE owner = ?", owner)
+
+	return mgo.TimeoutError(err)
+}
+
+// inTx runs fn in a transaction bounded by the write timeout, committing if it succeeds and rolling back otherwise.
+// fn must use the context it is given rather than the caller's.
+func (sc SQLiteClient) inTx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Write)
+	defer cancel()
+
+	tx, err := sc.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return mgo.TimeoutError(err)
+	}
+
+	err = fn(ctx, tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Error().Err(rbErr).Msg("Failed to roll back transaction")
+		}
+		return mgo.TimeoutError(err)
+	}
+
+	return mgo.TimeoutError(tx.Commit())
+}
+
+// errRollback rolls back the transaction of an atomic batch in which an operation failed
+var errRollback = errors.New("an operation of the batch failed")
+
+// savepoint runs fn in a savepoint, undoing only what fn changed if it fails. fnErr is what fn returned, and err is
+// set if the savepoint couldn't be made, released or rolled back to, leaving the transaction unusable.
+func savepoint(ctx context.Context, tx *sql.Tx, fn func() error) (fnErr error, err error) {
+	_, err = tx.ExecContext(ctx, "SAVEPOINT operation")
+	if err != nil {
+		return nil, err
+	}
+
+	fnErr = fn()
+	if fnErr != nil {
+		_, err = tx.ExecContext(ctx, "ROLLBACK TO operation")
+		if err != nil {
+			return fnErr, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "RELEASE operation")
+
+	return mgo.TimeoutError(fnErr), err
+}
+
+// writeOne makes an operation of a batch as the write of the same kind on its own would
+func writeOne(ctx context.Context, tx *sql.Tx, operation models.BatchOperation) (result models.BatchResult, err error) {
+	if operation.Kind == models.BatchCreate {
+		language := operation.Language
+		if language.Id.IsZero() {
+			language.Id = primitive.NewObjectID()
+		}
+		result.Id = language.Id.Hex()
+
+		return result, insertOne(ctx, tx, language)
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(operation.Id)
+	if err != nil {
+		return result, models.ErrInvalidId
+	}
+	result.Id = objectId.Hex()
+
+	switch operation.Kind {
+	case models.BatchReplace:
+		language := operation.Language
+		if !language.Id.IsZero() && language.Id != objectId {
+			return result, models.ErrIdMismatch
+		}
+		language.Id = objectId
+
+		_, result.Upserted, err = replaceOne(ctx, tx, result.Id, language, operation.Revision)
+	case models.BatchPatch:
+		_, err = updateOne(ctx, tx, result.Id, operation.Language, operation.Revision)
+	default:
+		err = deleteOne(ctx, tx, result.Id, operation.Revision)
+	}
+
+	return result, err
+}
+
+// insertOne stores a new language with an id at revision 1
+func insertOne(ctx context.Context, tx *sql.Tx, language models.Language) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM languages WHERE id = ?)", language.Id.Hex()).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return models.ErrDuplicateId
+	}
+
+	err = conflict(ctx, tx, language.Id.Hex(), language.Name)
+	if err != nil {
+		return err
+	}
+
+	return insertLanguage(ctx, tx, language)
+}
+
+// replaceOne sets every field of the language with the given id, inserting it if there isn't one and revision is 0
+func replaceOne(ctx context.Context, tx *sql.Tx, id string, language models.Language, revision int64) (written int64, isUpserted bool, err error) {
+	err = conflict(ctx, tx, id, language.Name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	heuristics, err := encodeHeuristics(language.Heuristics)
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = tx.QueryRowContext(ctx, "UPDATE languages SET name = ?, first_appeared = ?, year = ?, wiki = ?, heuristics = ?, revision = revision + 1 WHERE "+revisionCondition+" RETURNING revision",
+		language.Name, formatTime(language.FirstAppeared), language.Year, language.Wiki, heuristics, id, revision, revision).Scan(&written)
+	if errors.Is(err, sql.ErrNoRows) && revision != 0 {
+		return 0, false, models.ErrPreconditionFailed
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 1, true, insertLanguage(ctx, tx, language)
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = replaceCreators(ctx, tx, id, language.Creators)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return written, false, replaceExtensions(ctx, tx, id, language.Extensions)
+}
+
+// updateOne sets the fields that are set in language on the language with the given id
+func updateOne(ctx context.Context, tx *sql.Tx, id string, language models.Language, revision int64) (written int64, err error) {
+	columns, args, err := buildSet(language)
+	if err != nil {
+		return 0, err
+	}
+	columns = append(columns, "revision = revision + 1")
+
+	if language.Name != "" {
+		err := conflict(ctx, tx, id, language.Name)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, "UPDATE languages SET "+strings.Join(columns, ", ")+" WHERE "+revisionCondition+" RETURNING revision", append(args, id, revision, revision)...).Scan(&written)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, missingError(ctx, tx, id, revision)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(language.Creators) > 0 {
+		err = replaceCreators(ctx, tx, id, language.Creators)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if len(language.Extensions) > 0 {
+		err = replaceExtensions(ctx, tx, id, language.Extensions)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return written, nil
+}
+
+// patchOne replaces the language with the given id with the one patch makes of it. The stored language is read and
+// replaced in the same transaction, so nothing can be written in between.
+func patchOne(ctx context.Context, tx *sql.Tx, id string, revision int64, patch func(stored models.Language) (models.Language, error)) (written int64, err error) {
+	stored, err := scanLanguage(tx.QueryRowContext(ctx, selectLanguages+" WHERE l.id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) && revision == models.AnyRevision {
+		return 0, models.ErrPreconditionFailed
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.ErrNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if revision > 0 && stored.Revision != revision {
+		return 0, models.ErrPreconditionFailed
+	}
+
+	patched, err := patch(stored)
+	if err != nil {
+		return 0, err
+	}
+
+	written, _, err = replaceOne(ctx, tx, id, patched, stored.Revision)
+
+	return written, err
+}
+
+// deleteOne deletes the language with the given id
+func deleteOne(ctx context.Context, tx *sql.Tx, id string, revision int64) error {
+	res, err := tx.ExecContext(ctx, "DELETE FROM languages WHERE "+revisionCondition, id, revision, revision)
+	if err != nil {
+		return err
+	}
+
+	deletedCount, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deletedCount == 0 {
+		return missingError(ctx, tx, id, revision)
+	}
+
+	return nil
+}
+
+// SQLiteConnector implements the mgo.Connector interface
+type SQLiteConnector struct{}
+
+// Connect opens the configured database file, creating the schema if it doesn't exist yet
+func (sc SQLiteConnector) Connect(cfg config.Config) (mgo.Client, error) {
+	busyTimeout := cfg.Timeouts.Write
+	if busyTimeout <= 0 {
+		busyTimeout = 5 * time.Second
+	}
+
+	// Writes read before they write, and a deferred transaction that has read can't wait for another writer to
+	// finish before it writes, so transactions take the write lock as they begin, waiting up to busyTimeout for it
+	dsn := "file:" + cfg.SQLite.Path + "?" + url.Values{
+		"_pragma": []string{"foreign_keys(1)", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()), "journal_mode(WAL)"},
+		"_txlock": []string{"immediate"},
+	}.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := mgo.WithTimeout(context.Background(), cfg.Timeouts.Connect)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, schema)
+	if err == nil {
+		err = upgradeSchema(ctx, db)
+	}
+
+	return SQLiteClient{DB: db, Timeouts: cfg.Timeouts}, mgo.TimeoutError(err)
+}
+
+// upgradeSchema adds any columns that databases created by older versions are missing
+func upgradeSchema(ctx context.Context, db *sql.DB) error {
+	for _, column := range addedColumns {
+		var exists bool
+		err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pragma_table_info('languages') WHERE name = ?)", column.name).Scan(&exists)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		_, err = db.ExecContext(ctx, column.add)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// termCondition matches the languages holding a folded search term in any of the fields query.Search looks in.
+// Extensions are matched without the term's leading dot, as Search compares them so. It takes the term three times
+// followed by the term without its leading dot.
+const termCondition = `(instr(fold(l.name), ?) > 0 OR instr(fold(l.wiki), ?) > 0
+	OR EXISTS (SELECT 1 FROM language_creators c WHERE c.language_id = l.id AND instr(fold(c.creator), ?) > 0)
+	OR EXISTS (SELECT 1 FROM language_extensions e WHERE e.language_id = l.id AND instr(fold(e.extension), ?) > 0))`
+
+// revisionCondition matches the language with the given id, as long as it has the given revision if that isn't 0 or
+// models.AnyRevision. It takes the id followed by the revision twice.
+const revisionCondition = "id = ? AND (? <= 0 OR revision = ?)"
+
+// missingError works out why a write matched nothing: models.ErrPreconditionFailed if the language exists
+// but has a different revision to the one given, or doesn't exist and was required to by models.AnyRevision,
+// and models.ErrNotFound otherwise
+func missingError(ctx context.Context, tx *sql.Tx, id string, revision int64) error {
+	if revision == models.AnyRevision {
+		return models.ErrPreconditionFailed
+	}
+
+	if revision == 0 {
+		return models.ErrNotFound
+	}
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM languages WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return models.ErrPreconditionFailed
+	}
+
+	return models.ErrNotFound
+}
+
+// conflict returns a models.ConflictError if a language other than id already has the folded name, matching on the
+// same key as the unique name index
+func conflict(ctx context.Context, tx *sql.Tx, id string, name string) error {
+	var existing string
+	err := tx.QueryRowContext(ctx, "SELECT id FROM languages WHERE fold(name) = ? AND id != ?", query.Fold(name), id).Scan(&existing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return models.ConflictError{Id: existing}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLanguage(row scanner) (language models.Language, err error) {
+	var id, creators, extensions string
+	var firstAppeared, heuristics sql.NullString
+
+	err = row.Scan(&id, &language.Name, &firstAppeared, &language.Year, &language.Wiki, &language.Revision, &heuristics, &creators, &extensions)
+	if err != nil {
+		return models.Language{}, err
+	}
+
+	language.Id, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Language{}, err
+	}
+
+	if firstAppeared.Valid {
+		t, err := time.Parse(time.RFC3339Nano, firstAppeared.String)
+		if err != nil {
+			return models.Language{}, err
+		}
+		language.FirstAppeared = &t
+	}
+
+	if heuristics.Valid {
+		language.Heuristics = &models.Heuristics{}
+		err = json.Unmarshal([]byte(heuristics.String), language.Heuristics)
+		if err != nil {
+			return models.Language{}, err
+		}
+	}
+
+	language.Creators, err = decodeArray(creators)
+	if err != nil {
+		return models.Language{}, err
+	}
+
+	language.Extensions, err = decodeArray(extensions)
+
+	return
+}
+
+// decodeArray reads a JSON array built by json_group_array, treating an empty array as no value
+func decodeArray(data string) ([]string, error) {
+	var values []string
+
+	err := json.Unmarshal([]byte(data), &values)
+	if len(values) == 0 {
+		return nil, err
+	}
+
+	return values, err
+}
+
+// encodeHeuristics encodes heuristics for the heuristics column, as NULL if there are none
+func encodeHeuristics(heuristics *models.Heuristics) (interface{}, error) {
+	if heuristics == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(heuristics)
+	return string(data), err
+}
+
+func insertLanguage(ctx context.Context, tx *sql.Tx, language models.Language) error {
+	id := language.Id.Hex()
+
+	heuristics, err := encodeHeuristics(language.Heuristics)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO languages (id, name, first_appeared, year, wiki, heuristics, revision) VALUES (?, ?, ?, ?, ?, ?, 1)",
+		id, language.Name, formatTime(language.FirstAppeared), language.Year, language.Wiki, heuristics)
+	if err != nil {
+		return err
+	}
+
+	err = replaceCreators(ctx, tx, id, language.Creators)
+	if err != nil {
+		return err
+	}
+
+	return replaceExtensions(ctx, tx, id, language.Extensions)
+}
+
+func replaceCreators(ctx context.Context, tx *sql.Tx, id string, creators []string) error {
+	return replaceArray(ctx, tx, "language_creators", "creator", id, creators)
+}
+
+func replaceExtensions(ctx context.Context, tx *sql.Tx, id string, extensions []string) error {
+	return replaceArray(ctx, tx, "language_extensions", "extension", id, extensions)
+}
+
+func replaceArray(ctx context.Context, tx *sql.Tx, table string, column string, id string, values []string) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE language_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for i, value := range values {
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+table+" (language_id, position, "+column+") VALUES (?, ?, ?)", id, i, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildSet mirrors buildMap in package mgo, returning assignments for the non-zero scalar fields of language
+func buildSet(language models.Language) (columns []string, args []interface{}, err error) {
+	if language.Name != "" {
+		columns = append(columns, "name = ?")
+		args = append(args, language.Name)
+	}
+
+	if language.FirstAppeared != nil {
+		columns = append(columns, "first_appeared = ?")
+		args = append(args, formatTime(language.FirstAppeared))
+	}
+
+	if language.Year != 0 {
+		columns = append(columns, "year = ?")
+		args = append(args, language.Year)
+	}
+
+	if language.Wiki != "" {
+		columns = append(columns, "wiki = ?")
+		args = append(args, language.Wiki)
+	}
+
+	if language.Heuristics != nil {
+		heuristics, err := encodeHeuristics(language.Heuristics)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		columns = append(columns, "heuristics = ?")
+		args = append(args, heuristics)
+	}
+
+	return
+}
+
+// matchCondition compares the folded column with a folded value in the given mode
+func matchCondition(column string, mode models.MatchMode) string {
+	switch mode {
+	case models.MatchPrefix:
+		return "instr(fold(" + column + "), ?) = 1"
+	case models.MatchContains:
+		return "instr(fold(" + column + "), ?) > 0"
+	default:
+		return "fold(" + column + ") = ?"
+	}
+}
+
+// setConditions appends the conditions for the language's rows in an array table, selected by rows, to hold values in
+// the way mode asks for. match compares a row with a single value.
+func setConditions(conditions []string, args []interface{}, rows string, match string, values []interface{}, mode models.SetMode) ([]string, []interface{}) {
+	if len(values) == 0 {
+		return conditions, args
+	}
+
+	anyMatch := "(" + strings.Repeat(match+" OR ", len(values)-1) + match + ")"
+
+	switch mode {
+	case models.SetAny:
+		return append(conditions, "EXISTS ("+rows+" AND "+anyMatch+")"), append(args, values...)
+	case models.SetNone:
+		return append(conditions, "NOT EXISTS ("+rows+" AND "+anyMatch+")"), append(args, values...)
+	}
+
+	for _, value := range values {
+		conditions = append(conditions, "EXISTS ("+rows+" AND "+match+")")
+		args = append(args, value)
+	}
+
+	if mode == models.SetExact {
+		conditions = append(conditions, "NOT EXISTS ("+rows+" AND NOT "+anyMatch+")")
+		args = append(args, values...)
+	}
+
+	return conditions, args
+}
+
+// rangeConditions appends a comparison of column with each bound of r to conditions, converting the bounds with arg.
+// Comparisons with NULL are never true, so like Mongo a language without the value is never in the range.
+func rangeConditions[T any](conditions []string, args []interface{}, column string, placeholder string, r models.Range[T], arg func(T) interface{}) ([]string, []interface{}) {
+	for _, bound := range []struct {
+		operator string
+		limit    *T
+	}{{"=", r.Eq}, {">", r.Gt}, {">=", r.Gte}, {"<", r.Lt}, {"<=", r.Lte}} {
+		if bound.limit != nil {
+			conditions = append(conditions, column+" "+bound.operator+" "+placeholder)
+			args = append(args, arg(*bound.limit))
+		}
+	}
+
+	return conditions, args
+}
+
+// keyset matches the languages that come after anchor when sorted by sort and then id, or before it if reverse is set.
+// NULL sorts first, as it does in Mongo, but never compares as less or greater than anything, so it is matched explicitly.
+func keyset(anchor models.Language, sort []models.SortField, reverse bool) (condition string, args []interface{}) {
+	fields := append(slices.Clone(sort), models.SortField{Field: "id"})
+
+	column := func(field string) string {
+		if field == "id" {
+			return "l.id"
+		}
+		return sortColumns[field]
+	}
+
+	value := func(field string) (placeholder string, arg interface{}) {
+		switch field {
+		case "id":
+			return "?", anchor.Id.Hex()
+		case query.FieldFirstAppeared:
+			return "julianday(?)", formatTime(anchor.FirstAppeared)
+		default:
+			return "?", query.Value(anchor, field)
+		}
+	}
+
+	var alternatives []string
+	for i, f := range fields {
+		placeholder, arg := value(f.Field)
+
+		var beyond string
+		var beyondArgs []interface{}
+		switch {
+		case f.Descending == reverse && arg == nil:
+			beyond = column(f.Field) + " IS NOT NULL"
+		case f.Descending == reverse:
+			beyond = column(f.Field) + " > " + placeholder
+			beyondArgs = []interface{}{arg}
+		case arg == nil:
+			// Nothing comes before NULL
+			continue
+		default:
+			beyond = "(" + column(f.Field) + " < " + placeholder + " OR " + column(f.Field) + " IS NULL)"
+			beyondArgs = []interface{}{arg}
+		}
+
+		// Languages that tie with the anchor on every more significant field
+		var ties []string
+		for _, prev := range fields[:i] {
+			prevPlaceholder, prevArg := value(prev.Field)
+			if prevArg == nil {
+				ties = append(ties, column(prev.Field)+" IS NULL")
+				continue
+			}
+
+			ties = append(ties, column(prev.Field)+" = "+prevPlaceholder)
+			args = append(args, prevArg)
+		}
+
+		alternatives = append(alternatives, "("+strings.Join(append(ties, beyond), " AND ")+")")
+		args = append(args, beyondArgs...)
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// direction is the SQL sort direction for ascending or descending order
+func direction(descending bool) string {
+	if descending {
+		return " DESC"
+	}
+
+	return " ASC"
+}
+
+// formatTime stores timestamps as UTC RFC 3339 text so that equal instants compare equal
+func formatTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
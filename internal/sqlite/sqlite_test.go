@@ -0,0 +1,291 @@
+package sqlite
+
+import (
+	"languages-api/internal/config"
+	"languages-api/internal/mgo"
+	"languages-api/internal/mgo/mgotest"
+	"languages-api/internal/models"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newClient(t *testing.T) SQLiteClient {
+	c, err := SQLiteConnector{}.Connect(config.Config{SQLite: config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "languages.db")}})
+	if err != nil {
+		t.Fatal("Error connecting to database:", err)
+	}
+
+	if err := c.EnsureIndexes(context.Background()); err != nil {
+		t.Fatal("Error creating indexes:", err)
+	}
+
+	t.Cleanup(func() {
+		if err := c.Disconnect(context.Background()); err != nil {
+			t.Error("Error disconnecting from database:", err)
+		}
+	})
+
+	return c.(SQLiteClient)
+}
+
+// newMockClient returns a client holding the languages in mockData.json
+func newMockClient(t *testing.T) SQLiteClient {
+	c := newClient(t)
+
+	data, err := os.ReadFile("../../mockData.json")
+	if err != nil {
+		t.Fatal("Error reading mock data:", err)
+	}
+
+	var mock models.Languages
+	err = json.Unmarshal(data, &mock)
+	if err != nil {
+		t.Fatal("Error unmarshalling mock data:", err)
+	}
+
+	for _, l := range mock.Languages {
+		if _, err := c.InsertOne(context.Background(), l); err != nil {
+			t.Error("Error inserting language:", err)
+		}
+	}
+
+	return c
+}
+
+func Test_SQLiteClient_ShouldMeetTheClientContract(t *testing.T) {
+	mgotest.Run(t, func(t *testing.T) mgo.Client {
+		return newClient(t)
+	})
+}
+
+func Test_Ping_ShouldReturnNilWhenConnected(t *testing.T) {
+	err := newClient(t).Ping(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error pinging database: %v", err)
+	}
+}
+
+func Test_Connect_ShouldReturnSQLiteClient(t *testing.T) {
+	c := newClient(t)
+
+	if reflect.TypeOf(c).String() != "sqlite.SQLiteClient" {
+		t.Errorf("Connect() should return a SQLiteClient, but got %s", reflect.TypeOf(c).String())
+	}
+}
+
+func Test_Connect_ShouldReturnErrorForUnopenablePath(t *testing.T) {
+	_, err := SQLiteConnector{}.Connect(config.Config{SQLite: config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "missing", "languages.db")}})
+	if err == nil {
+		t.Error("Connect() should return an error when the directory does not exist")
+	}
+}
+
+func Test_EnsureIndexes_ShouldReturnErrDuplicateNamesWhenFoldedNamesRepeat(t *testing.T) {
+	c := newClient(t)
+
+	_, err := c.DB.Exec("DROP INDEX languages_name_key")
+	if err != nil {
+		t.Fatal("Error dropping index:", err)
+	}
+
+	for _, name := range []string{"Go", "GO"} {
+		_, err = c.DB.Exec("INSERT INTO languages (id, name, year, wiki) VALUES (?, ?, 2009, '')", primitive.NewObjectID().Hex(), name)
+		if err != nil {
+			t.Fatal("Error inserting language:", err)
+		}
+	}
+
+	err = c.EnsureIndexes(context.Background())
+	if !errors.Is(err, models.ErrDuplicateNames) {
+		t.Errorf("EnsureIndexes should return ErrDuplicateNames, but got %v", err)
+	}
+}
+
+func Test_Find_ShouldReturnLanguagesInIdOrder(t *testing.T) {
+	c := newClient(t)
+
+	golang := mgotest.NewGolang(t)
+	golang.Id = primitive.NewObjectID()
+
+	for _, l := range []models.Language{{Id: primitive.NewObjectID(), Name: "C", Extensions: []string{".c", ".h"}, Year: 1972}, golang} {
+		if _, err := c.InsertOne(context.Background(), l); err != nil {
+			t.Error("Error inserting language:", err)
+		}
+	}
+
+	langs, errs := c.Find(context.Background(), models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		t.Errorf("Unexpected errors in Find: %v", errs)
+	}
+
+	if len(langs.Languages) != 2 || langs.Languages[0].Name != "Golang" || langs.Languages[1].Name != "C" {
+		t.Errorf("Find should return Golang then C, but got %v", langs.Languages)
+	}
+}
+
+func Test_Find_ShouldSortNamesWithLocaleCollation(t *testing.T) {
+	c := newClient(t)
+
+	for _, name := range []string{"C++", "cobol", "C#", "bash", "C"} {
+		if _, err := c.InsertOne(context.Background(), models.Language{Name: name}); err != nil {
+			t.Error("Error inserting language:", err)
+		}
+	}
+
+	langs, _ := c.Find(context.Background(), models.Filter{}, models.FindOptions{Sort: []models.SortField{{Field: "name"}}})
+
+	var names []string
+	for _, l := range langs.Languages {
+		names = append(names, l.Name)
+	}
+
+	if expected := []string{"bash", "C", "C#", "C++", "cobol"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Find should sort names as %v, but got %v", expected, names)
+	}
+}
+
+func Test_FindOne_ShouldReturnInsertedLanguage(t *testing.T) {
+	c := newClient(t)
+	expected := mgotest.NewGolang(t)
+
+	id, err := c.InsertOne(context.Background(), expected)
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	expected.Id, _ = primitive.ObjectIDFromHex(id)
+	expected.Revision = 1
+
+	lang, err := c.FindOne(context.Background(), id, nil)
+	if err != nil {
+		t.Error("Error finding language:", err)
+	}
+
+	if !reflect.DeepEqual(lang, expected) {
+		t.Errorf("FindOne should return %v, but got %v", expected, lang)
+	}
+}
+
+func Test_DeleteOne_ShouldRemoveLanguageAndArrays(t *testing.T) {
+	c := newClient(t)
+
+	id, err := c.InsertOne(context.Background(), mgotest.NewGolang(t))
+	if err != nil {
+		t.Error("Error inserting language:", err)
+	}
+
+	err = c.DeleteOne(context.Background(), id, 0)
+	if err != nil {
+		t.Error("Error deleting language:", err)
+	}
+
+	var remaining int
+	err = c.DB.QueryRow("SELECT COUNT(*) FROM language_creators WHERE language_id = ?", id).Scan(&remaining)
+	if err != nil {
+		t.Error("Error counting creators:", err)
+	}
+
+	if remaining != 0 {
+		t.Errorf("DeleteOne should cascade to creators, but %d remain", remaining)
+	}
+}
+
+func Test_Connect_ShouldAddMissingColumnsToExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "languages.db")
+
+	c, err := SQLiteConnector{}.Connect(config.Config{SQLite: config.SQLiteConfig{Path: path}})
+	if err != nil {
+		t.Fatal("Error connecting to database:", err)
+	}
+
+	db := c.(SQLiteClient).DB
+	_, err = db.Exec("ALTER TABLE languages DROP COLUMN revision")
+	if err == nil {
+		_, err = db.Exec("ALTER TABLE languages DROP COLUMN heuristics")
+	}
+	if err == nil {
+		_, err = db.Exec("INSERT INTO languages (id, name, year, wiki) VALUES (?, 'Golang', 2009, '')", primitive.NewObjectID().Hex())
+	}
+	if err != nil {
+		t.Fatal("Error recreating old schema:", err)
+	}
+
+	if err := c.Disconnect(context.Background()); err != nil {
+		t.Error("Error disconnecting from database:", err)
+	}
+
+	c, err = SQLiteConnector{}.Connect(config.Config{SQLite: config.SQLiteConfig{Path: path}})
+	if err != nil {
+		t.Fatal("Error reconnecting to database:", err)
+	}
+	defer func() {
+		if err := c.Disconnect(context.Background()); err != nil {
+			t.Error("Error disconnecting from database:", err)
+		}
+	}()
+
+	langs, errs := c.Find(context.Background(), models.Filter{}, models.FindOptions{})
+	if len(errs) > 0 {
+		t.Errorf("Unexpected errors in Find: %v", errs)
+	}
+
+	if len(langs.Languages) != 1 || langs.Languages[0].Revision != 1 || langs.Languages[0].Heuristics != nil {
+		t.Errorf("Existing languages should start at revision 1 without heuristics, but got %v", langs.Languages)
+	}
+}
+
+func Test_InsertOne_ShouldReturnContextErrorIfCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := newClient(t).InsertOne(ctx, mgotest.NewGolang(t))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("InsertOne should return context.Canceled, but got %v", err)
+	}
+}
+
+func Test_Find_ShouldReturnErrTimeoutIfDeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, errs := newClient(t).Find(ctx, models.Filter{}, models.FindOptions{})
+	if len(errs) == 0 || !errors.Is(errs[0], models.ErrTimeout) {
+		t.Errorf("Find should return ErrTimeout, but got %v", errs)
+	}
+}
+
+func Test_RecordMigration_ShouldBeReturnedByAppliedMigrations(t *testing.T) {
+	c := newClient(t)
+	appliedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, version := range []int{2, 1} {
+		err := c.RecordMigration(context.Background(), models.AppliedMigration{Version: version, Name: "test", AppliedAt: appliedAt})
+		if err != nil {
+			t.Error("Error recording migration:", err)
+		}
+	}
+
+	err := c.RemoveMigration(context.Background(), 2)
+	if err != nil {
+		t.Error("Error removing migration:", err)
+	}
+
+	migrations, err := c.AppliedMigrations(context.Background())
+	if err != nil {
+		t.Error("Error getting applied migrations:", err)
+	}
+
+	expected := []models.AppliedMigration{{Version: 1, Name: "test", AppliedAt: appliedAt}}
+	if !reflect.DeepEqual(migrations, expected) {
+		t.Errorf("AppliedMigrations should return %v, but got %v", expected, migrations)
+	}
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"languages-api/internal/mgo"
+	"languages-api/internal/models"
+	"languages-api/internal/query"
+
+	"context"
+	"database/sql"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Stats summarises every stored language with a GROUP BY query for each part of models.Stats. Every creator is
+// read so that query.CompleteStats can total them before keeping only the topCreators most prolific.
+func (sc SQLiteClient) Stats(ctx context.Context, topCreators int64) (stats models.Stats, err error) {
+	ctx, cancel := mgo.WithTimeout(ctx, sc.Timeouts.Read)
+	defer cancel()
+
+	err = sc.DB.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(MIN(year), 0), COALESCE(MAX(year), 0) FROM languages").
+		Scan(&stats.Totals.Languages, &stats.Totals.EarliestYear, &stats.Totals.LatestYear)
+	if err != nil {
+		return models.Stats{}, mgo.TimeoutError(err)
+	}
+
+	// Every query sorts names in byte order, like Mongo does without a collation
+	counts := []struct {
+		query string
+		scan  func(rows *sql.Rows) error
+	}{
+		{"SELECT year, COUNT(*) FROM languages GROUP BY year ORDER BY year", func(rows *sql.Rows) error {
+			var c models.YearCount
+			err := rows.Scan(&c.Year, &c.Count)
+			stats.ByYear = append(stats.ByYear, c)
+			return err
+		}},
+		{"SELECT year - year % 10 AS decade, COUNT(*) FROM languages GROUP BY decade ORDER BY decade", func(rows *sql.Rows) error {
+			var c models.YearCount
+			err := rows.Scan(&c.Year, &c.Count)
+			stats.ByDecade = append(stats.ByDecade, c)
+			return err
+		}},
+		{"SELECT creator, COUNT(*) AS n FROM language_creators GROUP BY creator ORDER BY n DESC, creator", func(rows *sql.Rows) error {
+			var c models.NameCount
+			err := rows.Scan(&c.Name, &c.Count)
+			stats.TopCreators = append(stats.TopCreators, c)
+			return err
+		}},
+		{"SELECT extension, COUNT(*) AS n FROM language_extensions GROUP BY extension ORDER BY n DESC, extension", func(rows *sql.Rows) error {
+			var c models.NameCount
+			err := rows.Scan(&c.Name, &c.Count)
+			stats.Extensions = append(stats.Extensions, c)
+			return err
+		}},
+		{"SELECT id, name FROM languages WHERE first_appeared IS NULL ORDER BY id", func(rows *sql.Rows) error {
+			var id string
+			var ref models.LanguageRef
+
+			err := rows.Scan(&id, &ref.Name)
+			if err != nil {
+				return err
+			}
+
+			ref.Id, err = primitive.ObjectIDFromHex(id)
+			stats.MissingFirstAppeared = append(stats.MissingFirstAppeared, ref)
+			return err
+		}},
+	}
+
+	for _, c := range counts {
+		err = scanRows(ctx, sc.DB, c.query, c.scan)
+		if err != nil {
+			return models.Stats{}, mgo.TimeoutError(err)
+		}
+	}
+
+	return query.CompleteStats(stats, topCreators), nil
+}
+
+// facets counts the languages the where clause of Find matches by each of facets, with a GROUP BY query for each
+func (sc SQLiteClient) facets(ctx context.Context, where string, args []interface{}, facets []string) (*models.Facets, error) {
+	var counted models.Facets
+
+	// Every query sorts like its counterpart in Stats, and the joined tables are named apart from the ones the
+	// conditions in where use
+	counts := map[string]struct {
+		query string
+		scan  func(rows *sql.Rows) error
+	}{
+		query.FacetYear: {"SELECT l.year, COUNT(*) FROM languages l" + where + " GROUP BY l.year ORDER BY l.year", func(rows *sql.Rows) error {
+			var c models.YearCount
+			err := rows.Scan(&c.Year, &c.Count)
+			counted.Year = append(counted.Year, c)
+			return err
+		}},
+		query.FacetDecade: {"SELECT l.year - l.year % 10 AS decade, COUNT(*) FROM languages l" + where + " GROUP BY decade ORDER BY decade", func(rows *sql.Rows) error {
+			var c models.YearCount
+			err := rows.Scan(&c.Year, &c.Count)
+			counted.Decade = append(counted.Decade, c)
+			return err
+		}},
+		query.FacetCreators: {"SELECT lc.creator, COUNT(*) AS n FROM languages l JOIN language_creators lc ON lc.language_id = l.id" + where +
+			" GROUP BY lc.creator ORDER BY n DESC, lc.creator", func(rows *sql.Rows) error {
+			var c models.NameCount
+			err := rows.Scan(&c.Name, &c.Count)
+			counted.Creators = append(counted.Creators, c)
+			return err
+		}},
+		query.FacetExtensions: {"SELECT le.extension, COUNT(*) AS n FROM languages l JOIN language_extensions le ON le.language_id = l.id" + where +
+			" GROUP BY le.extension ORDER BY n DESC, le.extension", func(rows *sql.Rows) error {
+			var c models.NameCount
+			err := rows.Scan(&c.Name, &c.Count)
+			counted.Extensions = append(counted.Extensions, c)
+			return err
+		}},
+	}
+
+	for _, facet := range facets {
+		c := counts[facet]
+		err := scanRows(ctx, sc.DB, c.query, c.scan, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return query.CompleteFacets(counted, facets), nil
+}
+
+// scanRows runs a query with args and calls scan for each row it returns
+func scanRows(ctx context.Context, db *sql.DB, statement string, scan func(rows *sql.Rows) error, args ...interface{}) (err error) {
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to close database rows")
+		}
+	}()
+
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
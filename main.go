@@ -7,7 +7,18 @@ import (
 	"languages-api/internal/repo"
 	"languages-api/internal/router"
 
+	// Storage drivers register themselves with mgo.Register when imported
+	_ "languages-api/internal/jsonfile"
+	_ "languages-api/internal/mem"
+	_ "languages-api/internal/sqlite"
+
+	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/TV4/graceful"
 	"github.com/rs/zerolog/log"
@@ -19,7 +30,22 @@ func main() {
 		log.Fatal().Msgf("Error getting configurations: %v", err)
 	}
 
-	db, err := repo.New(cfg, mgo.MongoConnector{})
+	connector, err := mgo.Lookup(cfg.Driver)
+	if err != nil {
+		log.Fatal().Msgf("Error getting storage driver: %v", err)
+	}
+
+	log.Info().Msgf("Using %s storage driver", cfg.Driver)
+
+	migrating := len(os.Args) > 1 && os.Args[1] == "migrate"
+
+	var db *repo.Repo
+	if migrating {
+		// The subcommand decides which migrations to run, and the indexes may need them to be run first
+		db, err = repo.Open(cfg, connector)
+	} else {
+		db, err = repo.New(cfg, connector)
+	}
 	if err != nil {
 		log.Fatal().Msgf("Error creating database client: %v", err)
 	}
@@ -31,13 +57,86 @@ func main() {
 		}
 	}()
 
+	if migrating {
+		err = migrate(db, os.Args[2:])
+		if err != nil {
+			log.Fatal().Msgf("Error running migrations: %v", err)
+		}
+		return
+	}
+
 	ctrl := controller.New(cfg)
 
+	// Request contexts derive from baseCtx, which is only cancelled once LogListenAndServe returns, after Shutdown has
+	// drained in-flight requests or run out of time. That stops the NDJSON streams that outlive the grace period
+	// before the database is closed, without cutting short requests that would have finished within it.
+	baseCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: router.CreateHandler(ctrl, db),
+		BaseContext: func(_ net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
 	log.Info().Msgf("Listening on port %s", cfg.Port)
 	graceful.LogListenAndServe(srv)
 }
+
+// migrate runs the migrate subcommand: "migrate [up]" applies every pending migration and then creates the indexes,
+// "migrate down [steps]" reverts the given number of migrations (default 1), and "migrate status" lists them
+func migrate(db *repo.Repo, args []string) error {
+	runner, err := db.Migrator()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("Applied %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Build any indexes the migrations were holding up, such as the unique name index
+		return db.EnsureIndexes(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+
+		reverted, err := runner.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %d %s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := runner.Status(ctx)
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d %s: %s\n", status.Version, status.Name, appliedAt)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+}